@@ -0,0 +1,280 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// runCommand handles command-line subcommands such as
+// "avtotestprime-server migrate status". It returns the process exit code.
+func runCommand(args []string) int {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "import":
+		return importCommand(args[1:])
+	case "export":
+		return exportCommand(args[1:])
+	case "media":
+		return mediaCommand(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage()
+	return 2
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  avtotestprime-server                 start the web server
+  avtotestprime-server migrate status  list schema migrations
+  avtotestprime-server migrate up      apply all pending migrations
+  avtotestprime-server migrate down    revert the latest migration
+  avtotestprime-server migrate to N    migrate up or down to version N
+  avtotestprime-server import [-dry-run] [-upsert] FILE
+                                       import questions from CSV, JSON, XLSX or ZIP
+  avtotestprime-server export [-format json|csv|zip] [-category ID,...] [-q TEXT] FILE
+                                       export questions; FILE "-" writes to stdout
+  avtotestprime-server media report    list orphaned and missing media files
+  avtotestprime-server media gc        delete orphaned media files
+  avtotestprime-server media migrate DEST
+                                       copy all media files to the storage DEST,
+                                       given like MEDIA_STORAGE`)
+}
+
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+	openDB()
+	defer db.Close()
+	m, ok := db.(migrator)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "this storage backend has no schema to migrate")
+		return 1
+	}
+
+	var err error
+	switch args[0] {
+	case "status":
+		var status []migrationStatus
+		status, err = m.MigrationStatus()
+		if err == nil {
+			current, _ := m.SchemaVersion()
+			fmt.Printf("Schema version %d, latest %d\n", current, latestSchemaVersion())
+			for _, st := range status {
+				applied := "pending"
+				if st.Applied {
+					applied = "applied " + st.AppliedAt.Format("02.01.2006 15:04")
+				}
+				fmt.Printf("%4d  %-40s %s\n", st.Version, st.Name, applied)
+			}
+		}
+	case "up":
+		err = db.Migrate()
+	case "down":
+		var current int
+		current, err = m.SchemaVersion()
+		if err == nil && current > 0 {
+			err = m.MigrateTo(current - 1)
+		}
+	case "to":
+		if len(args) < 2 {
+			printUsage()
+			return 2
+		}
+		var target int
+		target, err = strconv.Atoi(args[1])
+		if err == nil {
+			err = m.MigrateTo(target)
+		}
+	default:
+		printUsage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func importCommand(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "check the file and print the report without saving")
+	upsert := fs.Bool("upsert", false, "update questions whose number already exists")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		printUsage()
+		return 2
+	}
+	name := fs.Arg(0)
+	content, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	f, err := readImportFile(name, content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+
+	openDB()
+	defer db.Close()
+	openMedia()
+	if err := db.Migrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	report, err := runImport(f, importOptions{DryRun: *dryRun, Upsert: *upsert})
+	for _, row := range report.Rows {
+		for _, msg := range row.Errors {
+			fmt.Printf("line %d: %s\n", row.Line, msg)
+		}
+	}
+	for _, name := range report.NewCategories {
+		fmt.Printf("new category: %s\n", name)
+	}
+	fmt.Printf("%d to create, %d to update, %d unchanged, %d invalid\n", report.Created, report.Updated, report.Unchanged, report.Failed)
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	case report.HasErrors():
+		fmt.Println("nothing imported")
+		return 1
+	case report.Applied:
+		fmt.Println("import done")
+	}
+	return 0
+}
+
+func exportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "json, csv or zip (default: from the file extension)")
+	categories := fs.String("category", "", "comma-separated category ids to export")
+	query := fs.String("q", "", "export only questions matching this text or number")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		printUsage()
+		return 2
+	}
+	name := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	}
+	if !slices.Contains(exportFormats, *format) {
+		fmt.Fprintf(os.Stderr, "export: unknown format %q\n", *format)
+		return 2
+	}
+
+	openDB()
+	defer db.Close()
+	openMedia()
+	if err := db.Migrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	questions := db.SearchQuestions(QuestionFilter{
+		Query:       *query,
+		CategoryIDs: parseIDs(strings.Split(*categories, ",")),
+	})
+
+	var out io.Writer = os.Stdout
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeExport(out, *format, questions); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	if name != "-" {
+		fmt.Printf("%d questions exported to %s\n", len(questions), name)
+	}
+	return 0
+}
+
+func mediaCommand(args []string) int {
+	if len(args) == 2 && args[0] == "migrate" {
+		return mediaMigrateCommand(args[1])
+	}
+	if len(args) != 1 || (args[0] != "report" && args[0] != "gc") {
+		printUsage()
+		return 2
+	}
+	openDB()
+	defer db.Close()
+	openMedia()
+	if err := db.Migrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "media: %v\n", err)
+		return 1
+	}
+
+	if args[0] == "gc" {
+		removed, freed, err := collectMediaGarbage()
+		fmt.Printf("%d files removed, %s freed\n", removed, formatFileSize(freed))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "media: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	report, err := scanMedia()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "media: %v\n", err)
+		return 1
+	}
+	for _, f := range report.Orphans {
+		fmt.Printf("orphan   %s  %s\n", f.Path, formatFileSize(f.Size))
+	}
+	for _, m := range report.Missing {
+		var owners []string
+		for _, ref := range m.Refs {
+			owners = append(owners, ref.Kind+" "+ref.Label)
+		}
+		fmt.Printf("missing  %s  (%s)\n", m.Path, strings.Join(owners, ", "))
+	}
+	fmt.Printf("%d files, %s; %d orphaned (%s), %d recent, %d missing\n",
+		report.Files, formatFileSize(report.Bytes), len(report.Orphans), formatFileSize(report.OrphanBytes()),
+		report.Recent, len(report.Missing))
+	if len(report.Missing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// mediaMigrateCommand copies the files of the current media store to
+// another one. It can run again to pick up files uploaded meanwhile; only
+// missing files are copied.
+func mediaMigrateCommand(dest string) int {
+	openMedia()
+	dst, err := openMediaStore(dest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "media: %v\n", err)
+		return 1
+	}
+	copied, err := copyMedia(mediaStore, dst, func(format string, args ...interface{}) {
+		fmt.Printf(format+"\n", args...)
+	})
+	fmt.Printf("%d files copied\n", copied)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "media: %v\n", err)
+		return 1
+	}
+	return 0
+}
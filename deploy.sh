@@ -47,7 +47,7 @@ apt install -y git curl
 
 if ! command -v go &> /dev/null; then
     echo -e "${YELLOW}Go o'rnatilmoqda...${NC}"
-    GO_VERSION="1.24.0"
+    GO_VERSION="1.26.0"
     wget -q "https://go.dev/dl/go${GO_VERSION}.linux-amd64.tar.gz" -O /tmp/go.tar.gz
     rm -rf /usr/local/go
     tar -C /usr/local -xzf /tmp/go.tar.gz
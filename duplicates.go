@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/draw"
+)
+
+// Duplicate detection. Questions are compared by their text and variants,
+// normalised so that case, punctuation, apostrophes and the Uzbek script
+// do not matter, and by a perceptual hash of their images, which survives
+// re-encoding and resizing.
+const (
+	// defaultDuplicateThreshold is the similarity, in percent, from which
+	// two questions are listed as likely duplicates.
+	defaultDuplicateThreshold = 85
+	// textWeight is the share of the question text in the similarity; the
+	// variants make up the rest.
+	textWeight = 0.7
+	// maxImageDistance is how many of the 64 bits of two image hashes may
+	// differ for the images to count as the same picture.
+	maxImageDistance = 10
+)
+
+// QuestionMerge folds duplicate questions into one. Letters maps the id of
+// each question merged away to how its variant letters translate into the
+// kept question's, so that answers given to it stay readable.
+type QuestionMerge struct {
+	KeepID  int
+	Letters map[int]map[string]string
+}
+
+// MergedIDs lists the questions merged away in ascending order, the order
+// the stores fold them in: where two of them were answered in the same
+// test, or bookmarked by the same user, the lowest id's entry is kept.
+func (m *QuestionMerge) MergedIDs() []int {
+	ids := make([]int, 0, len(m.Letters))
+	for id := range m.Letters {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// duplicateGroup is a set of questions that look like copies of each other.
+type duplicateGroup struct {
+	Questions []*Question
+	// Similarity is the highest similarity, in percent, between two of the
+	// questions.
+	Similarity int
+	// AnswersDiffer is set when the questions do not mark the same variant
+	// texts correct, so merging needs a closer look.
+	AnswersDiffer bool
+}
+
+// normalizeForCompare reduces s to lowercase Latin words separated by
+// single spaces.
+func normalizeForCompare(s string) string {
+	if isCyrillicText(s) {
+		s = cyrillicToLatin(s)
+	}
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case isApostrophe(r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// trigrams returns the set of three-letter pieces of a normalised string,
+// padded so that short words count too.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	r := []rune(" " + s + " ")
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
+
+// dice is the Dice coefficient of two trigram sets, from 0 to 1.
+func dice(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+// fingerprint is what the detector compares of a question.
+type fingerprint struct {
+	q        *Question
+	text     map[string]bool
+	variants map[string]bool
+	correct  string
+	image    uint64
+	hasImage bool
+}
+
+func newFingerprint(q *Question) *fingerprint {
+	var variants, correct []string
+	for _, v := range q.VariantsList {
+		n := normalizeForCompare(v.Text)
+		variants = append(variants, n)
+		if q.IsCorrectLetter(v.Letter) {
+			correct = append(correct, n)
+		}
+	}
+	// Sorted, so that the same variants in another order still match.
+	sort.Strings(variants)
+	sort.Strings(correct)
+	if q.IsOrdering() {
+		correct = nil
+		for _, v := range q.VariantsList {
+			correct = append(correct, normalizeForCompare(v.Text))
+		}
+	}
+	f := &fingerprint{
+		q:        q,
+		text:     trigrams(normalizeForCompare(q.Text)),
+		variants: trigrams(strings.Join(variants, " | ")),
+		correct:  q.QuestionType() + ":" + strings.Join(correct, " | "),
+	}
+	if q.Image != "" {
+		f.image, f.hasImage = imageHash(q.Image)
+	}
+	return f
+}
+
+// similarity compares two fingerprints from 0 to 1. Questions with
+// different pictures, or with a picture on one side only, are never
+// duplicates, however alike their text: many questions ask the same about
+// different signs.
+func (f *fingerprint) similarity(g *fingerprint, text float64) float64 {
+	if f.hasImage != g.hasImage {
+		return 0
+	}
+	if f.hasImage && f.q.Image != g.q.Image && bits.OnesCount64(f.image^g.image) > maxImageDistance {
+		return 0
+	}
+	return textWeight*text + (1-textWeight)*dice(f.variants, g.variants)
+}
+
+// findDuplicates groups questions whose similarity reaches threshold
+// percent. Pairs are found through the trigrams their texts share, so only
+// questions with much text in common are compared in full.
+func findDuplicates(questions []*Question, threshold int) []*duplicateGroup {
+	limit := float64(threshold) / 100
+	// The variants can add at most their weight, so a pair below this text
+	// similarity cannot reach the threshold.
+	minText := (limit - (1 - textWeight)) / textWeight
+
+	prints := make([]*fingerprint, len(questions))
+	for i, q := range questions {
+		prints[i] = newFingerprint(q)
+	}
+
+	parent := make([]int, len(prints))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	type pair struct {
+		i     int
+		score float64
+	}
+	var pairs []pair
+
+	postings := make(map[string][]int)
+	for i, f := range prints {
+		shared := make(map[int]int)
+		for t := range f.text {
+			for _, j := range postings[t] {
+				shared[j]++
+			}
+			postings[t] = append(postings[t], i)
+		}
+		for j, n := range shared {
+			text := 2 * float64(n) / float64(len(f.text)+len(prints[j].text))
+			if text < minText {
+				continue
+			}
+			if s := f.similarity(prints[j], text); s >= limit {
+				parent[find(i)] = find(j)
+				pairs = append(pairs, pair{i, s})
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range prints {
+		members[find(i)] = append(members[find(i)], i)
+	}
+	byRoot := make(map[int]*duplicateGroup)
+	var groups []*duplicateGroup
+	for root, ids := range members {
+		if len(ids) < 2 {
+			continue
+		}
+		g := &duplicateGroup{}
+		for _, i := range ids {
+			g.Questions = append(g.Questions, prints[i].q)
+			if prints[i].correct != prints[ids[0]].correct {
+				g.AnswersDiffer = true
+			}
+		}
+		sort.Slice(g.Questions, func(a, b int) bool { return g.Questions[a].Number < g.Questions[b].Number })
+		byRoot[root] = g
+		groups = append(groups, g)
+	}
+	for _, p := range pairs {
+		g := byRoot[find(p.i)]
+		g.Similarity = max(g.Similarity, int(p.score*100+0.5))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Questions[0].Number < groups[j].Questions[0].Number })
+	return groups
+}
+
+// imageHashes caches image hashes by media path. Stored files never
+// change, so entries stay valid.
+var imageHashes sync.Map
+
+type cachedHash struct {
+	hash uint64
+	ok   bool
+}
+
+// imageHash is a difference hash of the image at the media path p: the
+// picture is shrunk to 9x8 grey pixels and each bit tells whether a pixel
+// is brighter than its right neighbour. It is read from the thumbnail when
+// there is one. ok is false when the image cannot be read.
+func imageHash(p string) (uint64, bool) {
+	if h, found := imageHashes.Load(p); found {
+		return h.(cachedHash).hash, h.(cachedHash).ok
+	}
+	var h cachedHash
+	name := imageVariant(p, thumbSuffix)
+	if !mediaFileExists(name) {
+		name = p
+	}
+	if r, _, err := mediaStore.Open(name); err == nil {
+		img, _, err := image.Decode(r)
+		r.Close()
+		if err == nil {
+			small := image.NewGray(image.Rect(0, 0, 9, 8))
+			draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					h.hash <<= 1
+					if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+						h.hash |= 1
+					}
+				}
+			}
+			h.ok = true
+		}
+	}
+	imageHashes.Store(p, h)
+	return h.hash, h.ok
+}
+
+// variantLetterMap pairs the variants of from with those of to by their
+// text, best matches first, and returns from's letters mapped to to's.
+// Variants without a counterpart are left out.
+func variantLetterMap(from, to *Question) map[string]string {
+	type match struct {
+		from, to string
+		score    float64
+	}
+	var matches []match
+	for _, a := range from.VariantsList {
+		ta := trigrams(normalizeForCompare(a.Text))
+		for _, b := range to.VariantsList {
+			if s := dice(ta, trigrams(normalizeForCompare(b.Text))); s > 0.5 {
+				matches = append(matches, match{a.Letter, b.Letter, s})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	letters := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, m := range matches {
+		if _, done := letters[m.from]; !done && !taken[m.to] {
+			letters[m.from] = m.to
+			taken[m.to] = true
+		}
+	}
+	return letters
+}
+
+// remapAnswer rewrites a stored answer in the letters of another question.
+// A hotspot answer is a point on the image and stays as it is. It fails
+// when a letter of the answer has no counterpart, as the answer would then
+// say something the student did not.
+func remapAnswer(answer string, letters map[string]string) (string, bool) {
+	if _, ok := parsePoint(answer); ok {
+		return answer, true
+	}
+	out := ""
+	for _, c := range answer {
+		l, ok := letters[string(c)]
+		if !ok {
+			return "", false
+		}
+		out += l
+	}
+	return out, out != ""
+}
+
+// replaceQuestionIDs rewrites a session's question_ids list with every id
+// in merged replaced by keepID, dropping repeats. It returns the new list,
+// its length and whether it changed.
+func replaceQuestionIDs(list string, merged map[int]map[string]string, keepID int) (string, int, bool) {
+	var ids []int
+	json.Unmarshal([]byte(list), &ids)
+	changed := false
+	seen := make(map[int]bool)
+	var out []int
+	for _, id := range ids {
+		if _, ok := merged[id]; ok {
+			id = keepID
+			changed = true
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	if !changed {
+		return list, len(ids), false
+	}
+	b, _ := json.Marshal(out)
+	return string(b), len(out), true
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// forEachStore runs fn against a fresh in-memory store and a fresh SQLite
+// database, with the package-level db pointing at it.
+func forEachStore(t *testing.T, fn func(t *testing.T)) {
+	t.Run("memory", func(t *testing.T) {
+		useTestStore(t)
+		fn(t)
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		useTestStore(t)
+		s, err := newSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		if err := s.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		db = s
+		fn(t)
+	})
+}
+
+func TestRemapAnswer(t *testing.T) {
+	letters := map[string]string{"A": "B", "B": "A", "C": "D"}
+	tests := []struct {
+		answer, want string
+		ok           bool
+	}{
+		{"A", "B", true},
+		{"AC", "BD", true},
+		{"CBA", "DAB", true},
+		{"D", "", false},
+		{"AD", "", false},
+		{"", "", false},
+		{"12.5,40.0", "12.5,40.0", true},
+	}
+	for _, tt := range tests {
+		if got, ok := remapAnswer(tt.answer, letters); got != tt.want || ok != tt.ok {
+			t.Errorf("remapAnswer(%q) = %q, %v; want %q, %v", tt.answer, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMergeQuestions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		if err := db.CreateUser("student", "x", ""); err != nil {
+			t.Fatal(err)
+		}
+		user := db.GetUserByUsername("student")
+
+		// The kept question takes "Wait" as the answer, the duplicate
+		// "Stop", and the duplicate has a variant the kept one lacks.
+		keep := &Question{Number: 1, Text: "What does a flashing yellow light mean?", CorrectAnswer: "C",
+			VariantsList: []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}, {Letter: "C", Text: "Wait"}}}
+		dup := &Question{Number: 2, Text: "What does a flashing yellow light mean", CorrectAnswer: "B",
+			VariantsList: []Variant{{Letter: "A", Text: "Go"}, {Letter: "B", Text: "Stop"}, {Letter: "D", Text: "Honk"}}}
+		other := &Question{Number: 3, Text: "Speed limit in town?", CorrectAnswer: "A",
+			VariantsList: []Variant{{Letter: "A", Text: "70"}, {Letter: "B", Text: "90"}}}
+		for _, q := range []*Question{keep, dup, other} {
+			if err := db.CreateQuestion(q, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		newSession := func(ids ...int) *TestSession {
+			s := &TestSession{UserID: user.ID, TotalQuestions: len(ids)}
+			if err := db.CreateTestSession(s, ids); err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+		right := newSession(dup.ID, other.ID)
+		db.CreateTestAnswer(right.ID, dup.ID, "B", true, 1)
+		honk := newSession(dup.ID)
+		db.CreateTestAnswer(honk.ID, dup.ID, "D", false, 0)
+		untouched := newSession(other.ID, keep.ID)
+
+		letters := variantLetterMap(dup, keep)
+		if letters["A"] != "B" || letters["B"] != "A" || letters["D"] != "" {
+			t.Fatalf("letter map %v", letters)
+		}
+		if err := db.MergeQuestions(&QuestionMerge{KeepID: keep.ID, Letters: map[int]map[string]string{dup.ID: letters}}, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if db.GetQuestionByID(dup.ID) != nil {
+			t.Error("merged question still live")
+		}
+		answers := db.GetSessionAnswers(right.ID)
+		if len(answers) != 1 {
+			t.Fatalf("%d answers, want 1", len(answers))
+		}
+		if a := answers[0]; a.QuestionID != keep.ID || a.SelectedAnswer != "A" || a.IsCorrect || a.Credit != 0 {
+			t.Errorf("moved answer %+v, want A scored wrong against the kept question", a)
+		}
+		if answers := db.GetSessionAnswers(honk.ID); len(answers) != 0 {
+			t.Errorf("unmappable answer kept: %+v", answers[0])
+		}
+
+		s := db.GetTestSession(right.ID, user.ID)
+		if ids := s.QuestionIDList(); len(ids) != 2 || ids[0] != keep.ID || ids[1] != other.ID || s.TotalQuestions != 2 {
+			t.Errorf("session questions %v (%d)", ids, s.TotalQuestions)
+		}
+		if ids := db.GetTestSession(honk.ID, user.ID).QuestionIDList(); len(ids) != 1 || ids[0] != keep.ID {
+			t.Errorf("session questions %v", ids)
+		}
+		if ids := db.GetTestSession(untouched.ID, user.ID).QuestionIDList(); len(ids) != 2 || ids[0] != other.ID {
+			t.Errorf("unrelated session questions %v", ids)
+		}
+	})
+}
+
+func TestMergeQuestionsConflictingAnswers(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		if err := db.CreateUser("student", "x", ""); err != nil {
+			t.Fatal(err)
+		}
+		user := db.GetUserByUsername("student")
+		variants := []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}}
+		newQuestion := func(number int) *Question {
+			q := &Question{Number: number, Text: "Stop sign?", CorrectAnswer: "A", VariantsList: variants}
+			if err := db.CreateQuestion(q, 0); err != nil {
+				t.Fatal(err)
+			}
+			return q
+		}
+		same := map[string]string{"A": "A", "B": "B"}
+
+		// Each round merges two duplicates answered differently in the same
+		// test; map order must not decide which answer is kept.
+		for round := 0; round < 10; round++ {
+			keep, first, second := newQuestion(round*3+1), newQuestion(round*3+2), newQuestion(round*3+3)
+			s := &TestSession{UserID: user.ID, TotalQuestions: 2}
+			if err := db.CreateTestSession(s, []int{second.ID, first.ID}); err != nil {
+				t.Fatal(err)
+			}
+			db.CreateTestAnswer(s.ID, second.ID, "A", true, 1)
+			db.CreateTestAnswer(s.ID, first.ID, "B", false, 0)
+			db.ToggleBookmark(user.ID, second.ID)
+
+			merge := &QuestionMerge{KeepID: keep.ID, Letters: map[int]map[string]string{first.ID: same, second.ID: same}}
+			if err := db.MergeQuestions(merge, 0); err != nil {
+				t.Fatal(err)
+			}
+			answers := db.GetSessionAnswers(s.ID)
+			if len(answers) != 1 || answers[0].QuestionID != keep.ID || answers[0].SelectedAnswer != "B" || answers[0].IsCorrect {
+				t.Fatalf("round %d: answers %+v, want only the first duplicate's B", round, answers)
+			}
+			if !db.GetUserBookmarkIDs(user.ID)[keep.ID] {
+				t.Fatalf("round %d: bookmark not moved", round)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// exportFormats are the formats writeExport understands, in the order they
+// are offered.
+var exportFormats = []string{"json", "csv", "zip"}
+
+// exportBankFile is the name of the question file inside an export ZIP.
+const exportBankFile = "questions.json"
+
+// exportQuestion converts q to the import format. withImages decides how the
+// images are referenced: by base name when the files travel in the same
+// ZIP, otherwise by their media paths.
+func exportQuestion(q *Question, categoryNames map[int]string, withImages bool) importQuestion {
+	item := importQuestion{
+		Number:           q.Number,
+		Text:             q.Text,
+		Image:            q.Image,
+		Category:         categoryNames[q.CategoryID],
+		CorrectAnswer:    q.CorrectAnswer,
+		Hotspots:         q.HotspotList(),
+		Explanation:      q.Explanation,
+		ExplanationImage: q.ExplanationImage,
+		RuleRef:          q.RuleRef,
+		Status:           q.QuestionStatus(),
+	}
+	if !q.IsSingle() {
+		item.Type = q.QuestionType()
+	}
+	if withImages && q.Image != "" {
+		item.Image = path.Base(q.Image)
+	}
+	if withImages && q.ExplanationImage != "" {
+		item.ExplanationImage = path.Base(q.ExplanationImage)
+	}
+	for _, v := range q.VariantsList {
+		item.Variants = append(item.Variants, v.Text)
+	}
+	return item
+}
+
+// writeExport writes questions to w in the given format. JSON and CSV are
+// exactly what readImportFile reads back; a ZIP holds questions.json and an
+// images/ folder with every question and explanation image that still
+// exists, each once.
+func writeExport(w io.Writer, format string, questions []*Question) error {
+	names := categoryNames(db.GetAllCategories())
+	items := make([]importQuestion, 0, len(questions))
+	for _, q := range questions {
+		items = append(items, exportQuestion(q, names, format == "zip"))
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case "csv":
+		return writeExportCSV(w, items)
+	case "zip":
+		zw := zip.NewWriter(w)
+		added := make(map[string]bool)
+		for _, q := range questions {
+			for _, image := range []string{q.Image, q.ExplanationImage} {
+				if image == "" || added[path.Base(image)] || !mediaFileExists(image) {
+					continue
+				}
+				added[path.Base(image)] = true
+				if err := addMediaToZip(zw, "images/"+path.Base(image), image); err != nil {
+					return err
+				}
+			}
+		}
+		fw, err := zw.Create(exportBankFile)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(items); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// writeExportCSV writes one row per question with as many variant columns
+// as the longest question needs.
+func writeExportCSV(w io.Writer, items []importQuestion) error {
+	maxVariants := 2
+	for _, item := range items {
+		maxVariants = max(maxVariants, len(item.Variants))
+	}
+	header := []string{"number", "text", "image", "category", "type", "correct_answer", "hotspots", "explanation", "explanation_image", "rule_ref", "status"}
+	for i := 0; i < maxVariants; i++ {
+		header = append(header, "variant_"+strings.ToLower(string(variantLetters[i])))
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, item := range items {
+		record := []string{strconv.Itoa(item.Number), item.Text, item.Image, item.Category, item.Type, item.CorrectAnswer, item.Hotspots, item.Explanation, item.ExplanationImage, item.RuleRef, item.Status}
+		for i := 0; i < maxVariants; i++ {
+			v := ""
+			if i < len(item.Variants) {
+				v = item.Variants[i]
+			}
+			record = append(record, v)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func addMediaToZip(zw *zip.Writer, name, src string) error {
+	f, _, err := mediaStore.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, f)
+	return err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HandbookChapter groups the articles of the traffic rules. Articles is
+// filled by GetHandbookChapters, without the article bodies.
+type HandbookChapter struct {
+	ID        int
+	Number    int
+	Title     string
+	CreatedAt time.Time
+	Articles  []*HandbookArticle
+}
+
+// HandbookArticle is one article of the rules. Number is the label printed
+// in the rules ("10.1"); Position orders articles inside their chapter.
+type HandbookArticle struct {
+	ID            int
+	ChapterID     int
+	Number        string
+	Title         string
+	Body          string
+	Position      int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ChapterNumber int
+	ChapterTitle  string
+}
+
+// RoadSign is an entry of the road-sign catalog. The group is the part of
+// Code before the first dot, as in the official numbering.
+type RoadSign struct {
+	ID          int
+	Code        string
+	Name        string
+	Description string
+	Image       string
+	CreatedAt   time.Time
+}
+
+// QuestionStat is how often one user answered a question and how often
+// correctly.
+type QuestionStat struct {
+	QuestionID int
+	Answered   int
+	Correct    int
+}
+
+func (s *QuestionStat) Percent() int {
+	if s == nil || s.Answered == 0 {
+		return 0
+	}
+	return s.Correct * 100 / s.Answered
+}
+
+// roadSignGroups are the code prefixes of the sign groups; each is named
+// by the "sign.group_<prefix>" message.
+var roadSignGroups = map[string]bool{
+	"1": true, "2": true, "3": true, "4": true, "5": true, "6": true, "7": true,
+}
+
+func (s *RoadSign) Group() string {
+	prefix, _, _ := strings.Cut(s.Code, ".")
+	return prefix
+}
+
+// GroupName is the message key naming the sign's group.
+func (s *RoadSign) GroupName() string {
+	if roadSignGroups[s.Group()] {
+		return "sign.group_" + s.Group()
+	}
+	return "sign.group_other"
+}
+
+// compareCodes orders dotted codes such as "1.2" and "1.10" part by part,
+// numerically where both parts are numbers.
+func compareCodes(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			return na - nb
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+func sortRoadSigns(signs []*RoadSign) {
+	sort.Slice(signs, func(i, j int) bool { return compareCodes(signs[i].Code, signs[j].Code) < 0 })
+}
+
+// roadSignGroup is one group of the catalog page.
+type roadSignGroup struct {
+	Name  string
+	Signs []*RoadSign
+}
+
+// groupRoadSigns splits sorted signs into their groups, keeping the order.
+func groupRoadSigns(signs []*RoadSign) []*roadSignGroup {
+	var groups []*roadSignGroup
+	for _, s := range signs {
+		if n := len(groups); n == 0 || groups[n-1].Name != s.GroupName() {
+			groups = append(groups, &roadSignGroup{Name: s.GroupName()})
+		}
+		groups[len(groups)-1].Signs = append(groups[len(groups)-1].Signs, s)
+	}
+	return groups
+}
+
+// searchTerms splits a search query into lower-cased words; every word has
+// to match for a hit.
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// searchSnippet returns about width characters of text around the first
+// search term, so results show why they matched.
+func searchSnippet(text string, terms []string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(text)
+	start := 0
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i >= 0 {
+			start = min(i, len(text))
+			break
+		}
+	}
+	// Step back to a word boundary a little before the match.
+	from := max(0, start-width/3)
+	for from > 0 && text[from-1] != ' ' {
+		from--
+	}
+	snippet := text[from:]
+	if utf8.RuneCountInString(snippet) > width {
+		snippet = string([]rune(snippet)[:width]) + "…"
+	}
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	return snippet
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareCodes(t *testing.T) {
+	codes := []string{"1.10", "2.1", "1.2", "1.2.1", "5.a", "1.1", "10.1", "5.b"}
+	slices.SortFunc(codes, compareCodes)
+	if want := []string{"1.1", "1.2", "1.2.1", "1.10", "2.1", "5.a", "5.b", "10.1"}; !slices.Equal(codes, want) {
+		t.Errorf("sorted %v, want %v", codes, want)
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	text := "Drivers must give way to pedestrians at crossings. " +
+		"At night the headlights must be on, and the horn may only be used to prevent an accident."
+	if got := searchSnippet(text, []string{"horn"}, 40); got != "…be on, and the horn may only be used to …" {
+		t.Errorf("snippet %q", got)
+	}
+	if got := searchSnippet(text, []string{"missing"}, 20); got != "Drivers must give wa…" {
+		t.Errorf("snippet without a match %q", got)
+	}
+}
+
+func TestHandbookStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		second := &HandbookChapter{Number: 2, Title: "Signals"}
+		first := &HandbookChapter{Number: 1, Title: "General"}
+		for _, c := range []*HandbookChapter{second, first} {
+			if err := db.CreateHandbookChapter(c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.CreateHandbookChapter(&HandbookChapter{Number: 1, Title: "Again"}); err == nil {
+			t.Error("two chapters share number 1")
+		}
+		articles := []*HandbookArticle{
+			{ChapterID: first.ID, Number: "1.2", Title: "Terms", Body: "A crossing is where roads meet.", Position: 2},
+			{ChapterID: first.ID, Number: "1.1", Title: "Scope", Body: "These rules apply on every road.", Position: 1},
+			{ChapterID: second.ID, Number: "2.1", Title: "Lights", Body: "A flashing yellow light warns of a crossing.", Position: 1},
+		}
+		for _, a := range articles {
+			if err := db.CreateHandbookArticle(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var order []string
+		for _, c := range db.GetHandbookChapters() {
+			for _, a := range c.Articles {
+				order = append(order, a.Number)
+			}
+		}
+		if want := []string{"1.1", "1.2", "2.1"}; !slices.Equal(order, want) {
+			t.Errorf("articles %v, want %v", order, want)
+		}
+
+		var hits []string
+		for _, a := range db.SearchHandbookArticles("CROSSING yellow") {
+			hits = append(hits, a.Number)
+		}
+		if !slices.Equal(hits, []string{"2.1"}) {
+			t.Errorf("search hits %v, want every term to match", hits)
+		}
+		if got := db.SearchHandbookArticles("  "); len(got) != 0 {
+			t.Errorf("empty search found %d articles", len(got))
+		}
+
+		q := &Question{Number: 1, Text: "What does a flashing yellow light mean?", CorrectAnswer: "A",
+			VariantsList: []Variant{{Letter: "A", Text: "Caution"}, {Letter: "B", Text: "Stop"}}}
+		if err := db.CreateQuestion(q, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetQuestionArticles(q.ID, []int{articles[2].ID}); err != nil {
+			t.Fatal(err)
+		}
+		if got := db.GetArticleQuestions(articles[2].ID); len(got) != 1 || got[0].ID != q.ID {
+			t.Errorf("article questions %+v", got)
+		}
+
+		// Deleting a chapter takes its articles and their links with it.
+		if err := db.DeleteHandbookChapter(second.ID); err != nil {
+			t.Fatal(err)
+		}
+		if db.GetHandbookArticle(articles[2].ID) != nil || len(db.GetQuestionArticles(q.ID)) != 0 {
+			t.Error("articles of a deleted chapter are still there")
+		}
+		if len(db.GetHandbookChapters()) != 1 || db.GetHandbookArticle(articles[0].ID) == nil {
+			t.Error("deleting a chapter touched another")
+		}
+
+		for _, s := range []*RoadSign{{Code: "1.10", Name: "Slippery road"}, {Code: "1.2", Name: "Level crossing"}, {Code: "2.1", Name: "Main road"}} {
+			if err := db.CreateRoadSign(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.CreateRoadSign(&RoadSign{Code: "1.2", Name: "Copy"}); err == nil {
+			t.Error("two signs share code 1.2")
+		}
+		var codes []string
+		for _, s := range db.GetAllRoadSigns() {
+			codes = append(codes, s.Code)
+		}
+		if want := []string{"1.2", "1.10", "2.1"}; !slices.Equal(codes, want) {
+			t.Errorf("signs %v, want %v", codes, want)
+		}
+		if got := db.SearchRoadSigns("road"); len(got) != 2 || got[0].Code != "1.10" {
+			t.Errorf("sign search %+v", got)
+		}
+	})
+}
@@ -2,10 +2,14 @@ package main
 
 import (
         "encoding/json"
+        "errors"
         "fmt"
+        "log"
         "net/http"
+        "slices"
         "strconv"
         "strings"
+        "time"
 
         "github.com/gorilla/mux"
 )
@@ -39,7 +43,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
         if r.Method == "POST" {
                 r.ParseForm()
                 if !verifyCSRFToken(r, w) {
-                        data["Error"] = "Xavfsizlik xatosi. Qayta urinib ko'ring."
+                        data["Error"] = tr(r, "error.csrf")
                         renderTemplate(w, r, "login.html", data)
                         return
                 }
@@ -55,7 +59,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
                         }
                         return
                 }
-                data["Error"] = "Login yoki parol xato!"
+                data["Error"] = tr(r, "error.login_failed")
         }
 
         renderTemplate(w, r, "login.html", data)
@@ -68,12 +72,12 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 
 func dashboardHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
-        totalQuestions := countQuestions()
-        bookmarkCount := countBookmarks(user.ID)
-        testCount := countUserCompletedSessions(user.ID)
+        totalQuestions := db.CountQuestionsByStatus()[questionPublished]
+        bookmarkCount := db.CountBookmarks(user.ID)
+        testCount := db.CountUserCompletedSessions(user.ID)
 
         avgScore := 0
-        sessions := getUserCompletedSessions(user.ID)
+        sessions := db.GetUserCompletedSessions(user.ID)
         if len(sessions) > 0 {
                 total := 0
                 for _, s := range sessions {
@@ -88,61 +92,106 @@ func dashboardHandler(w http.ResponseWriter, r *http.Request) {
                 "BookmarkCount":  bookmarkCount,
                 "TestCount":      testCount,
                 "AvgScore":       avgScore,
+                "Unfinished":     db.GetUnfinishedSessions(user.ID),
+                "DueToday":       db.CountDueQuestions(user.ID, endOfDay(time.Now())),
         })
 }
 
+// parseIDs converts repeated form or query values into positive integer ids,
+// ignoring anything that does not parse.
+func parseIDs(values []string) []int {
+        var ids []int
+        for _, v := range values {
+                if id, err := strconv.Atoi(v); err == nil && id > 0 {
+                        ids = append(ids, id)
+                }
+        }
+        return ids
+}
+
+func idSet(ids []int) map[int]bool {
+        set := make(map[int]bool, len(ids))
+        for _, id := range ids {
+                set[id] = true
+        }
+        return set
+}
+
+func categoryNames(categories []*Category) map[int]string {
+        names := make(map[int]string, len(categories))
+        for _, c := range categories {
+                names[c.ID] = c.Name
+        }
+        return names
+}
+
 func allQuestionsHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
-        questions := getAllQuestions()
-        userBookmarks := getUserBookmarkIDs(user.ID)
+        categoryIDs := parseIDs(r.URL.Query()["category"])
+        questions := db.SearchQuestions(QuestionFilter{CategoryIDs: categoryIDs, Status: questionPublished})
+        localizeQuestions(userContentLang(user), questions)
+        userBookmarks := db.GetUserBookmarkIDs(user.ID)
+        categories := db.GetAllCategories()
 
         renderTemplate(w, r, "all_questions.html", map[string]interface{}{
-                "CurrentPage":   "all_questions",
-                "Questions":     questions,
-                "UserBookmarks": userBookmarks,
+                "CurrentPage":        "all_questions",
+                "Questions":          questions,
+                "UserBookmarks":      userBookmarks,
+                "Categories":         categories,
+                "CategoryNames":      categoryNames(categories),
+                "SelectedCategories": idSet(categoryIDs),
         })
 }
 
 func questionDetailHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        question := getQuestionByID(id)
-        if question == nil {
+        question := db.GetQuestionByID(id)
+        if question == nil || (!question.IsPublished() && !user.IsStaff) {
                 http.NotFound(w, r)
                 return
         }
-        bookmarks := getUserBookmarkIDs(user.ID)
+        localizeQuestions(userContentLang(user), []*Question{question})
+        bookmarks := db.GetUserBookmarkIDs(user.ID)
 
         renderTemplate(w, r, "question_detail.html", map[string]interface{}{
                 "CurrentPage":  "all_questions",
                 "QuestionData": question,
                 "IsBookmarked": bookmarks[question.ID],
+                "Articles":     db.GetQuestionArticles(question.ID),
         })
 }
 
 func searchQuestionsHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         query := r.URL.Query().Get("q")
+        categoryIDs := parseIDs(r.URL.Query()["category"])
         var questions []*Question
-        if query != "" {
-                questions = searchQuestions(query)
+        if query != "" || len(categoryIDs) > 0 {
+                questions = db.SearchQuestions(QuestionFilter{Query: query, CategoryIDs: categoryIDs, Status: questionPublished})
         } else {
                 questions = []*Question{}
         }
-        userBookmarks := getUserBookmarkIDs(user.ID)
+        localizeQuestions(userContentLang(user), questions)
+        userBookmarks := db.GetUserBookmarkIDs(user.ID)
+        categories := db.GetAllCategories()
 
         renderTemplate(w, r, "search.html", map[string]interface{}{
-                "CurrentPage":   "search_questions",
-                "Questions":     questions,
-                "Query":         query,
-                "UserBookmarks": userBookmarks,
+                "CurrentPage":        "search_questions",
+                "Questions":          questions,
+                "Query":              query,
+                "UserBookmarks":      userBookmarks,
+                "Categories":         categories,
+                "CategoryNames":      categoryNames(categories),
+                "SelectedCategories": idSet(categoryIDs),
+                "Filtered":           query != "" || len(categoryIDs) > 0,
         })
 }
 
 func toggleBookmarkHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        status := toggleBookmark(user.ID, id)
+        status := db.ToggleBookmark(user.ID, id)
 
         if r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
                 w.Header().Set("Content-Type", "application/json")
@@ -158,7 +207,8 @@ func toggleBookmarkHandler(w http.ResponseWriter, r *http.Request) {
 
 func bookmarksHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
-        questions := getBookmarkedQuestions(user.ID)
+        questions := db.GetBookmarkedQuestions(user.ID)
+        localizeQuestions(userContentLang(user), questions)
 
         renderTemplate(w, r, "bookmarks.html", map[string]interface{}{
                 "CurrentPage": "bookmarks",
@@ -168,7 +218,19 @@ func bookmarksHandler(w http.ResponseWriter, r *http.Request) {
 
 func startTestHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
-        totalAvailable := countQuestions()
+        totalAvailable := db.CountQuestionsByStatus()[questionPublished]
+        data := map[string]interface{}{
+                "CurrentPage":    "start_test",
+                "TotalAvailable": totalAvailable,
+                "Categories":     db.GetAllCategories(),
+                "ExamRules":      getExamRules(),
+                "Source":         testSourceRandom,
+                "SourceCounts": map[string]int{
+                        testSourceMistakes:   len(db.GetMistakeQuestionIDs(user.ID, getMistakesClearStreak())),
+                        testSourceBookmarks:  db.CountBookmarks(user.ID),
+                        testSourceUnanswered: len(db.GetUnansweredQuestionIDs(user.ID)),
+                },
+        }
 
         if r.Method == "POST" {
                 r.ParseForm()
@@ -183,9 +245,38 @@ func startTestHandler(w http.ResponseWriter, r *http.Request) {
                 if numQuestions > totalAvailable {
                         numQuestions = totalAvailable
                 }
-                questionIDs := getRandomQuestionIDs(numQuestions)
-                session, err := createTestSession(user.ID, numQuestions, questionIDs)
-                if err != nil {
+                categoryIDs := parseIDs(r.Form["categories"])
+                source := r.FormValue("source")
+                exam := r.FormValue("mode") == sessionModeExam
+                rules := getExamRules()
+                if exam {
+                        numQuestions = rules.QuestionCount
+                        categoryIDs = nil
+                        source = testSourceRandom
+                }
+                questionIDs := questionIDsForSource(user.ID, source, numQuestions, categoryIDs)
+                if len(questionIDs) == 0 {
+                        data["Error"] = tr(r, "error.no_questions_in_topics")
+                        if source != "" && source != testSourceRandom {
+                                data["Error"] = tr(r, "error.no_questions_in_source")
+                        }
+                        data["SelectedCategories"] = idSet(categoryIDs)
+                        data["Source"] = source
+                        data["ShuffleVariants"] = r.FormValue("shuffle_variants") == "on"
+                        renderTemplate(w, r, "start_test.html", data)
+                        return
+                }
+                session := newTestSession(user.ID, len(questionIDs))
+                if exam {
+                        session.Mode = sessionModeExam
+                        session.TimeLimit = rules.TimeLimit * 60
+                        session.MaxMistakes = rules.MaxMistakes
+                        session.HideFeedback = rules.HideFeedback
+                }
+                if (exam && rules.ShuffleVariants) || (!exam && r.FormValue("shuffle_variants") == "on") {
+                        session.ShuffleSeed = newShuffleSeed()
+                }
+                if err := db.CreateTestSession(session, questionIDs); err != nil {
                         http.Error(w, "Error creating test session", 500)
                         return
                 }
@@ -193,20 +284,57 @@ func startTestHandler(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        renderTemplate(w, r, "start_test.html", map[string]interface{}{
-                "CurrentPage":    "start_test",
-                "TotalAvailable": totalAvailable,
-        })
+        renderTemplate(w, r, "start_test.html", data)
+}
+
+// questionIDsForSource picks up to limit question ids for a new test from
+// the chosen source, restricted to categoryIDs when any are given.
+func questionIDsForSource(userID int, source string, limit int, categoryIDs []int) []int {
+        var ids []int
+        switch source {
+        case testSourceMistakes:
+                ids = db.GetMistakeQuestionIDs(userID, getMistakesClearStreak())
+        case testSourceBookmarks:
+                for id := range db.GetUserBookmarkIDs(userID) {
+                        ids = append(ids, id)
+                }
+        case testSourceUnanswered:
+                ids = db.GetUnansweredQuestionIDs(userID)
+        case testSourceWeakest:
+                ids = db.GetWeakestQuestionIDs(userID, db.CountQuestions())
+        default:
+                return db.GetRandomQuestionIDs(limit, categoryIDs)
+        }
+
+        if len(categoryIDs) > 0 {
+                qMap := db.GetQuestionsByIDs(ids)
+                filtered := ids[:0]
+                for _, id := range ids {
+                        if q, ok := qMap[id]; ok && slices.Contains(categoryIDs, q.CategoryID) {
+                                filtered = append(filtered, id)
+                        }
+                }
+                ids = filtered
+        }
+        // The weakest questions are taken in rank order, then shuffled like
+        // every other source.
+        if source == testSourceWeakest && len(ids) > limit {
+                ids = ids[:limit]
+        }
+        return pickRandomIDs(ids, limit)
 }
 
 func takeTestHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        session := getTestSession(id, user.ID)
+        session := db.GetTestSession(id, user.ID)
         if session == nil {
                 http.Redirect(w, r, "/test/start/", http.StatusFound)
                 return
         }
+        if session.Stale(time.Now()) {
+                gradeSession(session, nil)
+        }
         if session.Completed {
                 http.Redirect(w, r, fmt.Sprintf("/test/%d/result/", session.ID), http.StatusFound)
                 return
@@ -214,29 +342,78 @@ func takeTestHandler(w http.ResponseWriter, r *http.Request) {
 
         var questionIDs []int
         json.Unmarshal([]byte(session.QuestionIDs), &questionIDs)
-        qMap := getQuestionsByIDs(questionIDs)
+        qMap := db.GetQuestionsByIDs(questionIDs)
+        localizeQuestionMap(userContentLang(user), qMap)
         var orderedQuestions []*Question
         for _, qid := range questionIDs {
                 if q, ok := qMap[qid]; ok {
+                        q.VariantsList = session.DisplayVariants(q)
                         orderedQuestions = append(orderedQuestions, q)
                 }
         }
 
-        timeLimit := session.TotalQuestions * 60
+        // Restore saved answers and continue from the first unanswered
+        // question.
+        answers := make(map[int]*TestAnswer)
+        correctCount, wrongCount := 0, 0
+        for _, a := range getSessionAnswers(session, userContentLang(user)) {
+                answers[a.QuestionID] = a
+                if a.IsCorrect {
+                        correctCount++
+                } else {
+                        wrongCount++
+                }
+        }
+        if session.HideFeedback {
+                correctCount, wrongCount = 0, 0
+        }
+        startIndex := 0
+        for i, q := range orderedQuestions {
+                startIndex = i
+                if _, ok := answers[q.ID]; !ok {
+                        break
+                }
+        }
+
+        timeLimit := session.RemainingSeconds(time.Now())
+        if session.Deadline().IsZero() {
+                timeLimit = session.TotalQuestions * 60
+        }
 
         renderTemplate(w, r, "take_test.html", map[string]interface{}{
-                "CurrentPage":    "start_test",
-                "Session":        session,
-                "Questions":      orderedQuestions,
-                "TimeLimit":      timeLimit,
-                "TotalQuestions": len(orderedQuestions),
+                "CurrentPage":      "start_test",
+                "Session":          session,
+                "Questions":        orderedQuestions,
+                "TimeLimit":        timeLimit,
+                "TotalQuestions":   len(orderedQuestions),
+                "Answers":          answers,
+                "StartIndex":       startIndex,
+                "CorrectCount":     correctCount,
+                "WrongCount":       wrongCount,
+                "ShowExplanations": !session.IsExam() && !session.HideFeedback && showPracticeExplanations(),
         })
 }
 
+func abandonTestHandler(w http.ResponseWriter, r *http.Request) {
+        user := getCurrentUser(r)
+        id, _ := strconv.Atoi(mux.Vars(r)["id"])
+        if r.Method == "POST" {
+                r.ParseForm()
+                if !verifyCSRFToken(r, w) {
+                        http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                        return
+                }
+                if session := db.GetTestSession(id, user.ID); session != nil && !session.Completed {
+                        db.DeleteTestSession(session.ID, user.ID)
+                }
+        }
+        http.Redirect(w, r, "/dashboard/", http.StatusFound)
+}
+
 func submitTestHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        session := getTestSession(id, user.ID)
+        session := db.GetTestSession(id, user.ID)
         if session == nil {
                 http.Redirect(w, r, "/test/start/", http.StatusFound)
                 return
@@ -252,72 +429,273 @@ func submitTestHandler(w http.ResponseWriter, r *http.Request) {
                         http.Error(w, "CSRF token invalid", http.StatusForbidden)
                         return
                 }
-                timeSpent, _ := strconv.Atoi(r.FormValue("time_spent"))
+                // Answers already recorded through answerTestHandler win; the
+                // form only fills in the rest. Anything posted after the
+                // deadline is discarded.
+                var answers map[int]string
+                if !session.Expired(time.Now()) {
+                        answers = make(map[int]string)
+                        qMap := db.GetQuestionsByIDs(session.QuestionIDList())
+                        for qid, q := range qMap {
+                                answers[qid] = session.CanonicalAnswer(q, r.FormValue(fmt.Sprintf("answer_%d", qid)))
+                        }
+                }
+                gradeSession(session, answers)
 
-                var questionIDs []int
-                json.Unmarshal([]byte(session.QuestionIDs), &questionIDs)
-                qMap := getQuestionsByIDs(questionIDs)
+                http.Redirect(w, r, fmt.Sprintf("/test/%d/result/", session.ID), http.StatusFound)
+                return
+        }
 
-                correct := 0
-                wrong := 0
-                for _, qid := range questionIDs {
-                        answer := strings.ToUpper(r.FormValue(fmt.Sprintf("answer_%d", qid)))
-                        q, ok := qMap[qid]
-                        if !ok {
-                                continue
-                        }
-                        if answer != "" {
-                                isCorrect := answer == q.CorrectAnswer
-                                if isCorrect {
-                                        correct++
-                                } else {
-                                        wrong++
-                                }
-                                createTestAnswer(session.ID, qid, answer, isCorrect)
-                        } else {
-                                wrong++
-                                createTestAnswer(session.ID, qid, "", false)
+        http.Redirect(w, r, fmt.Sprintf("/test/%d/", session.ID), http.StatusFound)
+}
+
+// newTestSession prepares a practice session started now. Practice has no
+// server deadline; the test page only shows a clock of one minute per
+// question.
+func newTestSession(userID, totalQuestions int) *TestSession {
+        return &TestSession{
+                UserID:         userID,
+                TotalQuestions: totalQuestions,
+                Mode:           sessionModePractice,
+                StartedAt:      time.Now().UTC(),
+        }
+}
+
+// errAnswerRecorded is returned by recordTestAnswer when the session
+// already has an answer to the question, such as from a double submit.
+var errAnswerRecorded = errors.New("answer already recorded")
+
+// recordTestAnswer scores and stores an answer and, unless the question was
+// skipped, feeds it into the user's spaced-repetition schedule. Only a fully
+// correct answer counts as correct there.
+func recordTestAnswer(session *TestSession, q *Question, answer string) (*TestAnswer, error) {
+        credit := q.Credit(answer)
+        a := &TestAnswer{QuestionID: q.ID, SelectedAnswer: answer, IsCorrect: credit == 1, Credit: credit}
+        if err := db.CreateTestAnswer(session.ID, q.ID, answer, a.IsCorrect, credit); err != nil {
+                // Both stores refuse a second answer to a question; the
+                // answer being there tells that apart from a failed write.
+                for _, stored := range db.GetSessionAnswers(session.ID) {
+                        if stored.QuestionID == q.ID {
+                                return nil, errAnswerRecorded
                         }
                 }
+                return nil, err
+        }
+        if answer == "" {
+                return a, nil
+        }
+        st := db.GetQuestionState(session.UserID, q.ID)
+        if st == nil {
+                st = &QuestionState{UserID: session.UserID, QuestionID: q.ID}
+        }
+        st.Review(a.IsCorrect, time.Now())
+        return a, db.SaveQuestionState(st)
+}
 
-                session.CorrectAnswers = correct
-                session.WrongAnswers = wrong
-                session.TimeSpent = timeSpent
-                session.Completed = true
-                updateTestSession(session)
+// getSessionAnswers loads a session's answers with each question shown in
+// lang, its variants in the order the student saw them and DisplayedAnswer
+// set.
+func getSessionAnswers(session *TestSession, lang string) []*TestAnswer {
+        answers := db.GetSessionAnswers(session.ID)
+        var questions []*Question
+        for _, a := range answers {
+                if a.Question != nil {
+                        questions = append(questions, a.Question)
+                }
+        }
+        localizeQuestions(lang, questions)
+        for _, a := range answers {
+                if a.Question == nil {
+                        continue
+                }
+                a.DisplayedAnswer = session.DisplayAnswer(a.Question, a.SelectedAnswer)
+                a.Question.VariantsList = session.DisplayVariants(a.Question)
+        }
+        return answers
+}
 
-                http.Redirect(w, r, fmt.Sprintf("/test/%d/result/", session.ID), http.StatusFound)
+// gradeSession closes the session: questions not answered yet get the
+// posted answer, already mapped to canonical letters, or an empty wrong one, and the score and verdict are stored.
+func gradeSession(session *TestSession, answers map[int]string) {
+        questionIDs := session.QuestionIDList()
+        qMap := db.GetQuestionsByIDs(questionIDs)
+        recorded := make(map[int]*TestAnswer)
+        for _, a := range db.GetSessionAnswers(session.ID) {
+                recorded[a.QuestionID] = a
+        }
+
+        correct := 0
+        wrong := 0
+        points := 0.0
+        for _, qid := range questionIDs {
+                q, ok := qMap[qid]
+                if !ok {
+                        continue
+                }
+                a, ok := recorded[qid]
+                if !ok {
+                        a, _ = recordTestAnswer(session, q, answers[qid])
+                }
+                if a == nil {
+                        wrong++
+                        continue
+                }
+                if a.IsCorrect {
+                        correct++
+                } else {
+                        wrong++
+                }
+                points += a.Credit
+        }
+
+        session.Finish(correct, wrong, points, time.Now())
+        db.UpdateTestSession(session)
+}
+
+// answerTestHandler records and checks a single answer. Correctness is
+// returned only once the answer is stored, and not at all while an exam
+// hides feedback. An exam that runs out of time or allowed mistakes is closed
+// and the client is told to go to the result page.
+func answerTestHandler(w http.ResponseWriter, r *http.Request) {
+        user := getCurrentUser(r)
+        id, _ := strconv.Atoi(mux.Vars(r)["id"])
+        if r.Method != "POST" {
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+        r.ParseForm()
+        if !verifyCSRFToken(r, w) {
+                http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                return
+        }
+        session := db.GetTestSession(id, user.ID)
+        if session == nil {
+                http.NotFound(w, r)
                 return
         }
 
-        http.Redirect(w, r, fmt.Sprintf("/test/%d/", session.ID), http.StatusFound)
+        resultURL := fmt.Sprintf("/test/%d/result/", session.ID)
+        w.Header().Set("Content-Type", "application/json")
+        if !session.Completed && session.Expired(time.Now()) {
+                gradeSession(session, nil)
+        }
+        if session.Completed {
+                json.NewEncoder(w).Encode(map[string]interface{}{"finished": true, "redirect": resultURL})
+                return
+        }
+
+        questionID, _ := strconv.Atoi(r.FormValue("question_id"))
+        if !slices.Contains(session.QuestionIDList(), questionID) {
+                w.WriteHeader(http.StatusBadRequest)
+                json.NewEncoder(w).Encode(map[string]interface{}{"error": tr(r, "error.bad_request")})
+                return
+        }
+        q := db.GetQuestionByID(questionID)
+        if q == nil {
+                http.NotFound(w, r)
+                return
+        }
+        answer := session.CanonicalAnswer(q, r.FormValue("answer"))
+        if answer == "" {
+                w.WriteHeader(http.StatusBadRequest)
+                json.NewEncoder(w).Encode(map[string]interface{}{"error": tr(r, "error.bad_request")})
+                return
+        }
+
+        answers := db.GetSessionAnswers(session.ID)
+        var stored *TestAnswer
+        for _, a := range answers {
+                if a.QuestionID == questionID {
+                        stored = a
+                }
+        }
+        if stored == nil {
+                a, err := recordTestAnswer(session, q, answer)
+                if errors.Is(err, errAnswerRecorded) {
+                        w.WriteHeader(http.StatusConflict)
+                        json.NewEncoder(w).Encode(map[string]interface{}{"error": tr(r, "error.answer_already_recorded")})
+                        return
+                }
+                if err != nil {
+                        log.Printf("Error recording answer to question %d in session %d: %v", questionID, session.ID, err)
+                        w.WriteHeader(http.StatusInternalServerError)
+                        json.NewEncoder(w).Encode(map[string]interface{}{"error": tr(r, "error.save_failed")})
+                        return
+                }
+                stored = a
+                answers = append(answers, stored)
+        }
+
+        wrong := 0
+        for _, a := range answers {
+                if !a.IsCorrect {
+                        wrong++
+                }
+        }
+        finished := false
+        if session.IsExam() && wrong > session.MaxMistakes {
+                gradeSession(session, nil)
+                finished = true
+        }
+
+        resp := map[string]interface{}{
+                "recorded": true,
+                "answer":   session.DisplayAnswer(q, stored.SelectedAnswer),
+                "finished": finished,
+        }
+        if finished {
+                resp["redirect"] = resultURL
+        }
+        if !session.HideFeedback {
+                resp["correct"] = stored.IsCorrect
+                resp["credit"] = stored.CreditPercent()
+                if stored.IsPartial() {
+                        resp["partial"] = tr(r, "test.partial", stored.CreditPercent())
+                }
+                resp["correct_answer"] = session.DisplayAnswer(q, q.CorrectAnswer)
+                if q.IsHotspot() {
+                        resp["hotspots"] = q.Hotspots
+                }
+                if !session.IsExam() && q.HasExplanation() && showPracticeExplanations() {
+                        localizeQuestions(userContentLang(user), []*Question{q})
+                        resp["explanation"] = renderExplanation(q, requestLocale(r))
+                }
+        }
+        json.NewEncoder(w).Encode(resp)
 }
 
 func testResultHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        session := getTestSession(id, user.ID)
+        session := db.GetTestSession(id, user.ID)
         if session == nil {
                 http.Redirect(w, r, "/test/start/", http.StatusFound)
                 return
         }
-        answers := getSessionAnswers(session.ID)
+        answers := getSessionAnswers(session, userContentLang(user))
+        var ticket *Ticket
+        if session.TicketID != 0 {
+                ticket = db.GetTicketByID(session.TicketID)
+        }
 
         renderTemplate(w, r, "test_result.html", map[string]interface{}{
                 "CurrentPage": "start_test",
                 "Session":     session,
                 "Answers":     answers,
+                "Ticket":      ticket,
         })
 }
 
 func statisticsHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
-        sessions := getUserCompletedSessions(user.ID)
+        sessions := db.GetUserCompletedSessions(user.ID)
 
         totalTests := len(sessions)
         avgScore := 0
         bestScore := 0
         totalCorrect := 0
+        examsTaken := 0
+        examsPassed := 0
 
         if totalTests > 0 {
                 totalScore := 0
@@ -327,6 +705,12 @@ func statisticsHandler(w http.ResponseWriter, r *http.Request) {
                                 bestScore = s.ScorePercent
                         }
                         totalCorrect += s.CorrectAnswers
+                        if s.IsExam() {
+                                examsTaken++
+                                if s.Passed {
+                                        examsPassed++
+                                }
+                        }
                 }
                 avgScore = totalScore / totalTests
         }
@@ -338,20 +722,38 @@ func statisticsHandler(w http.ResponseWriter, r *http.Request) {
                 recentSessions = sessions
         }
 
+        tickets := db.GetAllTickets()
+        ticketStats := db.GetTicketStats(user.ID)
+        ticketNumbers := make(map[int]int, len(tickets))
+        passedTickets := 0
+        for _, t := range tickets {
+                ticketNumbers[t.ID] = t.Number
+                if st, ok := ticketStats[t.ID]; ok && st.Passed > 0 {
+                        passedTickets++
+                }
+        }
+
         renderTemplate(w, r, "statistics.html", map[string]interface{}{
                 "CurrentPage":    "statistics",
                 "TotalTests":     totalTests,
                 "AvgScore":       avgScore,
                 "BestScore":      bestScore,
                 "TotalCorrect":   totalCorrect,
+                "ExamsTaken":     examsTaken,
+                "ExamsPassed":    examsPassed,
                 "RecentSessions": recentSessions,
+                "Tickets":        tickets,
+                "TicketStats":    ticketStats,
+                "TicketNumbers":  ticketNumbers,
+                "PassedTickets":  passedTickets,
         })
 }
 
 func profileHandler(w http.ResponseWriter, r *http.Request) {
         user := getCurrentUser(r)
         data := map[string]interface{}{
-                "CurrentPage": "profile",
+                "CurrentPage":      "profile",
+                "ContentLanguages": contentLanguages,
         }
 
         if r.Method == "POST" {
@@ -365,10 +767,10 @@ func profileHandler(w http.ResponseWriter, r *http.Request) {
                 changed := false
 
                 if newUsername != "" && newUsername != user.Username {
-                        if usernameExists(newUsername, user.ID) {
-                                data["Error"] = "Bu login allaqachon mavjud!"
+                        if db.UsernameExists(newUsername, user.ID) {
+                                data["Error"] = tr(r, "error.username_taken")
                         } else {
-                                updateUserUsername(user.ID, newUsername)
+                                db.UpdateUserUsername(user.ID, newUsername)
                                 changed = true
                         }
                 }
@@ -378,24 +780,35 @@ func profileHandler(w http.ResponseWriter, r *http.Request) {
                         changed = true
                 }
 
+                if lang := r.FormValue("content_lang"); isContentLang(lang) && lang != userContentLang(user) && data["Error"] == nil {
+                        db.UpdateUserContentLang(user.ID, lang)
+                        changed = true
+                }
+                if locale := r.FormValue("locale"); isUILocale(locale) && locale != user.Locale && data["Error"] == nil {
+                        db.UpdateUserLocale(user.ID, locale)
+                        changed = true
+                }
+
                 if changed && data["Error"] == nil {
-                        data["Success"] = "Ma'lumotlar muvaffaqiyatli yangilandi!"
-                        user = getUserByID(user.ID)
+                        data["Success"] = tr(r, "success.profile_updated")
+                        user = db.GetUserByID(user.ID)
                 }
         }
 
+        data["ContentLang"] = userContentLang(user)
         renderTemplate(w, r, "profile.html", data)
 }
 
 func adminDashboardHandler(w http.ResponseWriter, r *http.Request) {
-        totalQuestions := countQuestions()
-        totalUsers := countNonStaffUsers()
-        totalTests := countCompletedSessions()
-        recentTests := getRecentCompletedSessions(5)
+        totalQuestions := db.CountQuestions()
+        totalUsers := db.CountNonStaffUsers()
+        totalTests := db.CountCompletedSessions()
+        recentTests := db.GetRecentCompletedSessions(5)
 
         renderTemplate(w, r, "admin/dashboard.html", map[string]interface{}{
                 "CurrentPage":    "admin_dashboard",
                 "TotalQuestions": totalQuestions,
+                "InReview":       db.CountQuestionsByStatus()[questionInReview],
                 "TotalUsers":     totalUsers,
                 "TotalTests":     totalTests,
                 "RecentTests":    recentTests,
@@ -403,10 +816,18 @@ func adminDashboardHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func adminQuestionsHandler(w http.ResponseWriter, r *http.Request) {
-        questions := getAllQuestions()
+        status := r.URL.Query().Get("status")
+        if !slices.Contains(questionStatuses, status) {
+                status = ""
+        }
+        questions := db.SearchQuestions(QuestionFilter{Status: status})
         renderTemplate(w, r, "admin/questions.html", map[string]interface{}{
-                "CurrentPage": "admin_questions",
-                "Questions":   questions,
+                "CurrentPage":   "admin_questions",
+                "Questions":     questions,
+                "CategoryNames": categoryNames(db.GetAllCategories()),
+                "Status":        status,
+                "Statuses":      questionStatuses,
+                "StatusCounts":  db.CountQuestionsByStatus(),
         })
 }
 
@@ -423,6 +844,61 @@ func parseVariantsFromForm(r *http.Request) []Variant {
         return variants
 }
 
+// parseExplanationFromForm reads the explanation fields of the question
+// form into q.
+func parseExplanationFromForm(r *http.Request, q *Question) {
+        q.Explanation = strings.TrimSpace(r.FormValue("explanation"))
+        q.RuleRef = strings.TrimSpace(r.FormValue("rule_ref"))
+}
+
+// saveQuestionImagesFromForm stores the images uploaded with the question
+// form into q and applies the remove checkboxes. The error is the first
+// upload that is not a usable image.
+func saveQuestionImagesFromForm(r *http.Request, q *Question) error {
+        for _, field := range []struct {
+                name   string
+                remove string
+                image  *string
+        }{
+                {"image", "remove_image", &q.Image},
+                {"explanation_image", "remove_explanation_image", &q.ExplanationImage},
+        } {
+                file, header, err := r.FormFile(field.name)
+                if err == nil {
+                        imagePath, err := saveUploadedFile(file, header)
+                        file.Close()
+                        if err != nil {
+                                return err
+                        }
+                        *field.image = imagePath
+                }
+                if r.FormValue(field.remove) == "on" {
+                        *field.image = ""
+                }
+        }
+        return nil
+}
+
+// parseAnswerKeyFromForm reads the question type and its answer key from the
+// question form into q and checks them. It runs after the images are saved,
+// since a hotspot question needs an image.
+func parseAnswerKeyFromForm(r *http.Request, q *Question) error {
+        q.Type = r.FormValue("question_type")
+        switch q.Type {
+        case questionMultiple:
+                q.CorrectAnswer = strings.Join(r.Form["correct_multi"], "")
+        case questionHotspot:
+                hotspots, err := parseHotspotList(r.FormValue("hotspots"))
+                if err != nil {
+                        return err
+                }
+                q.Hotspots = hotspots
+        default:
+                q.CorrectAnswer = r.FormValue("correct_answer")
+        }
+        return q.normalizeAnswerKey()
+}
+
 func adminAddQuestionHandler(w http.ResponseWriter, r *http.Request) {
         if r.Method == "POST" {
                 r.ParseMultipartForm(10 << 20)
@@ -430,75 +906,121 @@ func adminAddQuestionHandler(w http.ResponseWriter, r *http.Request) {
                         http.Error(w, "CSRF token invalid", http.StatusForbidden)
                         return
                 }
-                nextNum := getNextQuestionNumber()
+                nextNum := db.GetNextQuestionNumber()
                 variants := parseVariantsFromForm(r)
 
+                categoryID, _ := strconv.Atoi(r.FormValue("category_id"))
+                user := getCurrentUser(r)
+
                 q := &Question{
                         Number:        nextNum,
                         Text:          r.FormValue("text"),
                         CorrectAnswer: r.FormValue("correct_answer"),
                         VariantsList:  variants,
+                        CategoryID:    categoryID,
+                        Status:        questionDraft,
+                        AuthorID:      user.ID,
                 }
-
-                file, header, err := r.FormFile("image")
+                parseExplanationFromForm(r, q)
+                err := saveQuestionImagesFromForm(r, q)
                 if err == nil {
-                        defer file.Close()
-                        imagePath, err := saveUploadedFile(file, header)
-                        if err == nil {
-                                q.Image = imagePath
-                        }
+                        err = parseAnswerKeyFromForm(r, q)
+                }
+                if err != nil {
+                        renderTemplate(w, r, "admin/add_question.html", map[string]interface{}{
+                                "CurrentPage":    "admin_questions",
+                                "Categories":     db.GetAllCategories(),
+                                "Chapters":       db.GetHandbookChapters(),
+                                "QuestionData":   q,
+                                "LinkedArticles": idSet(parseIDs(r.Form["article"])),
+                                "Error":          errorText(requestLocale(r), err),
+                        })
+                        return
                 }
 
-                createQuestion(q)
+                if err := db.CreateQuestion(q, user.ID); err == nil {
+                        db.SetQuestionArticles(q.ID, parseIDs(r.Form["article"]))
+                        saveQuestionStatus(user, q, r.FormValue("workflow"))
+                }
                 http.Redirect(w, r, "/admin-panel/questions/", http.StatusFound)
                 return
         }
 
         renderTemplate(w, r, "admin/add_question.html", map[string]interface{}{
-                "CurrentPage": "admin_questions",
+                "CurrentPage":    "admin_questions",
+                "Categories":     db.GetAllCategories(),
+                "Chapters":       db.GetHandbookChapters(),
+                "QuestionData":   &Question{Type: questionSingle, VariantsList: []Variant{{Letter: "A"}, {Letter: "B"}}, CorrectAnswer: "A"},
+                "LinkedArticles": idSet(nil),
         })
 }
 
 func adminEditQuestionHandler(w http.ResponseWriter, r *http.Request) {
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        question := getQuestionByID(id)
+        question := db.GetQuestionByID(id)
         if question == nil {
                 http.NotFound(w, r)
                 return
         }
+        user := getCurrentUser(r)
 
         if r.Method == "POST" {
+                if !user.CanWrite() {
+                        http.Error(w, "Forbidden", http.StatusForbidden)
+                        return
+                }
                 r.ParseMultipartForm(10 << 20)
                 if !verifyCSRFToken(r, w) {
                         http.Error(w, "CSRF token invalid", http.StatusForbidden)
                         return
                 }
+                status := question.QuestionStatus()
                 question.Text = r.FormValue("text")
                 question.CorrectAnswer = r.FormValue("correct_answer")
                 question.VariantsList = parseVariantsFromForm(r)
-
-                file, header, err := r.FormFile("image")
+                question.CategoryID, _ = strconv.Atoi(r.FormValue("category_id"))
+                parseExplanationFromForm(r, question)
+                err := saveQuestionImagesFromForm(r, question)
                 if err == nil {
-                        defer file.Close()
-                        imagePath, err := saveUploadedFile(file, header)
-                        if err == nil {
-                                question.Image = imagePath
-                        }
+                        err = parseAnswerKeyFromForm(r, question)
                 }
-
-                if r.FormValue("remove_image") == "on" {
-                        question.Image = ""
+                if err != nil {
+                        data := map[string]interface{}{
+                                "CurrentPage":    "admin_questions",
+                                "QuestionData":   question,
+                                "Categories":     db.GetAllCategories(),
+                                "Chapters":       db.GetHandbookChapters(),
+                                "LinkedArticles": idSet(parseIDs(r.Form["article"])),
+                                "Languages":      translationLanguages(),
+                        }
+                        addQuestionReviewData(r, user, question, data)
+                        data["Error"] = errorText(requestLocale(r), err)
+                        renderTemplate(w, r, "admin/edit_question.html", data)
+                        return
                 }
 
-                updateQuestion(question)
+                var review *QuestionReview
+                question.Status, review = editorStatus(user, question, status, r.FormValue("workflow"))
+                db.UpdateQuestion(question, user.ID, review)
+                db.SetQuestionArticles(question.ID, parseIDs(r.Form["article"]))
                 http.Redirect(w, r, "/admin-panel/questions/", http.StatusFound)
                 return
         }
 
-        renderTemplate(w, r, "admin/edit_question.html", map[string]interface{}{
-                "CurrentPage":  "admin_questions",
-                "QuestionData": question,
-        })
+        var linked []int
+        for _, a := range db.GetQuestionArticles(question.ID) {
+                linked = append(linked, a.ID)
+        }
+        data := map[string]interface{}{
+                "CurrentPage":    "admin_questions",
+                "QuestionData":   question,
+                "Categories":     db.GetAllCategories(),
+                "Chapters":       db.GetHandbookChapters(),
+                "LinkedArticles": idSet(linked),
+                "Languages":      translationLanguages(),
+        }
+        addQuestionReviewData(r, user, question, data)
+        renderTemplate(w, r, "admin/edit_question.html", data)
 }
 
 func adminDeleteQuestionHandler(w http.ResponseWriter, r *http.Request) {
@@ -509,22 +1031,110 @@ func adminDeleteQuestionHandler(w http.ResponseWriter, r *http.Request) {
                         return
                 }
                 id, _ := strconv.Atoi(mux.Vars(r)["id"])
-                deleteQuestion(id)
+                db.DeleteQuestion(id, getCurrentUser(r).ID)
         }
         http.Redirect(w, r, "/admin-panel/questions/", http.StatusFound)
 }
 
+func adminCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+        data := map[string]interface{}{
+                "CurrentPage": "admin_categories",
+        }
+
+        if r.Method == "POST" {
+                r.ParseForm()
+                if !verifyCSRFToken(r, w) {
+                        http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                        return
+                }
+                c := &Category{
+                        Name:        strings.TrimSpace(r.FormValue("name")),
+                        Description: strings.TrimSpace(r.FormValue("description")),
+                }
+                if c.Name == "" {
+                        data["Error"] = tr(r, "error.category_name_required")
+                } else if err := db.CreateCategory(c); err != nil {
+                        data["Error"] = tr(r, "error.category_name_taken")
+                } else {
+                        http.Redirect(w, r, "/admin-panel/categories/", http.StatusFound)
+                        return
+                }
+        }
+
+        data["Categories"] = db.GetAllCategories()
+        renderTemplate(w, r, "admin/categories.html", data)
+}
+
+func adminEditCategoryHandler(w http.ResponseWriter, r *http.Request) {
+        id, _ := strconv.Atoi(mux.Vars(r)["id"])
+        category := db.GetCategoryByID(id)
+        if category == nil {
+                http.NotFound(w, r)
+                return
+        }
+
+        data := map[string]interface{}{
+                "CurrentPage": "admin_categories",
+                "Category":    category,
+        }
+
+        if r.Method == "POST" {
+                r.ParseForm()
+                if !verifyCSRFToken(r, w) {
+                        http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                        return
+                }
+                category.Name = strings.TrimSpace(r.FormValue("name"))
+                category.Description = strings.TrimSpace(r.FormValue("description"))
+                if category.Name == "" {
+                        data["Error"] = tr(r, "error.category_name_required")
+                } else if err := db.UpdateCategory(category); err != nil {
+                        data["Error"] = tr(r, "error.category_name_taken")
+                } else {
+                        http.Redirect(w, r, "/admin-panel/categories/", http.StatusFound)
+                        return
+                }
+        }
+
+        renderTemplate(w, r, "admin/edit_category.html", data)
+}
+
+func adminDeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+        if r.Method == "POST" {
+                r.ParseForm()
+                if !verifyCSRFToken(r, w) {
+                        http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                        return
+                }
+                id, _ := strconv.Atoi(mux.Vars(r)["id"])
+                db.DeleteCategory(id)
+        }
+        http.Redirect(w, r, "/admin-panel/categories/", http.StatusFound)
+}
+
 func adminUsersHandler(w http.ResponseWriter, r *http.Request) {
-        users := getNonStaffUsers()
+        users := db.GetNonStaffUsers()
         renderTemplate(w, r, "admin/users.html", map[string]interface{}{
                 "CurrentPage": "admin_users",
                 "Users":       users,
+                "Staff":       db.GetStaffUsers(),
         })
 }
 
+// roleFromForm is the role chosen on the user form: one of staffRoles, or
+// empty for a student.
+func roleFromForm(r *http.Request) string {
+        role := r.FormValue("role")
+        if !slices.Contains(staffRoles, role) {
+                return ""
+        }
+        return role
+}
+
 func adminAddUserHandler(w http.ResponseWriter, r *http.Request) {
         data := map[string]interface{}{
                 "CurrentPage": "admin_users",
+                "Roles":       staffRoles,
         }
 
         if r.Method == "POST" {
@@ -535,10 +1145,10 @@ func adminAddUserHandler(w http.ResponseWriter, r *http.Request) {
                 }
                 username := r.FormValue("username")
                 password := r.FormValue("password")
-                if usernameExists(username, 0) {
-                        data["Error"] = "Bu login allaqachon mavjud!"
+                if db.UsernameExists(username, 0) {
+                        data["Error"] = tr(r, "error.username_taken")
                 } else {
-                        createUser(username, password, false)
+                        createUser(username, password, roleFromForm(r))
                         http.Redirect(w, r, "/admin-panel/users/", http.StatusFound)
                         return
                 }
@@ -549,15 +1159,20 @@ func adminAddUserHandler(w http.ResponseWriter, r *http.Request) {
 
 func adminEditUserHandler(w http.ResponseWriter, r *http.Request) {
         id, _ := strconv.Atoi(mux.Vars(r)["id"])
-        editUser := getUserByID(id)
-        if editUser == nil || editUser.IsStaff {
+        editUser := db.GetUserByID(id)
+        if editUser == nil {
                 http.NotFound(w, r)
                 return
         }
+        // Admins cannot change their own role, so that there is always one
+        // left to manage staff.
+        ownAccount := editUser.ID == getCurrentUser(r).ID
 
         data := map[string]interface{}{
                 "CurrentPage": "admin_users",
                 "EditUser":    editUser,
+                "Roles":       staffRoles,
+                "OwnAccount":  ownAccount,
         }
 
         if r.Method == "POST" {
@@ -569,15 +1184,18 @@ func adminEditUserHandler(w http.ResponseWriter, r *http.Request) {
                 newUsername := r.FormValue("username")
                 newPassword := r.FormValue("password")
 
-                if newUsername != editUser.Username && usernameExists(newUsername, editUser.ID) {
-                        data["Error"] = "Bu login allaqachon mavjud!"
+                if newUsername != editUser.Username && db.UsernameExists(newUsername, editUser.ID) {
+                        data["Error"] = tr(r, "error.username_taken")
                 } else {
-                        updateUserUsername(editUser.ID, newUsername)
+                        db.UpdateUserUsername(editUser.ID, newUsername)
                         if newPassword != "" {
                                 updateUserPassword(editUser.ID, newPassword)
                         }
-                        data["Success"] = "Foydalanuvchi muvaffaqiyatli yangilandi!"
-                        editUser = getUserByID(id)
+                        if !ownAccount {
+                                db.UpdateUserRole(editUser.ID, roleFromForm(r))
+                        }
+                        data["Success"] = tr(r, "success.user_updated")
+                        editUser = db.GetUserByID(id)
                         data["EditUser"] = editUser
                 }
         }
@@ -593,13 +1211,13 @@ func adminDeleteUserHandler(w http.ResponseWriter, r *http.Request) {
                         return
                 }
                 id, _ := strconv.Atoi(mux.Vars(r)["id"])
-                deleteUser(id)
+                db.DeleteUser(id)
         }
         http.Redirect(w, r, "/admin-panel/users/", http.StatusFound)
 }
 
 func adminStatisticsHandler(w http.ResponseWriter, r *http.Request) {
-        users := getNonStaffUsers()
+        users := db.GetNonStaffUsers()
         type UserStat struct {
                 User       *User
                 TotalTests int
@@ -607,7 +1225,7 @@ func adminStatisticsHandler(w http.ResponseWriter, r *http.Request) {
         }
         var userStats []UserStat
         for _, u := range users {
-                sessions := getUserCompletedSessions(u.ID)
+                sessions := db.GetUserCompletedSessions(u.ID)
                 total := len(sessions)
                 avg := 0
                 if total > 0 {
@@ -627,5 +1245,60 @@ func adminStatisticsHandler(w http.ResponseWriter, r *http.Request) {
         renderTemplate(w, r, "admin/statistics.html", map[string]interface{}{
                 "CurrentPage": "admin_statistics",
                 "UserStats":   userStats,
+                "Tickets":     db.GetAllTickets(),
+                "TicketStats": db.GetTicketStats(0),
         })
 }
+
+func adminSettingsHandler(w http.ResponseWriter, r *http.Request) {
+        rules := getExamRules()
+        data := map[string]interface{}{
+                "CurrentPage":          "admin_settings",
+                "ExamRules":            rules,
+                "MistakesClearStreak":  getMistakesClearStreak(),
+                "TrashRetentionDays":   getTrashRetentionDays(),
+                "PracticeExplanations": showPracticeExplanations(),
+        }
+
+        if r.Method == "POST" {
+                r.ParseForm()
+                if !verifyCSRFToken(r, w) {
+                        http.Error(w, "CSRF token invalid", http.StatusForbidden)
+                        return
+                }
+                rules.QuestionCount, _ = strconv.Atoi(r.FormValue("question_count"))
+                rules.TimeLimit, _ = strconv.Atoi(r.FormValue("time_limit"))
+                rules.MaxMistakes, _ = strconv.Atoi(r.FormValue("max_mistakes"))
+                rules.HideFeedback = r.FormValue("hide_feedback") == "on"
+                rules.ShuffleVariants = r.FormValue("shuffle_variants") == "on"
+                clearStreak, _ := strconv.Atoi(r.FormValue("mistakes_clear_streak"))
+                retention, _ := strconv.Atoi(r.FormValue("trash_retention_days"))
+                data["ExamRules"] = rules
+                data["MistakesClearStreak"] = clearStreak
+                data["TrashRetentionDays"] = retention
+                practiceExplanations := r.FormValue("practice_explanations") == "on"
+                data["PracticeExplanations"] = practiceExplanations
+
+                if rules.QuestionCount < 1 || rules.TimeLimit < 1 || rules.MaxMistakes < 0 || clearStreak < 1 || retention < 1 {
+                        data["Error"] = tr(r, "error.invalid_values")
+                        renderTemplate(w, r, "admin/settings.html", data)
+                        return
+                }
+                err := saveExamRules(rules)
+                if err == nil {
+                        err = db.SaveSettings(map[string]string{
+                                settingMistakesClearStreak:  strconv.Itoa(clearStreak),
+                                settingTrashRetentionDays:   strconv.Itoa(retention),
+                                settingPracticeExplanations: strconv.FormatBool(practiceExplanations),
+                        })
+                }
+                if err != nil {
+                        data["Error"] = tr(r, "error.save_failed")
+                        renderTemplate(w, r, "admin/settings.html", data)
+                        return
+                }
+                data["Success"] = tr(r, "success.settings_saved")
+        }
+
+        renderTemplate(w, r, "admin/settings.html", data)
+}
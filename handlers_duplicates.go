@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// adminDuplicatesHandler lists groups of questions that look like copies of
+// each other. The threshold query parameter sets the similarity, in
+// percent, from which questions are grouped.
+func adminDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	threshold, err := strconv.Atoi(r.URL.Query().Get("threshold"))
+	if err != nil || threshold < 50 || threshold > 100 {
+		threshold = defaultDuplicateThreshold
+	}
+	data := map[string]interface{}{
+		"CurrentPage":   "admin_questions",
+		"Threshold":     threshold,
+		"Groups":        findDuplicates(db.GetAllQuestions(), threshold),
+		"CategoryNames": categoryNames(db.GetAllCategories()),
+	}
+	switch q := r.URL.Query(); {
+	case q.Get("error") != "":
+		data["Error"] = tr(r, "error.merge_failed")
+	case q.Get("merged") != "":
+		data["Success"] = tr(r, "success.questions_merged", q.Get("merged"), q.Get("into"))
+	}
+	renderTemplate(w, r, "admin/duplicates.html", data)
+}
+
+// adminMergeDuplicatesHandler merges the posted questions into the one
+// chosen to keep.
+func adminMergeDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	target := "/admin-panel/questions/duplicates/?threshold=" + r.FormValue("threshold")
+	if r.Method != "POST" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+
+	keepID, _ := strconv.Atoi(r.FormValue("keep"))
+	questions := db.GetQuestionsByIDs(parseIDs(r.Form["question"]))
+	keep := questions[keepID]
+	if keep == nil || len(questions) < 2 {
+		http.Redirect(w, r, target+"&error=1", http.StatusFound)
+		return
+	}
+	merge := &QuestionMerge{KeepID: keepID, Letters: make(map[int]map[string]string)}
+	for id, q := range questions {
+		if id != keepID {
+			merge.Letters[id] = variantLetterMap(q, keep)
+		}
+	}
+	if err := db.MergeQuestions(merge, getCurrentUser(r).ID); err != nil {
+		http.Redirect(w, r, target+"&error=1", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, target+"&merged="+strconv.Itoa(len(merge.Letters))+"&into="+strconv.Itoa(keep.Number), http.StatusFound)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func handbookHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, "handbook.html", map[string]interface{}{
+		"CurrentPage": "handbook",
+		"Chapters":    db.GetHandbookChapters(),
+		"SignCount":   len(db.GetAllRoadSigns()),
+	})
+}
+
+// handbookArticleHandler shows an article with the questions linked to it
+// and how well the user has answered them so far.
+func handbookArticleHandler(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	article := db.GetHandbookArticle(id)
+	if article == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var prev, next *HandbookArticle
+	var all []*HandbookArticle
+	for _, c := range db.GetHandbookChapters() {
+		all = append(all, c.Articles...)
+	}
+	for i, a := range all {
+		if a.ID != article.ID {
+			continue
+		}
+		if i > 0 {
+			prev = all[i-1]
+		}
+		if i+1 < len(all) {
+			next = all[i+1]
+		}
+	}
+
+	questions := db.GetArticleQuestions(article.ID)
+	localizeQuestions(userContentLang(user), questions)
+	ids := make([]int, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	stats := db.GetUserQuestionStats(user.ID, ids)
+	total := &QuestionStat{}
+	for _, st := range stats {
+		total.Answered += st.Answered
+		total.Correct += st.Correct
+	}
+
+	renderTemplate(w, r, "handbook_article.html", map[string]interface{}{
+		"CurrentPage": "handbook",
+		"Article":     article,
+		"Prev":        prev,
+		"Next":        next,
+		"Questions":   questions,
+		"Stats":       stats,
+		"Answered":    len(stats),
+		"Total":       total,
+	})
+}
+
+func roadSignsHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, "road_signs.html", map[string]interface{}{
+		"CurrentPage": "handbook",
+		"Groups":      groupRoadSigns(db.GetAllRoadSigns()),
+	})
+}
+
+// handbookHit is an article found by the handbook search.
+type handbookHit struct {
+	*HandbookArticle
+	Snippet string
+}
+
+func handbookSearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	terms := searchTerms(query)
+	var hits []*handbookHit
+	for _, a := range db.SearchHandbookArticles(query) {
+		hits = append(hits, &handbookHit{HandbookArticle: a, Snippet: searchSnippet(a.Body, terms, 200)})
+	}
+	renderTemplate(w, r, "handbook_search.html", map[string]interface{}{
+		"CurrentPage": "handbook",
+		"Query":       query,
+		"Articles":    hits,
+		"Signs":       db.SearchRoadSigns(query),
+	})
+}
+
+func adminHandbookHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		c := &HandbookChapter{}
+		if msg := chapterFromForm(r, c); msg != "" {
+			data["Error"] = msg
+		} else if err := db.CreateHandbookChapter(c); err != nil {
+			data["Error"] = tr(r, "error.chapter_number_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+			return
+		}
+	}
+
+	data["Chapters"] = db.GetHandbookChapters()
+	data["SignCount"] = len(db.GetAllRoadSigns())
+	renderTemplate(w, r, "admin/handbook.html", data)
+}
+
+// chapterFromForm fills c from the chapter form. It returns a user-facing
+// error message when the input is invalid.
+func chapterFromForm(r *http.Request, c *HandbookChapter) string {
+	number, err := strconv.Atoi(r.FormValue("number"))
+	if err != nil || number < 1 {
+		return tr(r, "error.chapter_number_required")
+	}
+	c.Number = number
+	c.Title = strings.TrimSpace(r.FormValue("title"))
+	if c.Title == "" {
+		return tr(r, "error.chapter_title_required")
+	}
+	return ""
+}
+
+func adminEditChapterHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	chapter := db.GetHandbookChapter(id)
+	if chapter == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+		"Chapter":     chapter,
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		if msg := chapterFromForm(r, chapter); msg != "" {
+			data["Error"] = msg
+		} else if err := db.UpdateHandbookChapter(chapter); err != nil {
+			data["Error"] = tr(r, "error.chapter_number_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+			return
+		}
+	}
+
+	renderTemplate(w, r, "admin/edit_chapter.html", data)
+}
+
+func adminDeleteChapterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		db.DeleteHandbookChapter(id)
+	}
+	http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+}
+
+// articleFromForm fills a from the add/edit form. An empty position puts a
+// new article at the end of its chapter. It returns a user-facing error
+// message when the input is invalid.
+func articleFromForm(r *http.Request, a *HandbookArticle) string {
+	chapterID, _ := strconv.Atoi(r.FormValue("chapter_id"))
+	chapter := db.GetHandbookChapter(chapterID)
+	if chapter == nil {
+		return tr(r, "error.chapter_required")
+	}
+	a.Number = strings.TrimSpace(r.FormValue("number"))
+	a.Title = strings.TrimSpace(r.FormValue("title"))
+	a.Body = strings.TrimSpace(r.FormValue("body"))
+	if a.Title == "" && a.Number == "" {
+		return tr(r, "error.article_title_required")
+	}
+	if a.Body == "" {
+		return tr(r, "error.article_body_required")
+	}
+	if s := strings.TrimSpace(r.FormValue("position")); s != "" {
+		position, err := strconv.Atoi(s)
+		if err != nil {
+			return tr(r, "error.article_position_invalid")
+		}
+		a.Position = position
+	} else if a.ID == 0 || a.ChapterID != chapter.ID {
+		a.Position = 1
+		if n := len(chapter.Articles); n > 0 {
+			a.Position = chapter.Articles[n-1].Position + 1
+		}
+	}
+	a.ChapterID = chapter.ID
+	return ""
+}
+
+func adminAddArticleHandler(w http.ResponseWriter, r *http.Request) {
+	article := &HandbookArticle{}
+	article.ChapterID, _ = strconv.Atoi(r.URL.Query().Get("chapter"))
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+		"Article":     article,
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		if msg := articleFromForm(r, article); msg != "" {
+			data["Error"] = msg
+		} else if err := db.CreateHandbookArticle(article); err != nil {
+			data["Error"] = tr(r, "error.article_save_failed", err)
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+			return
+		}
+	}
+
+	data["Chapters"] = db.GetHandbookChapters()
+	renderTemplate(w, r, "admin/add_article.html", data)
+}
+
+func adminEditArticleHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	article := db.GetHandbookArticle(id)
+	if article == nil {
+		http.NotFound(w, r)
+		return
+	}
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+		"Article":     article,
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		if msg := articleFromForm(r, article); msg != "" {
+			data["Error"] = msg
+		} else if err := db.UpdateHandbookArticle(article); err != nil {
+			data["Error"] = tr(r, "error.article_save_failed", err)
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+			return
+		}
+	}
+
+	data["Chapters"] = db.GetHandbookChapters()
+	data["Questions"] = db.GetArticleQuestions(article.ID)
+	renderTemplate(w, r, "admin/edit_article.html", data)
+}
+
+func adminDeleteArticleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		db.DeleteHandbookArticle(id)
+	}
+	http.Redirect(w, r, "/admin-panel/handbook/", http.StatusFound)
+}
+
+// roadSignFromForm fills s from the sign form, storing a newly uploaded
+// image under media/signs. It returns a user-facing error message when the
+// input is invalid.
+func roadSignFromForm(r *http.Request, s *RoadSign) string {
+	s.Code = strings.TrimSpace(r.FormValue("code"))
+	s.Name = strings.TrimSpace(r.FormValue("name"))
+	s.Description = strings.TrimSpace(r.FormValue("description"))
+	if s.Code == "" || s.Name == "" {
+		return tr(r, "error.sign_required")
+	}
+	if r.FormValue("remove_image") == "on" {
+		s.Image = ""
+	}
+	file, _, err := r.FormFile("image")
+	if err == nil {
+		defer file.Close()
+		imagePath, err := saveImage(file, "signs")
+		if err != nil {
+			return errorText(requestLocale(r), err)
+		}
+		s.Image = imagePath
+	}
+	return ""
+}
+
+func adminRoadSignsHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+	}
+
+	if r.Method == "POST" {
+		r.ParseMultipartForm(10 << 20)
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		s := &RoadSign{}
+		if msg := roadSignFromForm(r, s); msg != "" {
+			data["Error"] = msg
+		} else if err := db.CreateRoadSign(s); err != nil {
+			data["Error"] = tr(r, "error.sign_code_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/signs/", http.StatusFound)
+			return
+		}
+	}
+
+	data["Signs"] = db.GetAllRoadSigns()
+	renderTemplate(w, r, "admin/road_signs.html", data)
+}
+
+func adminEditRoadSignHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	sign := db.GetRoadSign(id)
+	if sign == nil {
+		http.NotFound(w, r)
+		return
+	}
+	data := map[string]interface{}{
+		"CurrentPage": "admin_handbook",
+		"Sign":        sign,
+	}
+
+	if r.Method == "POST" {
+		r.ParseMultipartForm(10 << 20)
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		if msg := roadSignFromForm(r, sign); msg != "" {
+			data["Error"] = msg
+		} else if err := db.UpdateRoadSign(sign); err != nil {
+			data["Error"] = tr(r, "error.sign_code_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/handbook/signs/", http.StatusFound)
+			return
+		}
+	}
+
+	renderTemplate(w, r, "admin/edit_road_sign.html", data)
+}
+
+func adminDeleteRoadSignHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		db.DeleteRoadSign(id)
+	}
+	http.Redirect(w, r, "/admin-panel/handbook/signs/", http.StatusFound)
+}
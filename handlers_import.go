@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxImportSize = 64 << 20
+
+// importUploadPath is where an uploaded file waits between the preview and
+// the apply step. The token is hex and the extension one of the supported
+// formats, so the form cannot point it anywhere else.
+func importUploadPath(token, filename string) string {
+	if _, err := hex.DecodeString(token); err != nil || len(token) != 32 {
+		return ""
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".csv", ".json", ".xlsx", ".zip":
+	default:
+		return ""
+	}
+	return filepath.Join(os.TempDir(), "avtotestprime-import-"+token+ext)
+}
+
+// adminImportQuestionsHandler imports a question bank in two steps: an
+// upload is checked and previewed without writing anything, then applied
+// from the kept copy once the admin confirms. Uploading with "apply" skips
+// the preview.
+func adminImportQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage": "admin_questions",
+		"Upsert":      true,
+	}
+	if r.Method != "POST" {
+		renderTemplate(w, r, "admin/import_questions.html", data)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	r.ParseMultipartForm(32 << 20)
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	user := getCurrentUser(r)
+	opts := importOptions{
+		DryRun: r.FormValue("action") != "apply",
+		Upsert: r.FormValue("upsert") == "on",
+		UserID: user.ID,
+		Locale: requestLocale(r),
+		Status: questionPublished,
+	}
+	// Only admins publish without review; an author's import adds drafts.
+	if !user.IsAdmin() {
+		opts.Status = questionDraft
+	}
+	data["Upsert"] = opts.Upsert
+
+	var filename string
+	var content []byte
+	file, header, err := r.FormFile("file")
+	if err == nil {
+		defer file.Close()
+		filename = header.Filename
+		content, err = io.ReadAll(file)
+	} else if path := importUploadPath(r.FormValue("token"), r.FormValue("filename")); path != "" {
+		filename = r.FormValue("filename")
+		content, err = os.ReadFile(path)
+	}
+	if err != nil || len(content) == 0 {
+		data["Error"] = tr(r, "error.import_file_required")
+		renderTemplate(w, r, "admin/import_questions.html", data)
+		return
+	}
+
+	f, err := readImportFile(filename, content)
+	if err != nil {
+		data["Error"] = errorText(requestLocale(r), err)
+		renderTemplate(w, r, "admin/import_questions.html", data)
+		return
+	}
+	report, err := runImport(f, opts)
+	if err != nil {
+		data["Error"] = tr(r, "error.import_stopped", errorText(requestLocale(r), err))
+	}
+	data["Report"] = report
+	data["Filename"] = filename
+
+	token := r.FormValue("token")
+	if report.Applied {
+		os.Remove(importUploadPath(token, filename))
+	} else if opts.DryRun && !report.HasErrors() {
+		if importUploadPath(token, filename) == "" {
+			token = generateRandomString(32)
+			os.WriteFile(importUploadPath(token, filename), content, 0600)
+		}
+		data["Token"] = token
+	}
+	renderTemplate(w, r, "admin/import_questions.html", data)
+}
+
+// adminExportQuestionsHandler shows the export form and, once a format is
+// chosen, downloads all questions or those matching the filter.
+func adminExportQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	categoryIDs := parseIDs(r.URL.Query()["category"])
+	if !slices.Contains(exportFormats, format) {
+		renderTemplate(w, r, "admin/export_questions.html", map[string]interface{}{
+			"CurrentPage":    "admin_questions",
+			"Formats":        exportFormats,
+			"Categories":     db.GetAllCategories(),
+			"TotalQuestions": db.CountQuestions(),
+		})
+		return
+	}
+
+	questions := db.SearchQuestions(QuestionFilter{Query: query, CategoryIDs: categoryIDs})
+	filename := fmt.Sprintf("avtotestprime-questions-%s.%s", time.Now().Format("20060102-150405"), format)
+	contentTypes := map[string]string{
+		"json": "application/json",
+		"csv":  "text/csv; charset=utf-8",
+		"zip":  "application/zip",
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := writeExport(w, format, questions); err != nil {
+		http.Error(w, "Error exporting questions", 500)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// adminMediaHandler shows the media report: orphaned files the garbage
+// collector would delete and referenced files that are gone.
+func adminMediaHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage": "admin_settings",
+	}
+	report, err := scanMedia()
+	if err != nil {
+		data["Error"] = tr(r, "error.media_scan_failed", err)
+		report = &mediaReport{}
+	}
+	data["Report"] = report
+	switch q := r.URL.Query(); {
+	case q.Get("error") != "":
+		data["Error"] = tr(r, "error.media_collect_failed")
+	case q.Get("removed") != "":
+		size, _ := strconv.ParseInt(q.Get("freed"), 10, 64)
+		data["Success"] = tr(r, "success.media_collected", q.Get("removed"), formatFileSize(size))
+	}
+	renderTemplate(w, r, "admin/media.html", data)
+}
+
+// adminCollectMediaHandler deletes the orphaned media files.
+func adminCollectMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/admin-panel/media/", http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	removed, freed, err := collectMediaGarbage()
+	if err != nil {
+		http.Redirect(w, r, "/admin-panel/media/?error=1", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/admin-panel/media/?removed="+strconv.Itoa(removed)+"&freed="+strconv.FormatInt(freed, 10), http.StatusFound)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// adminRenumberHandler shows the bank in number order for reordering by
+// drag and drop.
+func adminRenumberHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage":   "admin_questions",
+		"Questions":     db.GetAllQuestions(),
+		"CategoryNames": categoryNames(db.GetAllCategories()),
+	}
+	switch q := r.URL.Query(); {
+	case q.Get("error") != "":
+		data["Error"] = tr(r, "error.renumber_failed")
+	case q.Get("renumbered") != "":
+		data["Success"] = tr(r, "success.questions_renumbered", q.Get("renumbered"))
+	}
+	renderTemplate(w, r, "admin/renumber.html", data)
+}
+
+// adminRenumberSaveHandler numbers the questions 1, 2, ... in the posted
+// order. Without an order, as from the compact button, the current order
+// is kept and only the gaps close.
+func adminRenumberSaveHandler(w http.ResponseWriter, r *http.Request) {
+	target := "/admin-panel/questions/renumber/"
+	if r.Method != "POST" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	changed, err := db.RenumberQuestions(parseIDs(r.Form["question"]), getCurrentUser(r).ID)
+	if err != nil {
+		http.Redirect(w, r, target+"?error=1", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, target+"?renumbered="+strconv.Itoa(changed), http.StatusFound)
+}
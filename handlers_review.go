@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// editorStatus is the status savedStatus gives q when u saves it from the
+// editor, with the review entry that logs the change, or nil when the
+// status stays. current is the status before the save, empty for a new
+// question.
+func editorStatus(u *User, q *Question, current, choice string) (string, *QuestionReview) {
+	status := savedStatus(u, current, choice)
+	if status == q.QuestionStatus() {
+		return status, nil
+	}
+	return status, &QuestionReview{QuestionID: q.ID, UserID: u.ID, Action: statusAction(status)}
+}
+
+// saveQuestionStatus moves q, just created by u from the editor as a
+// draft, to the status savedStatus gives it. Edits of saved questions
+// change the status in the same write as the content instead, so that
+// content sent to review is never live.
+func saveQuestionStatus(u *User, q *Question, choice string) {
+	status, review := editorStatus(u, q, "", choice)
+	if review == nil {
+		return
+	}
+	if err := db.ReviewQuestion(review, status); err == nil {
+		q.Status = status
+	}
+}
+
+// addQuestionReviewData adds the review panel of q's editor to data: its
+// author, review log and the actions u may take, and the outcome of the
+// last one.
+func addQuestionReviewData(r *http.Request, u *User, q *Question, data map[string]interface{}) {
+	data["Author"] = db.GetUserByID(q.AuthorID)
+	data["Reviews"] = db.GetQuestionReviews(q.ID)
+	data["ReviewActions"] = reviewActions(u, q)
+	switch v := r.URL.Query(); {
+	case v.Get("error") == "comment":
+		data["Error"] = tr(r, "error.review_comment_required")
+	case v.Get("error") != "":
+		data["Error"] = tr(r, "error.review_failed")
+	case v.Get("reviewed") != "":
+		data["Success"] = tr(r, "success.question_reviewed")
+	}
+}
+
+// adminReviewQuestionHandler takes a review action on a question from the
+// review panel of its editor: a status change or a comment.
+func adminReviewQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	target := fmt.Sprintf("/admin-panel/questions/%d/edit/", id)
+	if r.Method != "POST" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	question := db.GetQuestionByID(id)
+	if question == nil {
+		http.NotFound(w, r)
+		return
+	}
+	user := getCurrentUser(r)
+	action := r.FormValue("action")
+	status, ok := reviewStatus(user, question, action)
+	if !ok {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	comment := strings.TrimSpace(r.FormValue("comment"))
+	if comment == "" && (action == reviewComment || action == reviewReturn) {
+		http.Redirect(w, r, target+"?error=comment", http.StatusFound)
+		return
+	}
+	review := &QuestionReview{QuestionID: id, UserID: user.ID, Action: action, Comment: comment}
+	if err := db.ReviewQuestion(review, status); err != nil {
+		http.Redirect(w, r, target+"?error=review", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, target+"?reviewed="+action, http.StatusFound)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// wordDiff is a run of words that both revisions share (Op ""), or that only
+// the newer ("add") or older ("del") one has.
+type wordDiff struct {
+	Text string
+	Op   string
+}
+
+// diffWords compares two texts word by word using the longest common
+// subsequence, which is plenty for question-sized texts.
+func diffWords(old, new string) []wordDiff {
+	a, b := strings.Fields(old), strings.Fields(new)
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var parts []wordDiff
+	emit := func(word, op string) {
+		if n := len(parts); n > 0 && parts[n-1].Op == op {
+			parts[n-1].Text += " " + word
+			return
+		}
+		parts = append(parts, wordDiff{Text: word, Op: op})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			emit(a[i], "")
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			emit(a[i], "del")
+			i++
+		default:
+			emit(b[j], "add")
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		emit(a[i], "del")
+	}
+	for ; j < len(b); j++ {
+		emit(b[j], "add")
+	}
+	return parts
+}
+
+// revisionChange is one field that differs between two revisions. Field is
+// the message key naming it, formatted with Letter for variants. Words is
+// set for the question text.
+type revisionChange struct {
+	Field  string
+	Letter string
+	Old    string
+	New    string
+	Words  []wordDiff
+}
+
+// revisionChanges lists what changed from prev to cur; prev is nil for the
+// first revision, which then shows its whole content as added.
+func revisionChanges(prev, cur *Question, categories map[int]string) []revisionChange {
+	if prev == nil {
+		prev = &Question{}
+	}
+	var changes []revisionChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, revisionChange{Field: field, Old: old, New: new})
+		}
+	}
+	if prev.Number != cur.Number {
+		old := ""
+		if prev.Number != 0 {
+			old = strconv.Itoa(prev.Number)
+		}
+		add("revision.number", old, strconv.Itoa(cur.Number))
+	}
+	if prev.Text != cur.Text {
+		changes = append(changes, revisionChange{Field: "revision.text", Words: diffWords(prev.Text, cur.Text)})
+	}
+	add("revision.image", prev.Image, cur.Image)
+	add("revision.category", categories[prev.CategoryID], categories[cur.CategoryID])
+	add("revision.question_type", prev.QuestionType(), cur.QuestionType())
+	add("revision.correct_answer", prev.CorrectAnswer, cur.CorrectAnswer)
+	add("revision.hotspots", prev.HotspotList(), cur.HotspotList())
+	if prev.Explanation != cur.Explanation {
+		changes = append(changes, revisionChange{Field: "revision.explanation", Words: diffWords(prev.Explanation, cur.Explanation)})
+	}
+	add("revision.explanation_image", prev.ExplanationImage, cur.ExplanationImage)
+	add("revision.rule_ref", prev.RuleRef, cur.RuleRef)
+
+	oldVariants := make(map[string]string)
+	for _, v := range prev.VariantsList {
+		oldVariants[v.Letter] = v.Text
+	}
+	newVariants := make(map[string]string)
+	for _, v := range cur.VariantsList {
+		newVariants[v.Letter] = v.Text
+	}
+	for _, l := range variantLetters {
+		letter := string(l)
+		if oldVariants[letter] != newVariants[letter] {
+			changes = append(changes, revisionChange{Field: "revision.variant", Letter: letter, Old: oldVariants[letter], New: newVariants[letter]})
+		}
+	}
+	return changes
+}
+
+// revisionEntry is a revision as listed on the history page.
+type revisionEntry struct {
+	*QuestionRevision
+	Changes    []revisionChange
+	Restorable bool
+}
+
+func adminQuestionHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	revisions := db.GetQuestionRevisions(id)
+	if len(revisions) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	current := db.GetQuestionByID(id)
+	categories := categoryNames(db.GetAllCategories())
+
+	entries := make([]*revisionEntry, len(revisions))
+	for i, rev := range revisions {
+		var prev *Question
+		if i+1 < len(revisions) {
+			prev = revisions[i+1].Question
+		}
+		entries[i] = &revisionEntry{
+			QuestionRevision: rev,
+			Changes:          revisionChanges(prev, rev.Question, categories),
+			// The newest revision is what the question already looks like,
+			// unless the question has been deleted.
+			Restorable: i > 0 || current == nil,
+		}
+	}
+
+	data := map[string]interface{}{
+		"CurrentPage": "admin_questions",
+		"Question":    revisions[0].Question,
+		"Deleted":     current == nil,
+		"Entries":     entries,
+	}
+	if r.URL.Query().Get("error") != "" {
+		data["Error"] = tr(r, "error.restore_question_failed")
+	}
+	renderTemplate(w, r, "admin/question_history.html", data)
+}
+
+func adminRestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	historyURL := fmt.Sprintf("/admin-panel/questions/%d/history/", id)
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		revision, _ := strconv.Atoi(mux.Vars(r)["revision"])
+		user := getCurrentUser(r)
+		current := db.GetQuestionByID(id)
+		// Restoring an old revision is an edit like any other as far as
+		// review is concerned.
+		var status string
+		var review *QuestionReview
+		if current != nil {
+			status, review = editorStatus(user, current, current.QuestionStatus(), "")
+		}
+		if err := db.RestoreQuestionRevision(id, revision, user.ID, review, status); err != nil {
+			http.Redirect(w, r, historyURL+"?error=1", http.StatusFound)
+			return
+		}
+	}
+	http.Redirect(w, r, historyURL, http.StatusFound)
+}
+
+func adminQuestionChangesHandler(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, r, "admin/question_changes.html", map[string]interface{}{
+		"CurrentPage": "admin_questions",
+		"Revisions":   db.GetRecentQuestionRevisions(100),
+	})
+}
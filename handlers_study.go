@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// endOfDay is the last moment of t's calendar day in server time.
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(-time.Second)
+}
+
+func studyHandler(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	now := time.Now()
+	renderTemplate(w, r, "study.html", map[string]interface{}{
+		"CurrentPage": "study",
+		"DueNow":      db.CountDueQuestions(user.ID, now),
+		"DueToday":    db.CountDueQuestions(user.ID, endOfDay(now)),
+		"NewCount":    len(db.GetUnansweredQuestionIDs(user.ID)),
+		"BatchSize":   studyBatchSize,
+	})
+}
+
+// startStudyHandler opens a study session with the questions that are due
+// now or, when kind is "new", with questions the user has never answered.
+func startStudyHandler(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/study/", http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+
+	var questionIDs []int
+	if r.FormValue("kind") == "new" {
+		questionIDs = pickRandomIDs(db.GetUnansweredQuestionIDs(user.ID), studyBatchSize)
+	} else {
+		questionIDs = db.GetDueQuestionIDs(user.ID, time.Now(), studyBatchSize)
+	}
+	if len(questionIDs) == 0 {
+		http.Redirect(w, r, "/study/", http.StatusFound)
+		return
+	}
+
+	session := newTestSession(user.ID, len(questionIDs))
+	session.Mode = sessionModeStudy
+	if err := db.CreateTestSession(session, questionIDs); err != nil {
+		http.Error(w, "Error creating test session", 500)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/test/%d/", session.ID), http.StatusFound)
+}
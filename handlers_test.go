@@ -0,0 +1,688 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+var loadTemplatesOnce sync.Once
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestSite serves the whole site from a fresh memory store with the
+// default users seeded, the way main does for DATABASE_URL=memory://.
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	db = newMemoryStore()
+	seedDefaultUsers()
+	store = sessions.NewCookieStore([]byte("test-secret"))
+	mediaStore = &localMediaStore{dir: t.TempDir()}
+	loadTemplatesOnce.Do(loadTemplates)
+	srv := httptest.NewServer(recoveryMiddleware(newRouter()))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testClient is a browser session on a test site: it keeps cookies and
+// does not follow redirects, so that tests can check where they go.
+type testClient struct {
+	t   *testing.T
+	srv *httptest.Server
+	c   *http.Client
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	return &testClient{t: t, srv: srv, c: &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (tc *testClient) do(req *http.Request) (*http.Response, string) {
+	tc.t.Helper()
+	resp, err := tc.c.Do(req)
+	if err != nil {
+		tc.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func (tc *testClient) get(path string) (*http.Response, string) {
+	tc.t.Helper()
+	req, _ := http.NewRequest("GET", tc.srv.URL+path, nil)
+	return tc.do(req)
+}
+
+var csrfPattern = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// token reads the CSRF token from the form on page.
+func (tc *testClient) token(page string) string {
+	tc.t.Helper()
+	_, body := tc.get(page)
+	m := csrfPattern.FindStringSubmatch(body)
+	if m == nil {
+		tc.t.Fatalf("no CSRF token on %s", page)
+	}
+	return m[1]
+}
+
+// post submits form to path with the CSRF token of page.
+func (tc *testClient) post(page, path string, form url.Values) (*http.Response, string) {
+	tc.t.Helper()
+	form.Set("csrf_token", tc.token(page))
+	req, _ := http.NewRequest("POST", tc.srv.URL+path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return tc.do(req)
+}
+
+// postMultipart is post for the forms that take file uploads.
+func (tc *testClient) postMultipart(page, path string, form url.Values) (*http.Response, string) {
+	tc.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("csrf_token", tc.token(page))
+	for k, vs := range form {
+		for _, v := range vs {
+			mw.WriteField(k, v)
+		}
+	}
+	mw.Close()
+	req, _ := http.NewRequest("POST", tc.srv.URL+path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return tc.do(req)
+}
+
+func (tc *testClient) login(username, password string) {
+	tc.t.Helper()
+	resp, _ := tc.post("/login/", "/login/", url.Values{"username": {username}, "password": {password}})
+	if resp.StatusCode != http.StatusFound {
+		tc.t.Fatalf("login %s: status %d", username, resp.StatusCode)
+	}
+}
+
+func expectRedirect(t *testing.T, resp *http.Response, prefix string) {
+	t.Helper()
+	if resp.StatusCode != http.StatusFound || !strings.HasPrefix(resp.Header.Get("Location"), prefix) {
+		t.Fatalf("got %d to %q, want a redirect to %s", resp.StatusCode, resp.Header.Get("Location"), prefix)
+	}
+}
+
+// addTestQuestion adds a published two-variant question with answer A
+// through the admin form.
+func addTestQuestion(t *testing.T, admin *testClient, text string) *Question {
+	t.Helper()
+	resp, _ := admin.postMultipart("/admin-panel/questions/add/", "/admin-panel/questions/add/", url.Values{
+		"text":           {text},
+		"variant_a":      {"Ha"},
+		"variant_b":      {"Yo'q"},
+		"correct_answer": {"A"},
+		"workflow":       {"publish"},
+	})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+	for _, q := range db.GetAllQuestions() {
+		if q.Text == text {
+			return q
+		}
+	}
+	t.Fatalf("question %q was not added", text)
+	return nil
+}
+
+func TestLoginRedirectsByRole(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	resp, _ := admin.post("/login/", "/login/", url.Values{"username": {"admin"}, "password": {"admin"}})
+	expectRedirect(t, resp, "/admin-panel/")
+
+	student := newTestClient(t, srv)
+	resp, _ = student.post("/login/", "/login/", url.Values{"username": {"user"}, "password": {"user"}})
+	expectRedirect(t, resp, "/dashboard/")
+
+	resp, _ = student.get("/admin-panel/")
+	expectRedirect(t, resp, "/dashboard/")
+
+	resp, body := newTestClient(t, srv).post("/login/", "/login/", url.Values{"username": {"user"}, "password": {"wrong"}})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "login-error") {
+		t.Fatalf("bad password: status %d, want the login form with an error", resp.StatusCode)
+	}
+}
+
+func TestPostWithoutCSRFTokenIsRejected(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	req, _ := http.NewRequest("POST", srv.URL+"/admin-panel/categories/", strings.NewReader("name=Belgilar"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if resp, _ := admin.do(req); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", resp.StatusCode)
+	}
+	if n := len(db.GetAllCategories()); n != 0 {
+		t.Fatalf("%d categories created without a token", n)
+	}
+}
+
+func TestPracticeTestStartAnswerSubmit(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q1 := addTestQuestion(t, admin, "Birinchi savol")
+	q2 := addTestQuestion(t, admin, "Ikkinchi savol")
+
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	resp, _ := student.post("/test/start/", "/test/start/", url.Values{"num_questions": {"2"}})
+	expectRedirect(t, resp, "/test/")
+	testURL := resp.Header.Get("Location")
+	id, _ := strconv.Atoi(strings.Trim(strings.TrimPrefix(testURL, "/test/"), "/"))
+	user := db.GetUserByUsername("user")
+	session := db.GetTestSession(id, user.ID)
+	if session == nil || session.TotalQuestions != 2 {
+		t.Fatalf("session %d: %+v, want 2 questions", id, session)
+	}
+
+	resp, body := student.get(testURL)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "Birinchi savol") {
+		t.Fatalf("test page: status %d", resp.StatusCode)
+	}
+	if strings.Contains(body, `"correct_answer"`) {
+		t.Fatal("test page leaks the answer key")
+	}
+
+	resp, body = student.post(testURL, testURL+"answer/", url.Values{
+		"question_id": {strconv.Itoa(q1.ID)},
+		"answer":      {session.DisplayAnswer(q1, "A")},
+	})
+	var answer struct {
+		Recorded bool
+		Correct  bool
+	}
+	if err := json.Unmarshal([]byte(body), &answer); err != nil || !answer.Recorded || !answer.Correct {
+		t.Fatalf("answer: status %d, body %s", resp.StatusCode, body)
+	}
+
+	resp, _ = student.post(testURL, testURL+"submit/", url.Values{
+		"answer_" + strconv.Itoa(q2.ID): {session.DisplayAnswer(q2, "B")},
+	})
+	expectRedirect(t, resp, testURL+"result/")
+
+	session = db.GetTestSession(id, user.ID)
+	if !session.Completed || session.CorrectAnswers != 1 || session.WrongAnswers != 1 || session.ScorePercent != 50 {
+		t.Fatalf("graded session: %+v, want 1 right, 1 wrong, 50%%", session)
+	}
+	if resp, _ := student.get(testURL + "result/"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("result page: status %d", resp.StatusCode)
+	}
+}
+
+func TestAnswerOutsideSessionIsRejected(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	addTestQuestion(t, admin, "Savol")
+	other := addTestQuestion(t, admin, "Boshqa savol")
+
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	resp, _ := student.post("/test/start/", "/test/start/", url.Values{"num_questions": {"1"}})
+	testURL := resp.Header.Get("Location")
+	session := db.GetUnfinishedSessions(db.GetUserByUsername("user").ID)[0]
+	questionID := other.ID
+	if session.QuestionIDList()[0] == other.ID {
+		questionID = db.GetAllQuestions()[0].ID
+	}
+	resp, _ = student.post(testURL, testURL+"answer/", url.Values{
+		"question_id": {strconv.Itoa(questionID)},
+		"answer":      {"A"},
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", resp.StatusCode)
+	}
+}
+
+// answerStore fails CreateTestAnswer with err, after first storing the
+// answer when race is set, as a concurrent request would.
+type answerStore struct {
+	Store
+	race bool
+	err  error
+}
+
+func (s answerStore) CreateTestAnswer(sessionID, questionID int, selectedAnswer string, isCorrect bool, credit float64) error {
+	if s.race {
+		s.Store.CreateTestAnswer(sessionID, questionID, selectedAnswer, isCorrect, credit)
+	}
+	return s.err
+}
+
+func TestAnswerWriteFailures(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q := addTestQuestion(t, admin, "Savol")
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	resp, _ := student.post("/test/start/", "/test/start/", url.Values{"num_questions": {"1"}})
+	testURL := resp.Header.Get("Location")
+	session := db.GetUnfinishedSessions(db.GetUserByUsername("user").ID)[0]
+	form := func() url.Values {
+		return url.Values{"question_id": {strconv.Itoa(q.ID)}, "answer": {session.DisplayAnswer(q, "A")}}
+	}
+
+	store := db
+	defer func() { db = store }()
+	db = answerStore{Store: store, err: errors.New("disk full")}
+	if resp, _ := student.post(testURL, testURL+"answer/", form()); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("failed write: status %d, want 500", resp.StatusCode)
+	}
+	db = answerStore{Store: store, race: true, err: errors.New("UNIQUE constraint failed")}
+	if resp, _ := student.post(testURL, testURL+"answer/", form()); resp.StatusCode != http.StatusConflict {
+		t.Errorf("answer recorded by another request: status %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestAdminQuestionCRUD(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q := addTestQuestion(t, admin, "Asl matn")
+	if q.Number != 1 || q.CorrectAnswer != "A" || len(q.VariantsList) != 2 {
+		t.Fatalf("added question: %+v", q)
+	}
+
+	editURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/edit/"
+	resp, _ := admin.postMultipart(editURL, editURL, url.Values{
+		"text":           {"Yangi matn"},
+		"variant_a":      {"Ha"},
+		"variant_b":      {"Yo'q"},
+		"variant_c":      {"Bilmayman"},
+		"correct_answer": {"C"},
+	})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+	q = db.GetQuestionByID(q.ID)
+	if q.Text != "Yangi matn" || q.CorrectAnswer != "C" || len(q.VariantsList) != 3 {
+		t.Fatalf("edited question: %+v", q)
+	}
+
+	resp, _ = admin.post("/admin-panel/questions/", "/admin-panel/questions/"+strconv.Itoa(q.ID)+"/delete/", url.Values{})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+	if db.GetQuestionByID(q.ID) != nil {
+		t.Fatal("deleted question is still live")
+	}
+}
+
+func TestAuthorEditOfPublishedQuestionGoesToReview(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q := addTestQuestion(t, admin, "Asl matn")
+	if err := createUser(roleAuthor, "secret", roleAuthor); err != nil {
+		t.Fatal(err)
+	}
+	author := newTestClient(t, srv)
+	author.login(roleAuthor, "secret")
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	detailURL := "/questions/" + strconv.Itoa(q.ID) + "/"
+
+	editURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/edit/"
+	resp, _ := author.postMultipart(editURL, editURL, url.Values{
+		"text":           {"Tekshirilmagan matn"},
+		"variant_a":      {"Ha"},
+		"variant_b":      {"Yo'q"},
+		"correct_answer": {"A"},
+	})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+	if q = db.GetQuestionByID(q.ID); q.Text != "Tekshirilmagan matn" || q.Status != questionInReview {
+		t.Fatalf("edited question %q is %s, want it in review", q.Text, q.Status)
+	}
+	if resp, _ := student.get(detailURL); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("student sees the unreviewed edit: status %d", resp.StatusCode)
+	}
+
+	// Restoring the published revision is an edit too.
+	db.ReviewQuestion(&QuestionReview{QuestionID: q.ID, Action: reviewApprove}, questionPublished)
+	historyURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/history/"
+	resp, _ = author.post(historyURL, historyURL+"1/restore/", url.Values{})
+	expectRedirect(t, resp, historyURL)
+	if q = db.GetQuestionByID(q.ID); q.Text != "Asl matn" || q.Status != questionInReview {
+		t.Fatalf("restored question %q is %s, want it in review", q.Text, q.Status)
+	}
+	if resp, _ := student.get(detailURL); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("student sees the unreviewed restore: status %d", resp.StatusCode)
+	}
+}
+
+func TestAdminRestoreDeletedQuestion(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q := addTestQuestion(t, admin, "Asl matn")
+	editURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/edit/"
+	resp, _ := admin.postMultipart(editURL, editURL, url.Values{
+		"text":           {"Yangi matn"},
+		"variant_a":      {"Ha"},
+		"variant_b":      {"Yo'q"},
+		"correct_answer": {"B"},
+	})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+	resp, _ = admin.post("/admin-panel/questions/", "/admin-panel/questions/"+strconv.Itoa(q.ID)+"/delete/", url.Values{})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+
+	historyURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/history/"
+	resp, body := admin.get(historyURL)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "Yangi") || !strings.Contains(body, historyURL+"3/restore/") {
+		t.Fatalf("history of the deleted question: status %d", resp.StatusCode)
+	}
+	resp, _ = admin.post(historyURL, historyURL+"1/restore/", url.Values{})
+	expectRedirect(t, resp, historyURL)
+	if q = db.GetQuestionByID(q.ID); q == nil || q.Text != "Asl matn" || q.CorrectAnswer != "A" || q.QuestionStatus() != questionPublished {
+		t.Fatalf("restored question: %+v", q)
+	}
+	resp, _ = admin.post(historyURL, historyURL+"9/restore/", url.Values{})
+	expectRedirect(t, resp, historyURL+"?error=")
+	if resp, _ := admin.get("/admin-panel/questions/999/history/"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("history of an unknown question: status %d", resp.StatusCode)
+	}
+}
+
+func TestAdminCategoryCRUD(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	admin.post("/admin-panel/categories/", "/admin-panel/categories/", url.Values{"name": {"Belgilar"}})
+	categories := db.GetAllCategories()
+	if len(categories) != 1 || categories[0].Name != "Belgilar" {
+		t.Fatalf("categories after add: %+v", categories)
+	}
+	c := categories[0]
+
+	editURL := "/admin-panel/categories/" + strconv.Itoa(c.ID) + "/edit/"
+	admin.post(editURL, editURL, url.Values{"name": {"Yo'l belgilari"}})
+	if c = db.GetCategoryByID(c.ID); c == nil || c.Name != "Yo'l belgilari" {
+		t.Fatalf("category after edit: %+v", c)
+	}
+
+	admin.post("/admin-panel/categories/", "/admin-panel/categories/"+strconv.Itoa(c.ID)+"/delete/", url.Values{})
+	if n := len(db.GetAllCategories()); n != 0 {
+		t.Fatalf("%d categories left after delete", n)
+	}
+}
+
+func TestAdminUserCRUD(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	resp, _ := admin.post("/admin-panel/users/add/", "/admin-panel/users/add/", url.Values{"username": {"ali"}, "password": {"secret"}})
+	expectRedirect(t, resp, "/admin-panel/users/")
+	u := db.GetUserByUsername("ali")
+	if u == nil || u.IsStaff {
+		t.Fatalf("added user: %+v", u)
+	}
+	if resp, body := admin.post("/admin-panel/users/add/", "/admin-panel/users/add/", url.Values{"username": {"ali"}, "password": {"x"}}); resp.StatusCode != http.StatusOK || !strings.Contains(body, "alert-danger") {
+		t.Fatalf("duplicate username: status %d, want the form with an error", resp.StatusCode)
+	}
+
+	editURL := "/admin-panel/users/" + strconv.Itoa(u.ID) + "/edit/"
+	admin.post(editURL, editURL, url.Values{"username": {"vali"}, "password": {"new-secret"}})
+	if authenticateUser("vali", "new-secret") == nil {
+		t.Fatal("renamed user cannot log in with the new password")
+	}
+
+	admin.post("/admin-panel/users/", "/admin-panel/users/"+strconv.Itoa(u.ID)+"/delete/", url.Values{})
+	if db.GetUserByID(u.ID) != nil {
+		t.Fatal("deleted user is still live")
+	}
+}
+
+func TestAdminTrashRestoreAndPurge(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	q := addTestQuestion(t, admin, "O'chiriladigan savol")
+	other := addTestQuestion(t, admin, "Qoladigan savol")
+	student := db.GetUserByUsername("user")
+	for _, id := range []int{q.ID, other.ID} {
+		resp, _ := admin.post("/admin-panel/questions/", "/admin-panel/questions/"+strconv.Itoa(id)+"/delete/", url.Values{})
+		expectRedirect(t, resp, "/admin-panel/questions/")
+	}
+	admin.post("/admin-panel/users/", "/admin-panel/users/"+strconv.Itoa(student.ID)+"/delete/", url.Values{})
+
+	resp, body := admin.get("/admin-panel/trash/")
+	for _, action := range []string{"questions/" + strconv.Itoa(q.ID) + "/restore/", "users/" + strconv.Itoa(student.ID) + "/purge/"} {
+		if !strings.Contains(body, "/admin-panel/trash/"+action) {
+			t.Errorf("trash page (status %d) has no %s button", resp.StatusCode, action)
+		}
+	}
+
+	// Emptying expired trash leaves what was deleted just now.
+	resp, _ = admin.post("/admin-panel/trash/", "/admin-panel/trash/purge/", url.Values{})
+	expectRedirect(t, resp, "/admin-panel/trash/?purged=0")
+	if len(db.GetDeletedQuestions()) != 2 || len(db.GetDeletedUsers()) != 1 {
+		t.Fatal("purge removed trash that has not expired")
+	}
+
+	trashAction := func(path string) {
+		t.Helper()
+		resp, _ := admin.post("/admin-panel/trash/", "/admin-panel/trash/"+path, url.Values{})
+		expectRedirect(t, resp, "/admin-panel/trash/")
+		if loc := resp.Header.Get("Location"); strings.Contains(loc, "error") {
+			t.Fatalf("%s: redirected to %s", path, loc)
+		}
+	}
+	trashAction("questions/" + strconv.Itoa(q.ID) + "/restore/")
+	if got := db.GetQuestionByID(q.ID); got == nil || got.Text != q.Text || got.QuestionStatus() != questionPublished {
+		t.Fatalf("restored question: %+v", got)
+	}
+	trashAction("questions/" + strconv.Itoa(other.ID) + "/purge/")
+	trashAction("users/" + strconv.Itoa(student.ID) + "/purge/")
+	if len(db.GetDeletedQuestions()) != 0 || len(db.GetDeletedUsers()) != 0 || db.GetQuestionRevisions(other.ID) != nil {
+		t.Fatal("purged items are still in the trash")
+	}
+	if db.GetQuestionByID(q.ID) == nil {
+		t.Fatal("purging one question removed another")
+	}
+
+	// Purging only ever removes trashed rows.
+	trashAction("questions/" + strconv.Itoa(q.ID) + "/purge/")
+	if db.GetQuestionByID(q.ID) == nil {
+		t.Fatal("purged a live question")
+	}
+	if resp, _ := admin.post("/admin-panel/trash/", "/admin-panel/trash/tickets/1/purge/", url.Values{}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown trash kind: status %d", resp.StatusCode)
+	}
+}
+
+func TestHandbookPages(t *testing.T) {
+	srv := newTestSite(t)
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	resp, _ := admin.post("/admin-panel/handbook/", "/admin-panel/handbook/", url.Values{"number": {"1"}, "title": {"Umumiy qoidalar"}})
+	expectRedirect(t, resp, "/admin-panel/handbook/")
+	if resp, body := admin.post("/admin-panel/handbook/", "/admin-panel/handbook/", url.Values{"number": {"1"}, "title": {"Takror"}}); resp.StatusCode != http.StatusOK || !strings.Contains(body, "alert-danger") {
+		t.Fatalf("duplicate chapter number: status %d, want the form with an error", resp.StatusCode)
+	}
+	chapter := strconv.Itoa(db.GetHandbookChapters()[0].ID)
+	for _, a := range []url.Values{
+		{"chapter_id": {chapter}, "number": {"1.1"}, "title": {"Atamalar"}, "body": {"Chorraha - yo'llar kesishgan joy."}},
+		{"chapter_id": {chapter}, "number": {"1.2"}, "title": {"Piyodalar"}, "body": {"Piyodalar o'tish joyida yo'l beriladi."}},
+	} {
+		resp, _ := admin.post("/admin-panel/handbook/articles/add/", "/admin-panel/handbook/articles/add/", a)
+		expectRedirect(t, resp, "/admin-panel/handbook/")
+	}
+	articles := db.GetHandbookChapters()[0].Articles
+	if len(articles) != 2 || articles[0].Position != 1 || articles[1].Position != 2 {
+		t.Fatalf("added articles: %+v", articles)
+	}
+	resp, _ = admin.postMultipart("/admin-panel/handbook/signs/", "/admin-panel/handbook/signs/", url.Values{"code": {"5.16"}, "name": {"Piyodalar o'tish joyi"}})
+	expectRedirect(t, resp, "/admin-panel/handbook/signs/")
+
+	q := addTestQuestion(t, admin, "Piyoda qayerda o'tadi?")
+	editURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/edit/"
+	resp, _ = admin.postMultipart(editURL, editURL, url.Values{
+		"text":           {q.Text},
+		"variant_a":      {"Ha"},
+		"variant_b":      {"Yo'q"},
+		"correct_answer": {"A"},
+		"article":        {strconv.Itoa(articles[1].ID)},
+	})
+	expectRedirect(t, resp, "/admin-panel/questions/")
+
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	first, second := "/handbook/articles/"+strconv.Itoa(articles[0].ID)+"/", "/handbook/articles/"+strconv.Itoa(articles[1].ID)+"/"
+	pages := []struct {
+		path  string
+		wants []string
+	}{
+		{"/handbook/", []string{"Umumiy qoidalar", first, second}},
+		{first, []string{"Chorraha", second}},
+		{second, []string{"Piyodalar o&#39;tish", first, "/questions/" + strconv.Itoa(q.ID) + "/"}},
+		{"/handbook/search/?q=piyodalar", []string{second, "5.16"}},
+		{"/handbook/signs/", []string{"5.16", "Piyodalar o&#39;tish joyi"}},
+	}
+	for _, p := range pages {
+		resp, body := student.get(p.path)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", p.path, resp.StatusCode)
+			continue
+		}
+		for _, want := range p.wants {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s does not show %q", p.path, want)
+			}
+		}
+	}
+	if _, body := student.get("/handbook/search/?q=piyodalar"); strings.Contains(body, first) {
+		t.Error("search found an article without the term")
+	}
+
+	resp, _ = admin.post("/admin-panel/handbook/", "/admin-panel/handbook/articles/"+strconv.Itoa(articles[1].ID)+"/delete/", url.Values{})
+	expectRedirect(t, resp, "/admin-panel/handbook/")
+	if resp, _ := student.get(second); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted article: status %d", resp.StatusCode)
+	}
+}
+
+func TestLanguageSwitcher(t *testing.T) {
+	srv := newTestSite(t)
+	visitor := newTestClient(t, srv)
+	if _, body := visitor.get("/login/"); !strings.Contains(body, `<html lang="uz">`) {
+		t.Fatal("login page is not in Uzbek by default")
+	}
+	req, _ := http.NewRequest("GET", srv.URL+"/login/", nil)
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9")
+	if _, body := visitor.do(req); !strings.Contains(body, `<html lang="ru">`) || !strings.Contains(body, "Войти") {
+		t.Error("login page ignores the browser's language")
+	}
+
+	req, _ = http.NewRequest("GET", srv.URL+"/lang/ru/", nil)
+	req.Header.Set("Referer", srv.URL+"/login/")
+	resp, _ := visitor.do(req)
+	expectRedirect(t, resp, srv.URL+"/login/")
+	if _, body := visitor.get("/login/"); !strings.Contains(body, "Войти") {
+		t.Error("switching to Russian did not stick")
+	}
+	if resp, _ := visitor.get("/lang/en/"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown language: status %d", resp.StatusCode)
+	}
+
+	// A signed-in user's choice is saved with the account, so it follows
+	// them to another browser and outweighs that browser's cookie.
+	student := newTestClient(t, srv)
+	student.login("user", "user")
+	resp, _ = student.get("/lang/ru/")
+	expectRedirect(t, resp, "/")
+	if u := db.GetUserByUsername("user"); u.Locale != "ru" {
+		t.Fatalf("saved locale %q, want ru", u.Locale)
+	}
+	visitor.get("/lang/uz/")
+	visitor.login("user", "user")
+	if _, body := visitor.get("/handbook/"); !strings.Contains(body, `<html lang="ru">`) || !strings.Contains(body, "Справочник") {
+		t.Error("handbook is not in the user's language")
+	}
+}
+
+func TestStaffRolePermissions(t *testing.T) {
+	srv := newTestSite(t)
+	for _, role := range []string{roleAuthor, roleReviewer} {
+		if err := createUser(role, "secret", role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.CreateCategory(&Category{Name: "Belgilar"}); err != nil {
+		t.Fatal(err)
+	}
+	category := strconv.Itoa(db.GetAllCategories()[0].ID)
+
+	adminOnly := []string{"/admin-panel/settings/", "/admin-panel/trash/", "/admin-panel/trash/purge/", "/admin-panel/media/collect/"}
+	writerOnly := []string{"/admin-panel/tickets/add/", "/admin-panel/tickets/generate/", "/admin-panel/categories/",
+		"/admin-panel/categories/" + category + "/delete/", "/admin-panel/handbook/", "/admin-panel/handbook/signs/"}
+	// Pages that change published questions without sending them to review.
+	reviewerPages := []string{"/admin-panel/questions/translations/", "/admin-panel/questions/duplicates/", "/admin-panel/questions/renumber/"}
+	reviewerOnly := append([]string{"/admin-panel/questions/duplicates/merge/", "/admin-panel/questions/renumber/save/"}, reviewerPages...)
+
+	// Every page with a form carries the session's CSRF token.
+	reviewer := newTestClient(t, srv)
+	reviewer.login(roleReviewer, "secret")
+	for _, path := range append(adminOnly, writerOnly...) {
+		if resp, _ := reviewer.post("/admin-panel/questions/renumber/", path, url.Values{"name": {"Yangi"}}); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("reviewer POST %s: status %d, want 403", path, resp.StatusCode)
+		}
+	}
+	for _, path := range []string{"/admin-panel/categories/", "/admin-panel/handbook/", "/admin-panel/tickets/"} {
+		if resp, body := reviewer.get(path); resp.StatusCode != http.StatusOK || strings.Contains(body, "action-btns") {
+			t.Errorf("reviewer GET %s: status %d, want the page without edit buttons", path, resp.StatusCode)
+		}
+	}
+	for _, path := range reviewerPages {
+		if resp, _ := reviewer.get(path); resp.StatusCode != http.StatusOK {
+			t.Errorf("reviewer GET %s: status %d, want 200", path, resp.StatusCode)
+		}
+	}
+	if n := len(db.GetAllCategories()); n != 1 {
+		t.Fatalf("%d categories after the reviewer's posts, want 1", n)
+	}
+
+	author := newTestClient(t, srv)
+	author.login(roleAuthor, "secret")
+	for _, path := range append(adminOnly, reviewerOnly...) {
+		if resp, _ := author.post("/admin-panel/questions/add/", path, url.Values{}); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("author POST %s: status %d, want 403", path, resp.StatusCode)
+		}
+	}
+	for _, path := range reviewerPages {
+		if resp, _ := author.get(path); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("author GET %s: status %d, want 403", path, resp.StatusCode)
+		}
+	}
+	resp, _ := author.post("/admin-panel/categories/", "/admin-panel/categories/", url.Values{"name": {"Chorrahalar"}})
+	expectRedirect(t, resp, "/admin-panel/categories/")
+	if n := len(db.GetAllCategories()); n != 2 {
+		t.Fatalf("%d categories after the author's add, want 2", n)
+	}
+}
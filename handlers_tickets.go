@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// parseNumberList reads question numbers such as "1, 4, 10-15" in the order
+// given.
+func parseNumberList(s string) ([]int, error) {
+	var nums []int
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\r' })
+	for _, f := range fields {
+		if lo, hi, ok := strings.Cut(f, "-"); ok {
+			from, err1 := strconv.Atoi(lo)
+			to, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil || from > to {
+				return nil, newMessageError("error.invalid_range", f)
+			}
+			for n := from; n <= to; n++ {
+				nums = append(nums, n)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, newMessageError("error.invalid_number", f)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// formatNumberList is the inverse of parseNumberList, collapsing runs into
+// ranges.
+func formatNumberList(nums []int) string {
+	var parts []string
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			parts = append(parts, fmt.Sprintf("%d-%d", nums[i], nums[j]))
+		} else {
+			for k := i; k <= j; k++ {
+				parts = append(parts, strconv.Itoa(nums[k]))
+			}
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ticketFromForm fills t from the add/edit form. It returns a user-facing
+// error message when the input is invalid.
+func ticketFromForm(r *http.Request, t *Ticket) string {
+	number, err := strconv.Atoi(r.FormValue("number"))
+	if err != nil || number < 1 {
+		return tr(r, "error.ticket_number_required")
+	}
+	t.Number = number
+	t.Title = strings.TrimSpace(r.FormValue("title"))
+
+	nums, err := parseNumberList(r.FormValue("questions"))
+	if err != nil {
+		return tr(r, "error.ticket_question_numbers", errorText(requestLocale(r), err))
+	}
+	if len(nums) == 0 {
+		return tr(r, "error.ticket_questions_required")
+	}
+	byNumber := make(map[int]int)
+	for _, q := range db.GetAllQuestions() {
+		byNumber[q.Number] = q.ID
+	}
+	seen := make(map[int]bool)
+	t.QuestionIDs = nil
+	var missing []string
+	for _, n := range nums {
+		id, ok := byNumber[n]
+		if !ok {
+			missing = append(missing, strconv.Itoa(n))
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			t.QuestionIDs = append(t.QuestionIDs, id)
+		}
+	}
+	if len(missing) > 0 {
+		return tr(r, "error.ticket_questions_missing", strings.Join(missing, ", "))
+	}
+	return ""
+}
+
+// ticketQuestionNumbers returns the bank numbers of a ticket's questions in
+// ticket order.
+func ticketQuestionNumbers(t *Ticket) []int {
+	qMap := db.GetQuestionsByIDs(t.QuestionIDs)
+	var nums []int
+	for _, id := range t.QuestionIDs {
+		if q, ok := qMap[id]; ok {
+			nums = append(nums, q.Number)
+		}
+	}
+	return nums
+}
+
+func ticketsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	renderTemplate(w, r, "tickets.html", map[string]interface{}{
+		"CurrentPage": "tickets",
+		"Tickets":     db.GetAllTickets(),
+		"Stats":       db.GetTicketStats(user.ID),
+	})
+}
+
+func startTicketHandler(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/tickets/", http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	ticket := db.GetTicketByID(id)
+	if ticket == nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Questions taken out of review or retired since the ticket was made
+	// are left out rather than served.
+	qMap := db.GetQuestionsByIDs(ticket.QuestionIDs)
+	var questionIDs []int
+	for _, qid := range ticket.QuestionIDs {
+		if q, ok := qMap[qid]; ok && q.IsPublished() {
+			questionIDs = append(questionIDs, qid)
+		}
+	}
+	if len(questionIDs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	session := newTestSession(user.ID, len(questionIDs))
+	session.TicketID = ticket.ID
+	if err := db.CreateTestSession(session, questionIDs); err != nil {
+		http.Error(w, "Error creating test session", 500)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/test/%d/", session.ID), http.StatusFound)
+}
+
+func adminTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	tickets := db.GetAllTickets()
+	renderTemplate(w, r, "admin/tickets.html", map[string]interface{}{
+		"CurrentPage":    "admin_tickets",
+		"Tickets":        tickets,
+		"Stats":          db.GetTicketStats(0),
+		"TotalQuestions": db.CountQuestions(),
+	})
+}
+
+// adminGenerateTicketsHandler rebuilds all tickets by cutting the published
+// questions, ordered by number, into consecutive groups of the requested
+// size.
+func adminGenerateTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		size, _ := strconv.Atoi(r.FormValue("size"))
+		if size < 1 {
+			size = 20
+		}
+		questions := db.SearchQuestions(QuestionFilter{Status: questionPublished})
+		sort.Slice(questions, func(i, j int) bool { return questions[i].Number < questions[j].Number })
+		var tickets []*Ticket
+		for start := 0; start < len(questions); start += size {
+			end := min(start+size, len(questions))
+			t := &Ticket{Number: len(tickets) + 1}
+			t.Title = fmt.Sprintf("Bilet %d", t.Number)
+			for _, q := range questions[start:end] {
+				t.QuestionIDs = append(t.QuestionIDs, q.ID)
+			}
+			tickets = append(tickets, t)
+		}
+		if err := db.ReplaceAllTickets(tickets); err != nil {
+			http.Error(w, "Error generating tickets", 500)
+			return
+		}
+	}
+	http.Redirect(w, r, "/admin-panel/tickets/", http.StatusFound)
+}
+
+func adminAddTicketHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage": "admin_tickets",
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		t := &Ticket{}
+		if msg := ticketFromForm(r, t); msg != "" {
+			data["Error"] = msg
+		} else if err := db.CreateTicket(t); err != nil {
+			data["Error"] = tr(r, "error.ticket_number_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/tickets/", http.StatusFound)
+			return
+		}
+		data["Form"] = map[string]string{
+			"Number":    r.FormValue("number"),
+			"Title":     r.FormValue("title"),
+			"Questions": r.FormValue("questions"),
+		}
+	} else {
+		next := 1
+		for _, t := range db.GetAllTickets() {
+			if t.Number >= next {
+				next = t.Number + 1
+			}
+		}
+		data["Form"] = map[string]string{"Number": strconv.Itoa(next)}
+	}
+
+	renderTemplate(w, r, "admin/add_ticket.html", data)
+}
+
+func adminEditTicketHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	ticket := db.GetTicketByID(id)
+	if ticket == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := map[string]interface{}{
+		"CurrentPage": "admin_tickets",
+		"Ticket":      ticket,
+		"Form": map[string]string{
+			"Number":    strconv.Itoa(ticket.Number),
+			"Title":     ticket.Title,
+			"Questions": formatNumberList(ticketQuestionNumbers(ticket)),
+		},
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		if msg := ticketFromForm(r, ticket); msg != "" {
+			data["Error"] = msg
+		} else if err := db.UpdateTicket(ticket); err != nil {
+			data["Error"] = tr(r, "error.ticket_number_taken")
+		} else {
+			http.Redirect(w, r, "/admin-panel/tickets/", http.StatusFound)
+			return
+		}
+		data["Form"] = map[string]string{
+			"Number":    r.FormValue("number"),
+			"Title":     r.FormValue("title"),
+			"Questions": r.FormValue("questions"),
+		}
+	}
+
+	renderTemplate(w, r, "admin/edit_ticket.html", data)
+}
+
+func adminDeleteTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		db.DeleteTicket(id)
+	}
+	http.Redirect(w, r, "/admin-panel/tickets/", http.StatusFound)
+}
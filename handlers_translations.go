@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// translationVariant is one row of the translation form: the original
+// variant next to its translation and, for Cyrillic, a transliteration to
+// start from.
+type translationVariant struct {
+	Letter    string
+	Original  string
+	Text      string
+	Suggested string
+}
+
+func findTranslationLanguage(code string) (contentLanguage, bool) {
+	for _, l := range translationLanguages() {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return contentLanguage{}, false
+}
+
+// translationFromForm reads the translation form for q. Fields left empty
+// fall back to the original, so only filled-in variants are kept.
+func translationFromForm(r *http.Request, q *Question, lang string) *QuestionTranslation {
+	t := &QuestionTranslation{
+		QuestionID:  q.ID,
+		Lang:        lang,
+		Text:        strings.TrimSpace(r.FormValue("text")),
+		Explanation: strings.TrimSpace(r.FormValue("explanation")),
+	}
+	for _, v := range q.VariantsList {
+		if text := strings.TrimSpace(r.FormValue("variant_" + strings.ToLower(v.Letter))); text != "" {
+			t.VariantsList = append(t.VariantsList, Variant{Letter: v.Letter, Text: text})
+		}
+	}
+	return t
+}
+
+// adminTranslateQuestionHandler edits one question's translation into one
+// language. Saving an empty form removes the translation.
+func adminTranslateQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	question := db.GetQuestionByID(id)
+	lang, ok := findTranslationLanguage(mux.Vars(r)["lang"])
+	if question == nil || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		if !verifyCSRFToken(r, w) {
+			http.Error(w, "CSRF token invalid", http.StatusForbidden)
+			return
+		}
+		t := translationFromForm(r, question, lang.Code)
+		if t.Text == "" && t.Explanation == "" && len(t.VariantsList) == 0 {
+			db.DeleteQuestionTranslation(question.ID, lang.Code)
+		} else {
+			db.SaveQuestionTranslation(t)
+		}
+		http.Redirect(w, r, "/admin-panel/questions/"+strconv.Itoa(question.ID)+"/translations/"+lang.Code+"/?saved=1", http.StatusFound)
+		return
+	}
+
+	t := db.GetQuestionTranslations(lang.Code, []int{question.ID})[question.ID]
+	current := t
+	if current == nil {
+		current = &QuestionTranslation{}
+	}
+	// suggested is what readers see today without a human translation.
+	suggested := *question
+	suggested.VariantsList = append([]Variant(nil), question.VariantsList...)
+	localizeQuestion(&suggested, lang.Code, nil)
+	canSuggest := suggested.Text != question.Text
+
+	variants := make([]translationVariant, len(question.VariantsList))
+	for i, v := range question.VariantsList {
+		variants[i] = translationVariant{
+			Letter:    v.Letter,
+			Original:  v.Text,
+			Text:      current.variantText(v.Letter),
+			Suggested: suggested.VariantsList[i].Text,
+		}
+	}
+
+	data := map[string]interface{}{
+		"CurrentPage":  "admin_questions",
+		"QuestionData": question,
+		"Lang":         lang,
+		"Languages":    translationLanguages(),
+		"Translation":  current,
+		"Exists":       t != nil,
+		"Status":       translationStatus(question, t),
+		"Variants":     variants,
+		"Suggested":    &suggested,
+		"CanSuggest":   canSuggest,
+	}
+	if r.URL.Query().Get("saved") != "" {
+		data["Success"] = tr(r, "success.translation_saved")
+	}
+	renderTemplate(w, r, "admin/translate_question.html", data)
+}
+
+// translationCell is a question's translation state in one language.
+type translationCell struct {
+	Lang   string
+	Status string
+	Label  string
+}
+
+// translationStatusLabel is the message key for a status in the report.
+func translationStatusLabel(status, lang string) string {
+	if status == translationMissing && lang == langUzCyrillic {
+		return "translation.status_auto"
+	}
+	return "translation.status_" + status
+}
+
+type translationReportRow struct {
+	Question *Question
+	Cells    []translationCell
+}
+
+// translationSummary counts a language's questions by translation state.
+type translationSummary struct {
+	Language contentLanguage
+	Counts   map[string]int
+	Percent  int
+}
+
+// adminTranslationsHandler reports which questions are untranslated,
+// partly translated or changed since they were translated. Only questions
+// that need work are listed unless all=1 is given.
+func adminTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	questions := db.GetAllQuestions()
+	ids := make([]int, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	showAll := r.URL.Query().Get("all") != ""
+
+	languages := translationLanguages()
+	translations := make([]map[int]*QuestionTranslation, len(languages))
+	summaries := make([]*translationSummary, len(languages))
+	for i, l := range languages {
+		translations[i] = db.GetQuestionTranslations(l.Code, ids)
+		summaries[i] = &translationSummary{Language: l, Counts: make(map[string]int)}
+	}
+
+	var rows []*translationReportRow
+	for _, q := range questions {
+		row := &translationReportRow{Question: q}
+		done := true
+		for i, l := range languages {
+			status := translationStatus(q, translations[i][q.ID])
+			summaries[i].Counts[status]++
+			row.Cells = append(row.Cells, translationCell{
+				Lang:   l.Code,
+				Status: status,
+				Label:  translationStatusLabel(status, l.Code),
+			})
+			if status != translationComplete {
+				done = false
+			}
+		}
+		if showAll || !done {
+			rows = append(rows, row)
+		}
+	}
+	for _, s := range summaries {
+		if len(questions) > 0 {
+			s.Percent = s.Counts[translationComplete] * 100 / len(questions)
+		}
+	}
+
+	renderTemplate(w, r, "admin/translations.html", map[string]interface{}{
+		"CurrentPage": "admin_questions",
+		"Languages":   languages,
+		"Summaries":   summaries,
+		"Rows":        rows,
+		"Total":       len(questions),
+		"ShowAll":     showAll,
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func adminTrashHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"CurrentPage":   "admin_trash",
+		"RetentionDays": getTrashRetentionDays(),
+		"Questions":     db.GetDeletedQuestions(),
+		"Users":         db.GetDeletedUsers(),
+	}
+	switch {
+	case r.URL.Query().Get("error") != "":
+		data["Error"] = tr(r, "error.restore_trash_failed")
+	case r.URL.Query().Get("purged") != "":
+		data["Success"] = tr(r, "success.trash_purged", r.URL.Query().Get("purged"))
+	}
+	renderTemplate(w, r, "admin/trash.html", data)
+}
+
+// adminTrashActionHandler restores or purges one trashed question or user.
+func adminTrashActionHandler(w http.ResponseWriter, r *http.Request) {
+	target := "/admin-panel/trash/"
+	if r.Method != "POST" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	var err error
+	switch vars["kind"] + "/" + vars["action"] {
+	case "questions/restore":
+		// Restoring the newest revision brings the question back unchanged.
+		if revisions := db.GetQuestionRevisions(id); len(revisions) > 0 {
+			err = db.RestoreQuestionRevision(id, revisions[0].Revision, getCurrentUser(r).ID, nil, "")
+		}
+	case "questions/purge":
+		err = db.PurgeQuestion(id)
+	case "users/restore":
+		err = db.RestoreUser(id)
+	case "users/purge":
+		err = db.PurgeUser(id)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		target += "?error=1"
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// adminPurgeTrashHandler empties expired trash right away instead of
+// waiting for the background sweeper.
+func adminPurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Redirect(w, r, "/admin-panel/trash/", http.StatusFound)
+		return
+	}
+	r.ParseForm()
+	if !verifyCSRFToken(r, w) {
+		http.Error(w, "CSRF token invalid", http.StatusForbidden)
+		return
+	}
+	n, err := purgeExpiredTrash()
+	if err != nil {
+		http.Redirect(w, r, "/admin-panel/trash/?error=1", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/admin-panel/trash/?purged="+strconv.Itoa(n), http.StatusFound)
+}
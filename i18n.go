@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// UI locales. Messages are looked up by key in the request's locale, then
+// in defaultLocale; a key missing from both is shown as it is.
+const defaultLocale = "uz"
+
+type uiLocale struct {
+	Code string
+	Name string
+}
+
+var uiLocales = []uiLocale{
+	{"uz", "O'zbekcha"},
+	{"ru", "Русский"},
+}
+
+var catalogs = map[string]map[string]string{
+	"uz": messagesUz,
+	"ru": messagesRu,
+}
+
+// localeCookie remembers the locale picked with the language switch for
+// visitors who are not logged in.
+const localeCookie = "lang"
+
+func isUILocale(code string) bool {
+	_, ok := catalogs[code]
+	return ok
+}
+
+// translate returns the message for key in locale, formatted with args
+// when there are any.
+func translate(locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		msg, ok = catalogs[defaultLocale][key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// messageError is an error whose text is a catalog message, for errors
+// raised away from the request that handlers show to the user. Error gives
+// the default locale; errorText gives any other.
+type messageError struct {
+	Key  string
+	Args []interface{}
+}
+
+func newMessageError(key string, args ...interface{}) error {
+	return &messageError{Key: key, Args: args}
+}
+
+func (e *messageError) Error() string {
+	return translate(defaultLocale, e.Key, e.Args...)
+}
+
+// errorText is err in locale when it is a catalog message.
+func errorText(locale string, err error) string {
+	var me *messageError
+	if errors.As(err, &me) {
+		return translate(locale, me.Key, me.Args...)
+	}
+	return err.Error()
+}
+
+// translator is the T template function for locale.
+func translator(locale string) func(string, ...interface{}) string {
+	return func(key string, args ...interface{}) string {
+		return translate(locale, key, args...)
+	}
+}
+
+// requestLocale picks the UI locale for r: the user's profile setting, then
+// the language switch cookie, then the browser's Accept-Language.
+func requestLocale(r *http.Request) string {
+	if u := getCurrentUser(r); u != nil && isUILocale(u.Locale) {
+		return u.Locale
+	}
+	if c, err := r.Cookie(localeCookie); err == nil && isUILocale(c.Value) {
+		return c.Value
+	}
+	if l := acceptLanguage(r.Header.Get("Accept-Language")); l != "" {
+		return l
+	}
+	return defaultLocale
+}
+
+// acceptLanguage returns the supported locale the browser prefers most, or
+// "" when it names none of them. "ru-RU;q=0.8" counts as "ru".
+func acceptLanguage(header string) string {
+	type choice struct {
+		code string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if isUILocale(primary) && q > 0 {
+			choices = append(choices, choice{primary, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	if len(choices) == 0 {
+		return ""
+	}
+	return choices[0].code
+}
+
+// tr translates key into the locale of r, for messages handlers build.
+func tr(r *http.Request, key string, args ...interface{}) string {
+	return translate(requestLocale(r), key, args...)
+}
+
+// setLocaleHandler is the language switch: it stores the locale in a
+// cookie, and in the profile of a logged-in user, then goes back.
+func setLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	if !isUILocale(code) {
+		http.NotFound(w, r)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: localeCookie, Value: code, Path: "/", MaxAge: 365 * 24 * 3600})
+	if u := getCurrentUser(r); u != nil {
+		db.UpdateUserLocale(u.ID, code)
+	}
+	referer := r.Header.Get("Referer")
+	if referer == "" {
+		referer = "/"
+	}
+	http.Redirect(w, r, referer, http.StatusFound)
+}
+
+// checkCatalogs logs the keys a locale lacks, so gaps in a translation show
+// up at startup rather than as Uzbek text on a Russian page.
+func checkCatalogs() {
+	for code, messages := range catalogs {
+		if code == defaultLocale {
+			continue
+		}
+		var missing []string
+		for key := range catalogs[defaultLocale] {
+			if _, ok := messages[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			log.Printf("Locale %s lacks %d messages: %s", code, len(missing), strings.Join(missing, ", "))
+		}
+	}
+}
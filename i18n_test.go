@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"ru", "ru"},
+		{"ru-RU,ru;q=0.9,en;q=0.8", "ru"},
+		{"en-US,ru;q=0.5,uz;q=0.7", "uz"},
+		{"uz;q=0,ru;q=0.1", "ru"},
+		{"en, de", ""},
+	}
+	for _, tt := range tests {
+		if got := acceptLanguage(tt.header); got != tt.want {
+			t.Errorf("acceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for code, messages := range catalogs {
+		for key := range catalogs[defaultLocale] {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("locale %s lacks %s", code, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Limits and sizes of uploaded images. Every upload is decoded and
+// re-encoded, which drops EXIF and any other metadata, and is stored at
+// most maxImageSide pixels on its longer side, with a thumbnail next to it.
+// Animated GIFs keep their first frame.
+const (
+	maxImageBytes  = 10 << 20
+	maxImagePixels = 40_000_000
+	maxImageSide   = 1280
+	thumbImageSide = 320
+	jpegQuality    = 82
+)
+
+// thumbSuffix marks the thumbnail of an image: "questions/1.jpg" has its
+// thumbnail at "questions/1_thumb.jpg".
+const thumbSuffix = "_thumb"
+
+// imageTypes are the formats accepted for upload, by sniffed content type.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// saveImage validates an uploaded image and stores it under media/dir,
+// resized and with its thumbnail and WebP variants. Files are named by a
+// hash of the upload, so the same image uploaded twice is stored once. It
+// returns the path of the main image relative to media; the variants are
+// found from it.
+func saveImage(src io.Reader, dir string) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(src, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", newMessageError("error.image_too_large", maxImageBytes>>20)
+	}
+	if !imageTypes[http.DetectContentType(data)] {
+		return "", newMessageError("error.image_type")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", newMessageError("error.image_corrupt")
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return "", newMessageError("error.image_dimensions", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", newMessageError("error.image_corrupt")
+	}
+	img = applyOrientation(img, jpegOrientation(data))
+
+	sum := sha256.Sum256(data)
+	base := dir + "/" + hex.EncodeToString(sum[:16])
+	for _, ext := range []string{".jpg", ".png"} {
+		if touchImage(base+ext) == nil {
+			return base + ext, nil
+		}
+	}
+	full := fitImage(img, maxImageSide)
+	ext := ".jpg"
+	if !full.Opaque() {
+		ext = ".png"
+	}
+	name := base + ext
+	if err := writeImageVariants(name, full); err != nil {
+		return "", err
+	}
+	if err := writeImageVariants(imageVariant(name, thumbSuffix), fitImage(full, thumbImageSide)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// touchImage marks a stored image and its variants as just written, so
+// the garbage collector leaves an image that is being reused alone until the
+// upload that reuses it is saved. It asks the store itself rather than a
+// cache and fails if the image or its thumbnail is gone, say collected by
+// another instance, in which case the caller writes them again. WebP copies
+// are only kept where they are smaller, so they are touched where they exist.
+func touchImage(name string) error {
+	for _, p := range []string{name, imageVariant(name, thumbSuffix)} {
+		if err := mediaStore.Touch(p); err != nil {
+			return err
+		}
+		if mediaFileExists(webpVariant(p)) {
+			if err := mediaStore.Touch(webpVariant(p)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeImageVariants stores img at name, as JPEG or PNG by its extension,
+// and next to it a lossless WebP copy when that comes out smaller. For
+// photos it rarely does, and they are served as JPEG.
+func writeImageVariants(name string, img image.Image) error {
+	var buf bytes.Buffer
+	var err error
+	if strings.HasSuffix(name, ".png") {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return err
+	}
+	if err := mediaStore.Put(name, buf.Bytes()); err != nil {
+		return err
+	}
+
+	var webp bytes.Buffer
+	if err := nativewebp.Encode(&webp, img, nil); err != nil || webp.Len() >= buf.Len() {
+		return mediaStore.Delete(webpVariant(name))
+	}
+	return mediaStore.Put(webpVariant(name), webp.Bytes())
+}
+
+// fitImage scales img down so that neither side exceeds side pixels. A
+// smaller image is only copied, so the result is always an *image.NRGBA.
+func fitImage(img image.Image, side int) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > side || h > side {
+		if w >= h {
+			w, h = side, max(1, h*side/w)
+		} else {
+			w, h = max(1, w*side/h), side
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	}
+	return dst
+}
+
+// imageVariant is the path of a variant of the image at p, such as its
+// thumbnail: suffix goes before the extension.
+func imageVariant(p, suffix string) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + suffix + ext
+}
+
+// webpVariant is where the WebP copy of the image at p is stored.
+func webpVariant(p string) string {
+	return strings.TrimSuffix(p, path.Ext(p)) + ".webp"
+}
+
+// imageURL is the URL of the image at p in the given size: "thumb" gives the
+// thumbnail where one was made, anything else the full image. The media
+// store decides whether that is a /media/ path or a signed bucket URL.
+func imageURL(p string, size ...string) string {
+	if p == "" {
+		return ""
+	}
+	if len(size) > 0 && size[0] == "thumb" {
+		if thumb := imageVariant(p, thumbSuffix); mediaFileExists(thumb) {
+			return mediaStore.URL(thumb)
+		}
+	}
+	return mediaStore.URL(p)
+}
+
+// mediaHandler serves /media/ from the media store. Browsers that accept
+// WebP get the WebP copy of an image when there is one smaller than the
+// image, and the image itself otherwise, such as for the larger copies
+// stored before only smaller ones were kept. Files are named by their
+// content, so they can be cached for good.
+func mediaHandler() http.Handler {
+	return http.StripPrefix("/media/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if !cleanMediaName(p) {
+			http.NotFound(w, r)
+			return
+		}
+		var f io.ReadSeekCloser
+		var info mediaFile
+		err := fs.ErrNotExist
+		if ext := path.Ext(p); ext == ".jpg" || ext == ".png" {
+			w.Header().Set("Vary", "Accept")
+			if strings.Contains(r.Header.Get("Accept"), "image/webp") && smallerWebP(p) {
+				if f, info, err = mediaStore.Open(webpVariant(p)); err == nil {
+					p = webpVariant(p)
+				}
+			}
+		}
+		if err != nil {
+			f, info, err = mediaStore.Open(p)
+		}
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		http.ServeContent(w, r, p, info.ModTime, f)
+	}))
+}
+
+// smallerWebP reports whether the image at p has a WebP copy that is
+// smaller than it.
+func smallerWebP(p string) bool {
+	webp, err := mediaStore.Stat(webpVariant(p))
+	if err != nil {
+		return false
+	}
+	original, err := mediaStore.Stat(p)
+	return err == nil && webp.Size < original.Size
+}
+
+// jpegOrientation reads the EXIF orientation (1-8) of a JPEG, or returns 1
+// when there is none. Phones store photos unrotated and rely on this tag,
+// which would be lost with the rest of the EXIF data.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF
+// header as found in a JPEG's EXIF segment.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// applyOrientation turns img upright for an EXIF orientation value.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testPhoto is a noisy photo-like JPEG, where lossless WebP comes out
+// several times larger.
+func testPhoto(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 1600, 900))
+	for y := 0; y < 900; y++ {
+		for x := 0; x < 1600; x++ {
+			img.Set(x, y, color.RGBA{uint8(x*7 + y*13), uint8(x * y), uint8(y*5 ^ x), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveImageVariants(t *testing.T) {
+	useTestStore(t)
+	upload := testPhoto(t)
+	name, err := saveImage(bytes.NewReader(upload), "questions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "questions/") || !strings.HasSuffix(name, ".jpg") {
+		t.Fatalf("stored as %q", name)
+	}
+	thumb := imageVariant(name, thumbSuffix)
+	for _, p := range []string{name, thumb} {
+		if !mediaFileExists(p) {
+			t.Errorf("%s not stored", p)
+		}
+	}
+	if mediaFileExists(webpVariant(name)) {
+		t.Error("WebP copy of a photo kept although it is larger")
+	}
+	f, _, err := mediaStore.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil || cfg.Width != maxImageSide || cfg.Height != 720 {
+		t.Errorf("stored image %dx%d (%v), want %dx720", cfg.Width, cfg.Height, err, maxImageSide)
+	}
+
+	again, err := saveImage(bytes.NewReader(upload), "questions")
+	if err != nil || again != name {
+		t.Errorf("same upload stored as %q (%v), want %q", again, err, name)
+	}
+
+	// A variant removed behind the app's back, as by the garbage
+	// collector of another instance, is written again.
+	if err := mediaStore.Delete(thumb); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := saveImage(bytes.NewReader(upload), "questions"); err != nil || again != name {
+		t.Fatalf("re-upload stored as %q (%v), want %q", again, err, name)
+	}
+	if !mediaFileExists(thumb) {
+		t.Error("deleted variant not written again")
+	}
+
+	if _, err := saveImage(strings.NewReader("not an image"), "questions"); err == nil {
+		t.Error("text accepted as an image")
+	}
+}
+
+func TestMediaHandlerServesSmallerFile(t *testing.T) {
+	useTestStore(t)
+	photo, err := saveImage(bytes.NewReader(testPhoto(t)), "questions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat, err := saveImage(bytes.NewReader(testImage(t, color.RGBA{0, 120, 200, 255})), "questions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A copy stored back when every image kept one, however large.
+	legacy := imageVariant(photo, thumbSuffix)
+	if err := mediaStore.Put(webpVariant(legacy), bytes.Repeat([]byte{0}, 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := mediaHandler()
+	serve := func(p string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/media/"+p, nil)
+		req.Header.Set("Accept", "image/avif,image/webp,*/*")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", p, rec.Code)
+		}
+		return rec
+	}
+	size := func(p string) int {
+		f, err := mediaStore.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(f.Size)
+	}
+
+	for _, p := range []string{photo, legacy} {
+		rec := serve(p)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/jpeg" || rec.Body.Len() != size(p) {
+			t.Errorf("%s served as %s, %d bytes; want the %d-byte JPEG", p, ct, rec.Body.Len(), size(p))
+		}
+	}
+	rec := serve(flat)
+	if ct := rec.Header().Get("Content-Type"); ct != "image/webp" || rec.Body.Len() >= size(flat) {
+		t.Errorf("%s served as %s, %d bytes; want a WebP smaller than the %d-byte JPEG", flat, ct, rec.Body.Len(), size(flat))
+	}
+}
@@ -0,0 +1,666 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// importQuestion is one question in the import format. JSON files hold an
+// array of these; CSV and XLSX files have a header row with the columns
+// number, text, image, category, correct_answer and variant_a ... variant_j.
+// An empty number means "next free number". The image and the explanation
+// image are file names inside the ZIP archive or paths already under
+// media/. An empty type is a single choice question; multiple choice
+// answers list every correct letter, ordering questions list their variants
+// in the right order, and hotspot questions give their areas as
+// "x,y,w,h; ..." instead of variants.
+type importQuestion struct {
+	Number           int      `json:"number,omitempty"`
+	Text             string   `json:"text"`
+	Image            string   `json:"image,omitempty"`
+	Category         string   `json:"category,omitempty"`
+	Type             string   `json:"type,omitempty"`
+	Variants         []string `json:"variants"`
+	CorrectAnswer    string   `json:"correct_answer"`
+	Hotspots         string   `json:"hotspots,omitempty"`
+	Explanation      string   `json:"explanation,omitempty"`
+	ExplanationImage string   `json:"explanation_image,omitempty"`
+	RuleRef          string   `json:"rule_ref,omitempty"`
+	Status           string   `json:"status,omitempty"`
+}
+
+const variantLetters = "ABCDEFGHIJ"
+
+// importRow is one parsed question with its source line and the problems
+// found while checking it.
+type importRow struct {
+	Line     int
+	Data     importQuestion
+	Action   string
+	Errors   []string
+	existing *Question
+	// badNumber is a number cell that could not be read, reported by
+	// checkImport.
+	badNumber string
+}
+
+func (r *importRow) fail(locale, key string, args ...interface{}) {
+	r.Errors = append(r.Errors, translate(locale, key, args...))
+}
+
+type importOptions struct {
+	DryRun bool
+	// UserID is recorded as the author of the question revisions; 0 for
+	// the command line.
+	UserID int
+	// Upsert updates questions whose number already exists instead of
+	// rejecting the row.
+	Upsert bool
+	// Locale is the language of the row errors in the report; empty means
+	// the default.
+	Locale string
+	// Status is the review status of the questions the import adds. Empty,
+	// as from the command line, publishes them. Anything but published also
+	// sends the published questions it updates back to review.
+	Status string
+}
+
+// publishes reports whether the import may publish questions, and so take
+// the status a row gives.
+func (o importOptions) publishes() bool {
+	return o.Status == "" || o.Status == questionPublished
+}
+
+// rowStatus is the status d gives its question, as written by the export,
+// or empty to leave it to Status. Only imports that may publish take it.
+func (o importOptions) rowStatus(d importQuestion) string {
+	if !o.publishes() {
+		return ""
+	}
+	return d.Status
+}
+
+// importReport is the outcome of checking, and unless it was a dry run
+// applying, an import file.
+type importReport struct {
+	Rows          []*importRow
+	Created       int
+	Updated       int
+	Unchanged     int
+	Failed        int
+	NewCategories []string
+	Applied       bool
+}
+
+func (r *importReport) HasErrors() bool {
+	return r.Failed > 0
+}
+
+// importFile is a parsed upload: the question rows and, for ZIP archives,
+// the images they may reference keyed by lower-case base name.
+type importFile struct {
+	Rows   []*importRow
+	Images map[string][]byte
+}
+
+// readImportFile parses a CSV, JSON, XLSX or ZIP file; the format is taken
+// from the extension of name. A ZIP holds exactly one CSV, JSON or XLSX file
+// plus images.
+func readImportFile(name string, data []byte) (*importFile, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".csv":
+		table, err := readCSVTable(data)
+		if err != nil {
+			return nil, err
+		}
+		return rowsFromTable(table)
+	case ".xlsx":
+		table, err := readXLSXTable(data)
+		if err != nil {
+			return nil, err
+		}
+		return rowsFromTable(table)
+	case ".json":
+		var items []importQuestion
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, newMessageError("import.json_unreadable", err)
+		}
+		f := &importFile{}
+		for i, item := range items {
+			f.Rows = append(f.Rows, &importRow{Line: i + 1, Data: item})
+		}
+		return f, nil
+	case ".zip":
+		return readImportZip(data)
+	}
+	return nil, newMessageError("import.unsupported_type", ext)
+}
+
+func readImportZip(data []byte) (*importFile, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, newMessageError("import.zip_unreadable", err)
+	}
+	images := make(map[string][]byte)
+	var bankName string
+	var bank []byte
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || strings.HasPrefix(path.Base(zf.Name), ".") {
+			continue
+		}
+		content, err := readZipFile(zf)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(path.Ext(zf.Name)) {
+		case ".csv", ".json", ".xlsx":
+			if bank != nil {
+				return nil, newMessageError("import.zip_several_banks", bankName, zf.Name)
+			}
+			bankName, bank = zf.Name, content
+		default:
+			images[strings.ToLower(path.Base(zf.Name))] = content
+		}
+	}
+	if bank == nil {
+		return nil, newMessageError("import.zip_no_bank")
+	}
+	f, err := readImportFile(bankName, bank)
+	if err != nil {
+		return nil, err
+	}
+	f.Images = images
+	return f, nil
+}
+
+func readZipFile(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// readCSVTable reads comma- or semicolon-separated values; spreadsheets in
+// Uzbek and Russian locales save with semicolons.
+func readCSVTable(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	cr := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	table, err := cr.ReadAll()
+	if err != nil {
+		return nil, newMessageError("import.csv_unreadable", err)
+	}
+	return table, nil
+}
+
+// rowsFromTable maps a header row and the rows below it to import rows.
+// Blank rows are skipped; line numbers stay those of the source file.
+func rowsFromTable(table [][]string) (*importFile, error) {
+	if len(table) == 0 {
+		return nil, newMessageError("import.file_empty")
+	}
+	columns := make(map[string]int)
+	for i, h := range table[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"text", "correct_answer"} {
+		if _, ok := columns[required]; !ok {
+			return nil, newMessageError("import.column_missing", required)
+		}
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	f := &importFile{}
+	for i, record := range table[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		row := &importRow{Line: i + 2}
+		row.Data = importQuestion{
+			Text:             cell(record, "text"),
+			Image:            cell(record, "image"),
+			Category:         cell(record, "category"),
+			Type:             cell(record, "type"),
+			CorrectAnswer:    cell(record, "correct_answer"),
+			Hotspots:         cell(record, "hotspots"),
+			Explanation:      cell(record, "explanation"),
+			ExplanationImage: cell(record, "explanation_image"),
+			RuleRef:          cell(record, "rule_ref"),
+			Status:           cell(record, "status"),
+		}
+		if s := cell(record, "number"); s != "" {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil || n != float64(int(n)) {
+				row.badNumber = s
+			}
+			row.Data.Number = int(n)
+		}
+		for _, l := range variantLetters {
+			if v := cell(record, "variant_"+strings.ToLower(string(l))); v != "" {
+				row.Data.Variants = append(row.Data.Variants, v)
+			}
+		}
+		f.Rows = append(f.Rows, row)
+	}
+	return f, nil
+}
+
+// checkImport validates every row against the bank and the options and
+// decides whether it creates or updates a question, or leaves one that
+// already reads the same alone. Rows without a number get the next free
+// ones.
+func checkImport(f *importFile, opts importOptions) *importReport {
+	report := &importReport{Rows: f.Rows}
+	byNumber := make(map[int]*Question)
+	for _, q := range db.GetAllQuestions() {
+		byNumber[q.Number] = q
+	}
+	trashed := make(map[int]bool)
+	for _, q := range db.GetDeletedQuestions() {
+		trashed[q.Number] = true
+	}
+	categories := make(map[string]int)
+	for _, c := range db.GetAllCategories() {
+		categories[strings.ToLower(c.Name)] = c.ID
+	}
+
+	next := db.GetNextQuestionNumber()
+	for _, row := range f.Rows {
+		if row.Data.Number >= next {
+			next = row.Data.Number + 1
+		}
+	}
+
+	seen := make(map[int]int)
+	for _, row := range f.Rows {
+		d := &row.Data
+		d.Text = strings.TrimSpace(d.Text)
+		d.CorrectAnswer = strings.ToUpper(strings.TrimSpace(d.CorrectAnswer))
+		if d.Text == "" {
+			row.fail(opts.Locale, "import.empty_text")
+		}
+		for i, v := range d.Variants {
+			if strings.TrimSpace(v) == "" {
+				row.fail(opts.Locale, "import.empty_variant", variantLetters[i])
+			}
+		}
+		d.Status = strings.ToLower(strings.TrimSpace(d.Status))
+		if d.Status != "" && !slices.Contains(questionStatuses, d.Status) {
+			row.fail(opts.Locale, "import.invalid_status", d.Status)
+		}
+		for _, name := range []string{d.Image, d.ExplanationImage} {
+			if name != "" && f.Images[strings.ToLower(path.Base(name))] == nil && !mediaFileExists(name) {
+				row.fail(opts.Locale, "import.image_missing", name)
+			}
+		}
+		if _, ok := categories[strings.ToLower(d.Category)]; d.Category != "" && !ok {
+			categories[strings.ToLower(d.Category)] = 0
+			report.NewCategories = append(report.NewCategories, d.Category)
+		}
+
+		switch {
+		case row.badNumber != "":
+			row.fail(opts.Locale, "import.invalid_number", row.badNumber)
+		case d.Number < 0:
+			row.fail(opts.Locale, "import.invalid_number", d.Number)
+		case d.Number == 0:
+			d.Number = next
+			next++
+		case seen[d.Number] != 0:
+			row.fail(opts.Locale, "import.duplicate_number", d.Number, seen[d.Number])
+		}
+		seen[d.Number] = row.Line
+		row.Action = "create"
+		if trashed[d.Number] {
+			row.fail(opts.Locale, "import.number_in_trash", d.Number)
+		} else if q := byNumber[d.Number]; q != nil {
+			if opts.Upsert {
+				row.Action = "update"
+				row.existing = q
+			} else {
+				row.fail(opts.Locale, "import.number_exists", d.Number)
+			}
+		}
+
+		checkAnswerKey(row, opts.Locale)
+		if len(row.Errors) == 0 && row.existing != nil && importUnchanged(row, categories[strings.ToLower(d.Category)], opts.rowStatus(*d)) {
+			row.Action = "unchanged"
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			report.Failed++
+		case row.Action == "unchanged":
+			report.Unchanged++
+		case row.Action == "update":
+			report.Updated++
+		default:
+			report.Created++
+		}
+	}
+	return report
+}
+
+// importUnchanged reports whether importing row would leave the question it
+// updates as it is. categoryID is that of the row's category, 0 for one the
+// import still has to create, and status the one it gives, empty to keep it.
+func importUnchanged(row *importRow, categoryID int, status string) bool {
+	d := row.Data
+	if (status != "" && status != row.existing.QuestionStatus()) || (d.Category != "" && categoryID == 0) || !keepsImage(d.Image, row.existing.Image) || !keepsImage(d.ExplanationImage, row.existing.ExplanationImage) {
+		return false
+	}
+	q := *row.existing
+	applyImportRow(&q, d, categoryID)
+	q.normalizeAnswerKey()
+	return len(revisionChanges(row.existing, &q, nil)) == 0 && q.CategoryID == row.existing.CategoryID
+}
+
+// keepsImage reports whether name, the image a row gives, leaves current,
+// the question's image, in place: it is empty, the same media path or, as
+// in a ZIP written by the export, the same file name.
+func keepsImage(name, current string) bool {
+	return name == "" || current != "" && path.Base(name) == path.Base(current)
+}
+
+// applyImportRow copies the content of d onto q, apart from the images,
+// which may have to be saved first. An update without a category,
+// explanation or rule reference keeps the current one, so a text-only
+// re-import does not wipe them.
+func applyImportRow(q *Question, d importQuestion, categoryID int) {
+	q.Text = d.Text
+	q.Type = d.Type
+	q.CorrectAnswer = d.CorrectAnswer
+	q.Hotspots, _ = parseHotspotList(d.Hotspots)
+	q.VariantsList = nil
+	for i, v := range d.Variants {
+		q.VariantsList = append(q.VariantsList, Variant{Letter: string(variantLetters[i]), Text: strings.TrimSpace(v)})
+	}
+	if d.Category != "" {
+		q.CategoryID = categoryID
+	}
+	if d.Explanation != "" {
+		q.Explanation = strings.TrimSpace(d.Explanation)
+	}
+	if d.RuleRef != "" {
+		q.RuleRef = strings.TrimSpace(d.RuleRef)
+	}
+}
+
+// checkAnswerKey checks a row's answer by its question type and brings it
+// into stored form. A hotspot question needs an image, its own or, on
+// update, that of the question it replaces.
+func checkAnswerKey(row *importRow, locale string) {
+	d := &row.Data
+	d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+	q := &Question{Type: d.Type, CorrectAnswer: d.CorrectAnswer, Image: d.Image}
+	if q.Image == "" && row.existing != nil {
+		q.Image = row.existing.Image
+	}
+	if q.IsHotspot() {
+		var err error
+		if q.Hotspots, err = parseHotspotList(d.Hotspots); err == nil {
+			err = q.normalizeAnswerKey()
+		}
+		if err != nil {
+			row.Errors = append(row.Errors, errorText(locale, err))
+		}
+		return
+	}
+	if len(d.Variants) < 2 || len(d.Variants) > len(variantLetters) {
+		row.fail(locale, "import.variant_count", len(variantLetters))
+		return
+	}
+	for i := range d.Variants {
+		q.VariantsList = append(q.VariantsList, Variant{Letter: string(variantLetters[i])})
+	}
+	err := q.normalizeAnswerKey()
+	switch {
+	case err == nil:
+		d.CorrectAnswer = q.CorrectAnswer
+	case !slices.Contains(questionTypes, q.QuestionType()):
+		row.Errors = append(row.Errors, errorText(locale, err))
+	default:
+		row.fail(locale, "import.correct_not_variant", d.CorrectAnswer)
+	}
+}
+
+// runImport checks f and, unless opts.DryRun is set or some row is invalid,
+// writes it to the bank. Nothing is written when any row has errors.
+func runImport(f *importFile, opts importOptions) (*importReport, error) {
+	report := checkImport(f, opts)
+	if opts.DryRun || report.HasErrors() {
+		return report, nil
+	}
+
+	categoryIDs := make(map[string]int)
+	for _, c := range db.GetAllCategories() {
+		categoryIDs[strings.ToLower(c.Name)] = c.ID
+	}
+	for _, name := range report.NewCategories {
+		c := &Category{Name: name}
+		if err := db.CreateCategory(c); err != nil {
+			return report, newMessageError("import.category_failed", name, err)
+		}
+		categoryIDs[strings.ToLower(name)] = c.ID
+	}
+
+	for _, row := range report.Rows {
+		if row.Action == "unchanged" {
+			continue
+		}
+		d := row.Data
+		q := row.existing
+		if q == nil {
+			q = &Question{Number: d.Number, Status: opts.Status, AuthorID: opts.UserID}
+			if status := opts.rowStatus(d); status != "" {
+				q.Status = status
+			}
+		}
+		applyImportRow(q, d, categoryIDs[strings.ToLower(d.Category)])
+		for _, field := range []struct {
+			name  string
+			image *string
+		}{
+			{d.Image, &q.Image},
+			{d.ExplanationImage, &q.ExplanationImage},
+		} {
+			if keepsImage(field.name, *field.image) {
+				continue
+			}
+			if content := f.Images[strings.ToLower(path.Base(field.name))]; content != nil {
+				imagePath, err := saveQuestionImage(bytes.NewReader(content))
+				if err != nil {
+					return report, newMessageError("import.image_save_failed", row.Line, errorText(opts.Locale, err))
+				}
+				*field.image = imagePath
+			} else {
+				*field.image = field.name
+			}
+		}
+		// Checked in checkAnswerKey; this drops what the type does not use,
+		// such as variants given for a hotspot question.
+		q.normalizeAnswerKey()
+
+		var err error
+		if row.existing != nil {
+			var review *QuestionReview
+			if status := opts.rowStatus(d); status != "" && status != q.QuestionStatus() {
+				q.Status = status
+				review = &QuestionReview{QuestionID: q.ID, UserID: opts.UserID, Action: statusAction(status)}
+			} else if !opts.publishes() && q.IsPublished() {
+				q.Status = questionInReview
+				review = &QuestionReview{QuestionID: q.ID, UserID: opts.UserID, Action: reviewSubmit}
+			}
+			err = db.UpdateQuestion(q, opts.UserID, review)
+		} else {
+			err = db.CreateQuestion(q, opts.UserID)
+		}
+		if err != nil {
+			return report, newMessageError("import.row_failed", row.Line, err)
+		}
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// readXLSXTable reads the cell values of the first worksheet of an XLSX
+// workbook. Only what a question bank needs is supported: shared and inline
+// strings and plain numbers.
+func readXLSXTable(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, newMessageError("import.xlsx_unreadable", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, zf := range zr.File {
+		files[zf.Name] = zf
+	}
+	readXML := func(name string, v interface{}) error {
+		zf, ok := files[name]
+		if !ok {
+			return os.ErrNotExist
+		}
+		content, err := readZipFile(zf)
+		if err != nil {
+			return err
+		}
+		return xml.Unmarshal(content, v)
+	}
+
+	var shared struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := readXML("xl/sharedStrings.xml", &shared); err != nil && err != os.ErrNotExist {
+		return nil, newMessageError("import.xlsx_unreadable", err)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Num   int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := readXML(firstSheetPath(readXML), &sheet); err != nil {
+		return nil, newMessageError("import.xlsx_sheet_unreadable", err)
+	}
+
+	var table [][]string
+	for _, row := range sheet.Rows {
+		line := row.Num
+		if line < 1 {
+			line = len(table) + 1
+		}
+		for len(table) < line {
+			table = append(table, nil)
+		}
+		var record []string
+		for i, c := range row.Cells {
+			col := xlsxColumn(c.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared.Items) {
+					record[col] = shared.Items[n].String()
+				}
+			case "inlineStr":
+				record[col] = c.Inline.String()
+			default:
+				record[col] = c.Value
+			}
+		}
+		table[line-1] = record
+	}
+	return table, nil
+}
+
+// xlsxText is a shared or inline string, either plain or split into
+// formatted runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.Runs {
+		s += r.T
+	}
+	return s
+}
+
+// firstSheetPath resolves the first sheet listed in the workbook to its part
+// name, falling back to the name Excel normally uses.
+func firstSheetPath(readXML func(string, interface{}) error) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+	var workbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if readXML("xl/workbook.xml", &workbook) != nil || len(workbook.Sheets) == 0 ||
+		readXML("xl/_rels/workbook.xml.rels", &rels) != nil {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID == workbook.Sheets[0].RID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return fallback
+}
+
+// xlsxColumn turns the letters of a cell reference such as "C12" into a
+// zero-based column index, or -1 if there are none.
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
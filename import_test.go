@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+)
+
+// useTestStore points the package at an empty in-memory bank and a
+// temporary media directory.
+func useTestStore(t *testing.T) {
+	t.Helper()
+	db = newMemoryStore()
+	mediaStore = &localMediaStore{dir: t.TempDir()}
+}
+
+func questionByNumber(t *testing.T, number int) *Question {
+	t.Helper()
+	for _, q := range db.GetAllQuestions() {
+		if q.Number == number {
+			return q
+		}
+	}
+	t.Fatalf("no question %d", number)
+	return nil
+}
+
+func importJSON(t *testing.T, data string, opts importOptions) *importReport {
+	t.Helper()
+	f, err := readImportFile("questions.json", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := runImport(f, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range report.Rows {
+		if len(row.Errors) > 0 {
+			t.Fatalf("line %d: %v", row.Line, row.Errors)
+		}
+	}
+	return report
+}
+
+func TestImportUpsertSkipsUnchangedRows(t *testing.T) {
+	useTestStore(t)
+	const bank = `[
+		{"number": 1, "text": "Stop sign?", "category": "Signs", "variants": ["Stop", "Go"], "correct_answer": "a", "explanation": "Red octagon."},
+		{"number": 2, "text": "Order these", "type": "ordering", "variants": ["First", "Second"], "correct_answer": ""}
+	]`
+	if r := importJSON(t, bank, importOptions{}); r.Created != 2 || !r.Applied {
+		t.Fatalf("first import: %+v", r)
+	}
+	before := len(db.GetQuestionRevisions(questionByNumber(t, 1).ID))
+
+	r := importJSON(t, bank, importOptions{Upsert: true})
+	if r.Unchanged != 2 || r.Updated != 0 || r.Created != 0 {
+		t.Fatalf("re-import: %d created, %d updated, %d unchanged", r.Created, r.Updated, r.Unchanged)
+	}
+	for _, row := range r.Rows {
+		if row.Action != "unchanged" {
+			t.Errorf("line %d: action %q", row.Line, row.Action)
+		}
+	}
+	if after := len(db.GetQuestionRevisions(questionByNumber(t, 1).ID)); after != before {
+		t.Errorf("revisions %d, want %d", after, before)
+	}
+
+	// A row that leaves out the explanation keeps it, so it is unchanged
+	// too; a changed variant is an update.
+	r = importJSON(t, `[
+		{"number": 1, "text": "Stop sign?", "variants": ["Stop", "Go"], "correct_answer": "A"},
+		{"number": 2, "text": "Order these", "type": "ordering", "variants": ["First", "Then"], "correct_answer": ""}
+	]`, importOptions{Upsert: true})
+	if r.Unchanged != 1 || r.Updated != 1 {
+		t.Fatalf("partial re-import: %d updated, %d unchanged", r.Updated, r.Unchanged)
+	}
+	if q := questionByNumber(t, 2); q.VariantsList[1].Text != "Then" {
+		t.Errorf("question 2 variants %+v", q.VariantsList)
+	}
+
+	// A category the bank does not have yet is a change.
+	r = importJSON(t, `[{"number": 1, "text": "Stop sign?", "category": "Rules", "variants": ["Stop", "Go"], "correct_answer": "A"}]`, importOptions{Upsert: true, DryRun: true})
+	if r.Updated != 1 {
+		t.Fatalf("new category: %d updated, %d unchanged", r.Updated, r.Unchanged)
+	}
+}
+
+func TestImportUpsertSendsPublishedQuestionsToReview(t *testing.T) {
+	useTestStore(t)
+	importJSON(t, `[{"number": 1, "text": "Stop sign?", "variants": ["Stop", "Go"], "correct_answer": "A"}]`, importOptions{})
+	q := questionByNumber(t, 1)
+	if !q.IsPublished() {
+		t.Fatalf("imported as %s", q.Status)
+	}
+
+	importJSON(t, `[{"number": 1, "text": "Which sign means stop?", "variants": ["Stop", "Go"], "correct_answer": "A"}]`,
+		importOptions{Upsert: true, UserID: 7, Status: questionDraft})
+	q = questionByNumber(t, 1)
+	if q.Text != "Which sign means stop?" || q.Status != questionInReview {
+		t.Fatalf("updated question %q is %s, want it in review", q.Text, q.Status)
+	}
+	if reviews := db.GetQuestionReviews(q.ID); len(reviews) != 1 || reviews[0].Action != reviewSubmit || reviews[0].UserID != 7 {
+		t.Errorf("review log %+v", reviews)
+	}
+}
+
+func TestImportRowStatus(t *testing.T) {
+	useTestStore(t)
+	importJSON(t, `[
+		{"number": 1, "text": "Stop sign?", "variants": ["Stop", "Go"], "correct_answer": "A", "status": "draft"},
+		{"number": 2, "text": "Yield sign?", "variants": ["Yield", "Go"], "correct_answer": "A", "status": "Retired"},
+		{"number": 3, "text": "Speed limit?", "variants": ["70", "90"], "correct_answer": "A"}
+	]`, importOptions{})
+	for number, want := range map[int]string{1: questionDraft, 2: questionRetired, 3: questionPublished} {
+		if q := questionByNumber(t, number); q.QuestionStatus() != want {
+			t.Errorf("question %d is %s, want %s", number, q.Status, want)
+		}
+	}
+
+	// Publishing a draft by upsert logs it in the review log.
+	r := importJSON(t, `[{"number": 1, "text": "Stop sign?", "variants": ["Stop", "Go"], "correct_answer": "A", "status": "published"}]`,
+		importOptions{Upsert: true})
+	if r.Updated != 1 {
+		t.Fatalf("status change: %d updated, %d unchanged", r.Updated, r.Unchanged)
+	}
+	q := questionByNumber(t, 1)
+	if reviews := db.GetQuestionReviews(q.ID); !q.IsPublished() || len(reviews) != 1 || reviews[0].Action != reviewApprove {
+		t.Errorf("question 1 is %s, review log %+v", q.Status, reviews)
+	}
+
+	// An author's import cannot publish.
+	importJSON(t, `[{"number": 4, "text": "Parking?", "variants": ["Yes", "No"], "correct_answer": "B", "status": "published"}]`,
+		importOptions{UserID: 7, Status: questionDraft})
+	if q := questionByNumber(t, 4); q.Status != questionDraft {
+		t.Errorf("author's import added a %s question", q.Status)
+	}
+
+	f, err := readImportFile("questions.json", []byte(`[{"text": "Bad", "variants": ["A", "B"], "correct_answer": "A", "status": "live"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := checkImport(f, importOptions{}); report.Failed != 1 {
+		t.Errorf("unknown status accepted: %+v", report.Rows[0])
+	}
+}
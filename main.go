@@ -1,8 +1,8 @@
 package main
 
 import (
+        "bytes"
         "encoding/json"
-        "fmt"
         "html/template"
         "io"
         "log"
@@ -10,7 +10,6 @@ import (
         "mime/multipart"
         "net/http"
         "os"
-        "path/filepath"
         "strings"
         "time"
 
@@ -20,7 +19,8 @@ import (
 
 var (
         store     *sessions.CookieStore
-        templates map[string]*template.Template
+        // templates holds the parsed pages per UI locale.
+        templates map[string]map[string]*template.Template
 )
 
 var funcMap = template.FuncMap{
@@ -34,6 +34,8 @@ var funcMap = template.FuncMap{
                 return set[id]
         },
         "lower": strings.ToLower,
+        // T is replaced per locale in loadTemplates.
+        "T": translator(defaultLocale),
         "formatDate": func(t time.Time, format string) string {
                 switch format {
                 case "d.m.Y H:i":
@@ -63,23 +65,23 @@ var funcMap = template.FuncMap{
                 b, _ := json.Marshal(v)
                 return template.JS(b)
         },
-        "imageURL": func(path string) string {
-                if path == "" {
-                        return ""
-                }
-                return "/media/" + path
-        },
+        "imageURL": imageURL,
+        "fileSize": formatFileSize,
         "hasImage": func(path string) bool {
                 return path != ""
         },
         "strContains": strings.Contains,
+        "markdown":    renderMarkdown,
+        "addDays": func(t time.Time, days int) time.Time {
+                return t.AddDate(0, 0, days)
+        },
         "roundFloat": func(f float64) int {
                 return int(math.Round(f))
         },
 }
 
 func loadTemplates() {
-        templates = make(map[string]*template.Template)
+        templates = make(map[string]map[string]*template.Template)
         base := "templates/base.html"
         pages := []string{
                 "dashboard.html",
@@ -96,16 +98,50 @@ func loadTemplates() {
                 "admin/questions.html",
                 "admin/add_question.html",
                 "admin/edit_question.html",
+                "admin/import_questions.html",
+                "admin/export_questions.html",
+                "admin/question_history.html",
+                "admin/question_changes.html",
+                "admin/categories.html",
+                "admin/edit_category.html",
+                "admin/tickets.html",
+                "admin/add_ticket.html",
+                "admin/edit_ticket.html",
+                "tickets.html",
+                "study.html",
                 "admin/users.html",
                 "admin/add_user.html",
                 "admin/edit_user.html",
+                "admin/trash.html",
+                "admin/media.html",
+                "admin/duplicates.html",
+                "admin/renumber.html",
+                "handbook.html",
+                "handbook_article.html",
+                "handbook_search.html",
+                "road_signs.html",
+                "admin/translate_question.html",
+                "admin/translations.html",
+                "admin/handbook.html",
+                "admin/edit_chapter.html",
+                "admin/add_article.html",
+                "admin/edit_article.html",
+                "admin/road_signs.html",
+                "admin/edit_road_sign.html",
                 "admin/statistics.html",
+                "admin/settings.html",
         }
-        for _, page := range pages {
-                t := template.Must(template.New("").Funcs(funcMap).ParseFiles(base, "templates/"+page))
-                templates[page] = t
+        // Every locale gets its own parse of the pages, with T bound to it.
+        for _, l := range uiLocales {
+                T := template.FuncMap{"T": translator(l.Code)}
+                set := make(map[string]*template.Template)
+                for _, page := range pages {
+                        set[page] = template.Must(template.New("").Funcs(funcMap).Funcs(T).ParseFiles(base, "templates/"+page))
+                }
+                set["login.html"] = template.Must(template.New("login.html").Funcs(funcMap).Funcs(T).ParseFiles("templates/login.html"))
+                templates[l.Code] = set
         }
-        templates["login.html"] = template.Must(template.New("login.html").Funcs(funcMap).ParseFiles("templates/login.html"))
+        checkCatalogs()
 }
 
 func renderTemplate(w http.ResponseWriter, r *http.Request, tmplName string, data map[string]interface{}) {
@@ -122,8 +158,11 @@ func renderTemplate(w http.ResponseWriter, r *http.Request, tmplName string, dat
                 data["IsStaff"] = false
         }
         data["CSRFToken"] = getCSRFToken(w, r)
+        locale := requestLocale(r)
+        data["Locale"] = locale
+        data["Locales"] = uiLocales
 
-        tmpl, ok := templates[tmplName]
+        tmpl, ok := templates[locale][tmplName]
         if !ok {
                 log.Printf("Template not found: %s", tmplName)
                 http.Error(w, "Template not found", 500)
@@ -165,20 +204,30 @@ func verifyCSRFToken(r *http.Request, w http.ResponseWriter) bool {
 }
 
 func saveUploadedFile(file multipart.File, header *multipart.FileHeader) (string, error) {
-        os.MkdirAll("media/questions", 0755)
-        ext := filepath.Ext(header.Filename)
-        filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-        path := filepath.Join("media", "questions", filename)
-        dst, err := os.Create(path)
-        if err != nil {
-                return "", err
+        return saveQuestionImage(file)
+}
+
+// saveQuestionImage stores an image under media/questions and returns the
+// path for Question.Image.
+func saveQuestionImage(src io.Reader) (string, error) {
+        return saveImage(src, "questions")
+}
+
+// renderExplanation renders the shared explanation block for q in locale,
+// for pages that insert it after answering without a reload.
+func renderExplanation(q *Question, locale string) string {
+        var buf bytes.Buffer
+        if err := templates[locale]["take_test.html"].ExecuteTemplate(&buf, "question_explanation", q); err != nil {
+                log.Printf("Template error (question_explanation): %v", err)
         }
-        defer dst.Close()
-        _, err = io.Copy(dst, file)
-        return "questions/" + filename, err
+        return buf.String()
 }
 
 func main() {
+        if len(os.Args) > 1 {
+                os.Exit(runCommand(os.Args[1:]))
+        }
+
         secret := os.Getenv("SESSION_SECRET")
         if secret == "" {
                 secret = "default-secret-key-change-in-production"
@@ -193,17 +242,33 @@ func main() {
 
         initDB()
         defer db.Close()
+        openMedia()
+        startSweeper(sweepInterval)
 
         loadTemplates()
 
+        r := newRouter()
+
+        port := os.Getenv("PORT")
+        if port == "" {
+                port = "5000"
+        }
+
+        log.Printf("AvtotestPrime starting on :%s", port)
+        log.Fatal(http.ListenAndServe("0.0.0.0:"+port, recoveryMiddleware(r)))
+}
+
+// newRouter registers every page of the site.
+func newRouter() *mux.Router {
         r := mux.NewRouter()
 
         r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
-        r.PathPrefix("/media/").Handler(http.StripPrefix("/media/", http.FileServer(http.Dir("media"))))
+        r.PathPrefix("/media/").Handler(mediaHandler())
 
         r.HandleFunc("/", indexHandler)
         r.HandleFunc("/login/", loginHandler)
         r.HandleFunc("/logout/", logoutHandler)
+        r.HandleFunc("/lang/{code}/", setLocaleHandler)
 
         r.HandleFunc("/dashboard/", authRequired(dashboardHandler))
         r.HandleFunc("/questions/", authRequired(allQuestionsHandler))
@@ -214,26 +279,65 @@ func main() {
         r.HandleFunc("/test/start/", authRequired(startTestHandler))
         r.HandleFunc("/test/{id}/", authRequired(takeTestHandler))
         r.HandleFunc("/test/{id}/submit/", authRequired(submitTestHandler))
+        r.HandleFunc("/test/{id}/answer/", authRequired(answerTestHandler))
+        r.HandleFunc("/test/{id}/abandon/", authRequired(abandonTestHandler))
         r.HandleFunc("/test/{id}/result/", authRequired(testResultHandler))
+        r.HandleFunc("/tickets/", authRequired(ticketsHandler))
+        r.HandleFunc("/tickets/{id}/start/", authRequired(startTicketHandler))
+        r.HandleFunc("/study/", authRequired(studyHandler))
+        r.HandleFunc("/study/start/", authRequired(startStudyHandler))
         r.HandleFunc("/statistics/", authRequired(statisticsHandler))
         r.HandleFunc("/profile/", authRequired(profileHandler))
+        r.HandleFunc("/handbook/", authRequired(handbookHandler))
+        r.HandleFunc("/handbook/search/", authRequired(handbookSearchHandler))
+        r.HandleFunc("/handbook/signs/", authRequired(roadSignsHandler))
+        r.HandleFunc("/handbook/articles/{id}/", authRequired(handbookArticleHandler))
 
         r.HandleFunc("/admin-panel/", adminRequired(adminDashboardHandler))
         r.HandleFunc("/admin-panel/questions/", adminRequired(adminQuestionsHandler))
-        r.HandleFunc("/admin-panel/questions/add/", adminRequired(adminAddQuestionHandler))
+        r.HandleFunc("/admin-panel/questions/add/", roleRequired((*User).CanWrite, adminAddQuestionHandler))
+        r.HandleFunc("/admin-panel/questions/import/", roleRequired((*User).CanWrite, adminImportQuestionsHandler))
+        r.HandleFunc("/admin-panel/questions/export/", adminRequired(adminExportQuestionsHandler))
+        r.HandleFunc("/admin-panel/questions/history/", adminRequired(adminQuestionChangesHandler))
+        r.HandleFunc("/admin-panel/questions/translations/", roleRequired((*User).CanReview, adminTranslationsHandler))
+        r.HandleFunc("/admin-panel/questions/duplicates/", roleRequired((*User).CanReview, adminDuplicatesHandler))
+        r.HandleFunc("/admin-panel/questions/duplicates/merge/", roleRequired((*User).CanReview, adminMergeDuplicatesHandler))
+        r.HandleFunc("/admin-panel/questions/renumber/", roleRequired((*User).CanReview, adminRenumberHandler))
+        r.HandleFunc("/admin-panel/questions/renumber/save/", roleRequired((*User).CanReview, adminRenumberSaveHandler))
+        r.HandleFunc("/admin-panel/questions/{id}/history/", adminRequired(adminQuestionHistoryHandler))
+        r.HandleFunc("/admin-panel/questions/{id}/history/{revision}/restore/", roleRequired((*User).CanWrite, adminRestoreRevisionHandler))
         r.HandleFunc("/admin-panel/questions/{id}/edit/", adminRequired(adminEditQuestionHandler))
-        r.HandleFunc("/admin-panel/questions/{id}/delete/", adminRequired(adminDeleteQuestionHandler))
-        r.HandleFunc("/admin-panel/users/", adminRequired(adminUsersHandler))
-        r.HandleFunc("/admin-panel/users/add/", adminRequired(adminAddUserHandler))
-        r.HandleFunc("/admin-panel/users/{id}/edit/", adminRequired(adminEditUserHandler))
-        r.HandleFunc("/admin-panel/users/{id}/delete/", adminRequired(adminDeleteUserHandler))
+        r.HandleFunc("/admin-panel/questions/{id}/review/", adminRequired(adminReviewQuestionHandler))
+        r.HandleFunc("/admin-panel/questions/{id}/translations/{lang}/", roleRequired((*User).CanReview, adminTranslateQuestionHandler))
+        r.HandleFunc("/admin-panel/questions/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteQuestionHandler))
+        r.HandleFunc("/admin-panel/categories/", postRoleRequired((*User).CanWrite, adminCategoriesHandler))
+        r.HandleFunc("/admin-panel/categories/{id}/edit/", roleRequired((*User).CanWrite, adminEditCategoryHandler))
+        r.HandleFunc("/admin-panel/categories/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteCategoryHandler))
+        r.HandleFunc("/admin-panel/tickets/", adminRequired(adminTicketsHandler))
+        r.HandleFunc("/admin-panel/tickets/generate/", roleRequired((*User).CanWrite, adminGenerateTicketsHandler))
+        r.HandleFunc("/admin-panel/tickets/add/", roleRequired((*User).CanWrite, adminAddTicketHandler))
+        r.HandleFunc("/admin-panel/tickets/{id}/edit/", roleRequired((*User).CanWrite, adminEditTicketHandler))
+        r.HandleFunc("/admin-panel/tickets/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteTicketHandler))
+        r.HandleFunc("/admin-panel/users/", roleRequired((*User).IsAdmin, adminUsersHandler))
+        r.HandleFunc("/admin-panel/users/add/", roleRequired((*User).IsAdmin, adminAddUserHandler))
+        r.HandleFunc("/admin-panel/users/{id}/edit/", roleRequired((*User).IsAdmin, adminEditUserHandler))
+        r.HandleFunc("/admin-panel/users/{id}/delete/", roleRequired((*User).IsAdmin, adminDeleteUserHandler))
+        r.HandleFunc("/admin-panel/handbook/", postRoleRequired((*User).CanWrite, adminHandbookHandler))
+        r.HandleFunc("/admin-panel/handbook/chapters/{id}/edit/", roleRequired((*User).CanWrite, adminEditChapterHandler))
+        r.HandleFunc("/admin-panel/handbook/chapters/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteChapterHandler))
+        r.HandleFunc("/admin-panel/handbook/articles/add/", roleRequired((*User).CanWrite, adminAddArticleHandler))
+        r.HandleFunc("/admin-panel/handbook/articles/{id}/edit/", roleRequired((*User).CanWrite, adminEditArticleHandler))
+        r.HandleFunc("/admin-panel/handbook/articles/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteArticleHandler))
+        r.HandleFunc("/admin-panel/handbook/signs/", postRoleRequired((*User).CanWrite, adminRoadSignsHandler))
+        r.HandleFunc("/admin-panel/handbook/signs/{id}/edit/", roleRequired((*User).CanWrite, adminEditRoadSignHandler))
+        r.HandleFunc("/admin-panel/handbook/signs/{id}/delete/", roleRequired((*User).CanWrite, adminDeleteRoadSignHandler))
+        r.HandleFunc("/admin-panel/trash/", roleRequired((*User).IsAdmin, adminTrashHandler))
+        r.HandleFunc("/admin-panel/trash/purge/", roleRequired((*User).IsAdmin, adminPurgeTrashHandler))
+        r.HandleFunc("/admin-panel/trash/{kind}/{id}/{action}/", roleRequired((*User).IsAdmin, adminTrashActionHandler))
+        r.HandleFunc("/admin-panel/media/", roleRequired((*User).IsAdmin, adminMediaHandler))
+        r.HandleFunc("/admin-panel/media/collect/", roleRequired((*User).IsAdmin, adminCollectMediaHandler))
         r.HandleFunc("/admin-panel/statistics/", adminRequired(adminStatisticsHandler))
+        r.HandleFunc("/admin-panel/settings/", roleRequired((*User).IsAdmin, adminSettingsHandler))
 
-        port := os.Getenv("PORT")
-        if port == "" {
-                port = "5000"
-        }
-
-        log.Printf("AvtotestPrime starting on :%s", port)
-        log.Fatal(http.ListenAndServe("0.0.0.0:"+port, recoveryMiddleware(r)))
+        return r
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// renderMarkdown turns the small Markdown subset used for explanations into
+// HTML: paragraphs, headings, bullet and numbered lists, quotes, **bold**,
+// *italic*, `code` and [links](https://...). Everything else is escaped, so
+// the result is safe to put into a page.
+func renderMarkdown(src string) template.HTML {
+	var out strings.Builder
+	var para []string
+	list := ""
+	closeBlocks := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + strings.Join(para, "<br>") + "</p>\n")
+			para = nil
+		}
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeBlocks()
+			out.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			closeBlocks()
+		case mdHeading.MatchString(trimmed):
+			closeBlocks()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			tag := []string{"h4", "h5", "h6"}[len(m[1])-1]
+			out.WriteString("<" + tag + ">" + markdownInline(m[2]) + "</" + tag + ">\n")
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			openList("ul")
+			out.WriteString("<li>" + markdownInline(trimmed[2:]) + "</li>\n")
+		case mdNumbered.MatchString(trimmed):
+			openList("ol")
+			out.WriteString("<li>" + markdownInline(mdNumbered.ReplaceAllString(trimmed, "")) + "</li>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			closeBlocks()
+			out.WriteString("<blockquote>" + markdownInline(strings.TrimSpace(trimmed[1:])) + "</blockquote>\n")
+		default:
+			if list != "" {
+				closeBlocks()
+			}
+			para = append(para, markdownInline(trimmed))
+		}
+	}
+	closeBlocks()
+	return template.HTML(out.String())
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,3})\s+(.*)$`)
+	mdNumbered = regexp.MustCompile(`^\d+[.)]\s+`)
+	mdBold     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdItalic   = regexp.MustCompile(`\*([^*\s][^*]*?)\*`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// markdownInline escapes one line and applies the inline markup. Code spans
+// are left exactly as written.
+func markdownInline(s string) string {
+	parts := strings.Split(s, "`")
+	var out strings.Builder
+	for i, part := range parts {
+		part = html.EscapeString(part)
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			out.WriteString("<code>" + part + "</code>")
+		case i%2 == 1:
+			// An unmatched backtick is just a character.
+			out.WriteString("`" + markdownEmphasis(part))
+		default:
+			out.WriteString(markdownEmphasis(part))
+		}
+	}
+	return out.String()
+}
+
+func markdownEmphasis(s string) string {
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		url := sub[2]
+		local := strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//")
+		if !local && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return m
+		}
+		return `<a href="` + url + `" target="_blank" rel="noopener">` + sub[1] + `</a>`
+	})
+	s = mdBold.ReplaceAllString(s, "<strong>$1</strong>")
+	return mdItalic.ReplaceAllString(s, "<em>$1</em>")
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"", ""},
+		{"Slow down.\nThen stop.\n\nNext", "<p>Slow down.<br>Then stop.</p>\n<p>Next</p>\n"},
+		{"## Rule 8", "<h5>Rule 8</h5>\n"},
+		{"- one\n* two\n1. first\n2) second", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"> quoted", "<blockquote>quoted</blockquote>\n"},
+		{"**bold** and *it*", "<p><strong>bold</strong> and <em>it</em></p>\n"},
+		{"`**not bold**` and `open", "<p><code>**not bold**</code> and `open</p>\n"},
+		{"[rules](/handbook/1/) [site](https://example.uz)", `<p><a href="/handbook/1/" target="_blank" rel="noopener">rules</a> <a href="https://example.uz" target="_blank" rel="noopener">site</a></p>` + "\n"},
+		{"[x](javascript:alert(1)) [y](//evil.test)", "<p>[x](javascript:alert(1)) [y](//evil.test)</p>\n"},
+		{`<script>alert("x")</script>`, "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>\n"},
+		{`[a](https://x.test/"onmouseover=alert(1))`, `<p><a href="https://x.test/&#34;onmouseover=alert(1" target="_blank" rel="noopener">a</a>)</p>` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := string(renderMarkdown(tt.src)); got != tt.want {
+			t.Errorf("renderMarkdown(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
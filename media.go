@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+// MediaReference is one use of a file under media: the image of a question,
+// of one of its revisions or of a road sign. Label is what an admin knows the
+// owner by, a question number or a sign code.
+type MediaReference struct {
+	Path    string
+	Kind    string
+	OwnerID int
+	Label   string
+}
+
+// orphanGracePeriod keeps files written in the last hour out of the
+// garbage collector: an upload is stored before the question or sign that
+// uses it is saved.
+const orphanGracePeriod = time.Hour
+
+// mediaFile is a file found under media.
+type mediaFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// missingMedia is a referenced file that is not under media.
+type missingMedia struct {
+	Path string
+	Refs []*MediaReference
+}
+
+// mediaReport compares the files under media with the paths the database
+// refers to.
+type mediaReport struct {
+	Files   int
+	Bytes   int64
+	Orphans []mediaFile
+	// Recent counts unreferenced files still inside the grace period.
+	Recent  int
+	Missing []missingMedia
+}
+
+// OrphanBytes is the space the garbage collector would free.
+func (r *mediaReport) OrphanBytes() int64 {
+	var n int64
+	for _, f := range r.Orphans {
+		n += f.Size
+	}
+	return n
+}
+
+// scanMedia walks the media store and sorts its files into referenced and orphaned
+// ones. A referenced image keeps its thumbnail and WebP copies. Revisions
+// count as references, so an image replaced in the editor stays until its
+// question is purged, and restoring an old revision still finds it.
+func scanMedia() (*mediaReport, error) {
+	refs := db.GetMediaReferences()
+	used := make(map[string]bool)
+	byPath := make(map[string][]*MediaReference)
+	for _, ref := range refs {
+		p := path.Clean(ref.Path)
+		byPath[p] = append(byPath[p], ref)
+		for _, v := range []string{p, imageVariant(p, thumbSuffix)} {
+			used[v] = true
+			used[webpVariant(v)] = true
+		}
+	}
+
+	report := &mediaReport{}
+	found := make(map[string]bool)
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	err := mediaStore.Walk(func(f mediaFile) error {
+		found[f.Path] = true
+		report.Files++
+		report.Bytes += f.Size
+		switch {
+		case used[f.Path]:
+		case f.ModTime.After(cutoff):
+			report.Recent++
+		default:
+			report.Orphans = append(report.Orphans, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for p, refs := range byPath {
+		if !found[p] {
+			report.Missing = append(report.Missing, missingMedia{Path: p, Refs: refs})
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Path < report.Orphans[j].Path })
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].Path < report.Missing[j].Path })
+	return report, nil
+}
+
+// collectMediaGarbage deletes the orphaned files of a fresh scan and
+// returns how many files and bytes it removed.
+func collectMediaGarbage() (int, int64, error) {
+	report, err := scanMedia()
+	if err != nil {
+		return 0, 0, err
+	}
+	removed := 0
+	var freed int64
+	for _, f := range report.Orphans {
+		if err := mediaStore.Delete(f.Path); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += f.Size
+	}
+	return removed, freed, nil
+}
+
+// formatFileSize prints a byte count the way file managers do.
+func formatFileSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
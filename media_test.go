@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMediaGarbageKeepsRevisionImages(t *testing.T) {
+	srv := newTestSite(t)
+	dir := mediaStore.(*localMediaStore).dir
+	saveTestImage := func(c color.Color) string {
+		name, err := saveQuestionImage(bytes.NewReader(testImage(t, c)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	old := saveTestImage(color.RGBA{200, 0, 0, 255})
+	current := saveTestImage(color.RGBA{0, 200, 0, 255})
+	trashed := saveTestImage(color.RGBA{0, 0, 200, 255})
+	orphan := saveTestImage(color.RGBA{200, 200, 0, 255})
+	newQuestion := func(number int, image string) *Question {
+		q := &Question{Number: number, Text: "Sign?", Image: image, CorrectAnswer: "A",
+			VariantsList: []Variant{{Letter: "A", Text: "Yes"}, {Letter: "B", Text: "No"}}}
+		if err := db.CreateQuestion(q, 0); err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+
+	// The image is replaced, so only revision 1 still names the old one.
+	q := newQuestion(1, old)
+	q.Image = current
+	if err := db.UpdateQuestion(q, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	gone := newQuestion(2, trashed)
+	if err := db.DeleteQuestion(gone.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Age every file past the grace period.
+	past := time.Now().Add(-2 * orphanGracePeriod)
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			os.Chtimes(p, past, past)
+		}
+		return nil
+	})
+
+	removed, _, err := collectMediaGarbage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The unused image, its thumbnail and their WebP copies.
+	if removed != 4 {
+		t.Errorf("removed %d files, want 4", removed)
+	}
+	if mediaFileExists(orphan) {
+		t.Errorf("unused image %s kept", orphan)
+	}
+	for _, p := range []string{old, current, trashed, imageVariant(old, thumbSuffix), webpVariant(trashed)} {
+		if !mediaFileExists(p) {
+			t.Errorf("%s collected", p)
+		}
+	}
+
+	admin := newTestClient(t, srv)
+	admin.login("admin", "admin")
+	historyURL := "/admin-panel/questions/" + strconv.Itoa(q.ID) + "/history/"
+	resp, _ := admin.post(historyURL, historyURL+"1/restore/", url.Values{})
+	expectRedirect(t, resp, historyURL)
+	if resp.Header.Get("Location") != historyURL {
+		t.Fatalf("restore redirected to %s", resp.Header.Get("Location"))
+	}
+	if q = db.GetQuestionByID(q.ID); q.Image != old || !mediaFileExists(q.Image) {
+		t.Errorf("restored question shows %s", q.Image)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MediaStore holds uploaded files by their path relative to the media
+// root, such as "questions/ab12.jpg". Files are written once and never
+// changed, only touched to keep them from the garbage collector.
+type MediaStore interface {
+	Put(name string, data []byte) error
+	Open(name string) (io.ReadSeekCloser, mediaFile, error)
+	Stat(name string) (mediaFile, error)
+	Touch(name string) error
+	Delete(name string) error
+	Walk(fn func(f mediaFile) error) error
+	// URL is where browsers load the file from.
+	URL(name string) string
+}
+
+var mediaStore MediaStore = &localMediaStore{dir: "media"}
+
+// openMediaStore picks a backend from a MEDIA_STORAGE value:
+//
+//	media                                          local directory (the default)
+//	file://path/to/dir                             local directory
+//	s3://KEY:SECRET@host:9000/bucket/prefix?...    S3-compatible bucket such as MinIO
+//
+// S3 options: secure=false for plain HTTP, region=..., and urls=signed to
+// hand browsers presigned bucket URLs instead of proxying through /media/.
+func openMediaStore(dsn string) (MediaStore, error) {
+	switch {
+	case dsn == "":
+		return &localMediaStore{dir: "media"}, nil
+	case strings.HasPrefix(dsn, "s3://"):
+		return newS3MediaStore(dsn)
+	case strings.HasPrefix(dsn, "file://"):
+		return &localMediaStore{dir: strings.TrimPrefix(dsn, "file://")}, nil
+	case !strings.Contains(dsn, "://"):
+		return &localMediaStore{dir: dsn}, nil
+	}
+	return nil, fmt.Errorf("unsupported MEDIA_STORAGE scheme: %q", dsn)
+}
+
+// openMedia sets the package-level media store from MEDIA_STORAGE.
+func openMedia() {
+	s, err := openMediaStore(os.Getenv("MEDIA_STORAGE"))
+	if err != nil {
+		log.Fatalf("Failed to open media storage: %v", err)
+	}
+	mediaStore = s
+}
+
+// cleanMediaName checks that p is a plain relative path inside the media
+// root, as stored in Question.Image.
+func cleanMediaName(p string) bool {
+	clean := path.Clean("/" + p)[1:]
+	return clean != "" && clean == p
+}
+
+// mediaFileExists reports whether an image path as stored in
+// Question.Image points at an existing media file.
+func mediaFileExists(p string) bool {
+	if !cleanMediaName(p) {
+		return false
+	}
+	_, err := mediaStore.Stat(p)
+	return err == nil
+}
+
+// localMediaStore keeps files in a directory served under /media/.
+type localMediaStore struct {
+	dir string
+}
+
+func (s *localMediaStore) file(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s *localMediaStore) Put(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.file(name)), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.file(name), data, 0644)
+}
+
+func (s *localMediaStore) Open(name string) (io.ReadSeekCloser, mediaFile, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, info, err
+	}
+	f, err := os.Open(s.file(name))
+	return f, info, err
+}
+
+func (s *localMediaStore) Stat(name string) (mediaFile, error) {
+	info, err := os.Stat(s.file(name))
+	if err != nil {
+		return mediaFile{}, err
+	}
+	if info.IsDir() {
+		return mediaFile{}, fs.ErrNotExist
+	}
+	return mediaFile{Path: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *localMediaStore) Touch(name string) error {
+	now := time.Now()
+	return os.Chtimes(s.file(name), now, now)
+}
+
+func (s *localMediaStore) Delete(name string) error {
+	err := os.Remove(s.file(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *localMediaStore) Walk(fn func(f mediaFile) error) error {
+	err := filepath.WalkDir(s.dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.dir, name)
+		return fn(mediaFile{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *localMediaStore) URL(name string) string {
+	return "/media/" + name
+}
+
+// mediaStatTTL is how long an s3MediaStore trusts what it has seen of a
+// file. Other instances may delete files, so this bounds how long a page
+// can link to one that is gone.
+const mediaStatTTL = time.Minute
+
+// signedURLExpiry is how long a presigned bucket URL stays valid; pages
+// are rendered with fresh URLs, so it only needs to outlast a test.
+const signedURLExpiry = 12 * time.Hour
+
+// s3MediaStore keeps files in an S3-compatible bucket, so that several
+// app instances share them.
+type s3MediaStore struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	signed bool
+	// stats caches what Stat found, a file or its absence, for
+	// mediaStatTTL, so that imageURL and the WebP check of /media/ do not
+	// ask the bucket on every request.
+	stats sync.Map
+}
+
+// s3Stat is a cached Stat result; a missing file has an empty Path.
+type s3Stat struct {
+	file mediaFile
+	at   time.Time
+}
+
+func (s *s3MediaStore) remember(name string, f mediaFile) {
+	s.stats.Store(name, s3Stat{file: f, at: time.Now()})
+}
+
+func newS3MediaStore(dsn string) (*s3MediaStore, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	bucket, prefix, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("MEDIA_STORAGE: no bucket in %q", u.Redacted())
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	q := u.Query()
+	secret, _ := u.User.Password()
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(u.User.Username(), secret, ""),
+		Secure: q.Get("secure") != "false",
+		Region: q.Get("region"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: q.Get("region")}); err != nil {
+			return nil, err
+		}
+	}
+	return &s3MediaStore{client: client, bucket: bucket, prefix: prefix, signed: q.Get("urls") == "signed"}, nil
+}
+
+func (s *s3MediaStore) Put(name string, data []byte) error {
+	_, err := s.client.PutObject(context.Background(), s.bucket, s.prefix+name, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: mime.TypeByExtension(path.Ext(name))})
+	s.stats.Delete(name)
+	return err
+}
+
+func (s *s3MediaStore) Open(name string) (io.ReadSeekCloser, mediaFile, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mediaFile{}, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, mediaFile{}, s.notExist(err)
+	}
+	return obj, mediaFile{Path: name, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *s3MediaStore) Stat(name string) (mediaFile, error) {
+	if v, ok := s.stats.Load(name); ok && time.Since(v.(s3Stat).at) < mediaStatTTL {
+		if f := v.(s3Stat).file; f.Path != "" {
+			return f, nil
+		}
+		return mediaFile{}, fs.ErrNotExist
+	}
+	info, err := s.client.StatObject(context.Background(), s.bucket, s.prefix+name, minio.StatObjectOptions{})
+	if err != nil {
+		err = s.notExist(err)
+		if err == fs.ErrNotExist {
+			s.remember(name, mediaFile{})
+		}
+		return mediaFile{}, err
+	}
+	f := mediaFile{Path: name, Size: info.Size, ModTime: info.LastModified}
+	s.remember(name, f)
+	return f, nil
+}
+
+// notExist turns the bucket's "no such key" into fs.ErrNotExist.
+func (s *s3MediaStore) notExist(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fs.ErrNotExist
+	}
+	return err
+}
+
+// Touch copies the object onto itself, which is how S3 updates the
+// modification time. It fails if the object is gone.
+func (s *s3MediaStore) Touch(name string) error {
+	s.stats.Delete(name)
+	_, err := s.client.CopyObject(context.Background(),
+		minio.CopyDestOptions{
+			Bucket:          s.bucket,
+			Object:          s.prefix + name,
+			ReplaceMetadata: true,
+			UserMetadata:    map[string]string{"Touched": time.Now().UTC().Format(time.RFC3339)},
+			ContentType:     mime.TypeByExtension(path.Ext(name)),
+		},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: s.prefix + name})
+	return err
+}
+
+func (s *s3MediaStore) Delete(name string) error {
+	s.remember(name, mediaFile{})
+	return s.client.RemoveObject(context.Background(), s.bucket, s.prefix+name, minio.RemoveObjectOptions{})
+}
+
+func (s *s3MediaStore) Walk(fn func(f mediaFile) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(mediaFile{Path: strings.TrimPrefix(obj.Key, s.prefix), Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL proxies the file through /media/ unless presigned URLs were asked
+// for; those skip the app server but cannot offer WebP by content
+// negotiation.
+func (s *s3MediaStore) URL(name string) string {
+	if s.signed {
+		u, err := s.client.PresignedGetObject(context.Background(), s.bucket, s.prefix+name, signedURLExpiry, nil)
+		if err == nil {
+			return u.String()
+		}
+		log.Printf("Error signing media URL for %s: %v", name, err)
+	}
+	return "/media/" + name
+}
+
+// copyMedia copies every file of src missing from dst, or differing from it
+// in size, and returns how many files it copied.
+func copyMedia(src, dst MediaStore, logf func(format string, args ...interface{})) (int, error) {
+	copied := 0
+	err := src.Walk(func(f mediaFile) error {
+		if have, err := dst.Stat(f.Path); err == nil && have.Size == f.Size {
+			return nil
+		}
+		r, _, err := src.Open(f.Path)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		if err := dst.Put(f.Path, data); err != nil {
+			return err
+		}
+		logf("copied %s (%s)", f.Path, formatFileSize(f.Size))
+		copied++
+		return nil
+	})
+	return copied, err
+}
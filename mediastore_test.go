@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is just enough of the S3 API, path-style, for s3MediaStore: one
+// bucket with objects kept in memory.
+type fakeS3 struct {
+	bucket string
+	mu     sync.Mutex
+	made   bool
+	objs   map[string]fakeObject
+}
+
+type fakeObject struct {
+	data     []byte
+	modified time.Time
+}
+
+func (o fakeObject) etag() string {
+	sum := md5.Sum(o.data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	switch {
+	case key == "" && r.Method == "HEAD":
+		if !f.made {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case key == "" && r.Method == "PUT":
+		f.made = true
+	case key == "" && r.Method == "GET" && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		src, _ := strings.CutPrefix(strings.TrimPrefix(source, "/"), f.bucket+"/")
+		obj, ok := f.objs[src]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		obj.modified = time.Now()
+		f.objs[key] = obj
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: obj.etag(), LastModified: obj.modified.UTC().Format(time.RFC3339)})
+	case r.Method == "PUT":
+		data, err := readPayload(r)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		obj := fakeObject{data: data, modified: time.Now()}
+		f.objs[key] = obj
+		w.Header().Set("ETag", obj.etag())
+	case r.Method == "GET" || r.Method == "HEAD":
+		obj, ok := f.objs[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", obj.etag())
+		http.ServeContent(w, r, key, obj.modified, bytes.NewReader(obj.data))
+	case r.Method == "DELETE":
+		delete(f.objs, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: prefix, MaxKeys: 1000}
+	for key, obj := range f.objs {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{key, obj.modified.UTC().Format(time.RFC3339), obj.etag(), len(obj.data)})
+		}
+	}
+	slices.SortFunc(result.Contents, func(a, b content) int { return strings.Compare(a.Key, b.Key) })
+	result.KeyCount = len(result.Contents)
+	writeXML(w, result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(v)
+}
+
+// readPayload reads an upload body, undoing the signed chunk encoding the
+// client uses over plain HTTP.
+func readPayload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func TestS3MediaStore(t *testing.T) {
+	fake := &fakeS3{bucket: "media", objs: make(map[string]fakeObject)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	if _, err := openMediaStore("s3://key:secret@" + host + "/?secure=false"); err == nil {
+		t.Error("opened a store without a bucket")
+	}
+	store, err := openMediaStore("s3://key:secret@" + host + "/media/app?secure=false&region=us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.made {
+		t.Fatal("the missing bucket was not created")
+	}
+
+	photo := []byte("not really a jpeg")
+	if err := store.Put("questions/a.jpg", photo); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("signs/b.png", []byte("sign")); err != nil {
+		t.Fatal(err)
+	}
+	fake.objs["elsewhere/c.jpg"] = fakeObject{data: []byte("not ours"), modified: time.Now()}
+	if _, ok := fake.objs["app/questions/a.jpg"]; !ok {
+		t.Fatalf("objects %v, want them under the prefix", fake.objs)
+	}
+
+	rc, f, err := store.Open("questions/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(data, photo) || f.Path != "questions/a.jpg" || f.Size != int64(len(photo)) {
+		t.Errorf("opened %q as %+v", data, f)
+	}
+	if _, _, err := store.Open("questions/missing.jpg"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("opening a missing file: %v", err)
+	}
+	if f, err := store.Stat("signs/b.png"); err != nil || f.Size != 4 {
+		t.Errorf("stat %+v, %v", f, err)
+	}
+	yesterday := time.Now().Add(-24 * time.Hour)
+	fake.objs["app/signs/b.png"] = fakeObject{data: []byte("sign"), modified: yesterday}
+	if err := store.Touch("signs/b.png"); err != nil {
+		t.Errorf("touch: %v", err)
+	}
+	if f, err := store.Stat("signs/b.png"); err != nil || !f.ModTime.After(yesterday) {
+		t.Errorf("touched file %+v, %v", f, err)
+	}
+	if err := store.Touch("signs/missing.png"); err == nil {
+		t.Error("touched a missing file")
+	}
+
+	var walked []string
+	if err := store.Walk(func(f mediaFile) error {
+		walked = append(walked, f.Path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"questions/a.jpg", "signs/b.png"}; !slices.Equal(walked, want) {
+		t.Errorf("walked %v, want %v", walked, want)
+	}
+
+	if err := store.Delete("questions/a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objs["app/questions/a.jpg"]; ok {
+		t.Error("deleted object is still in the bucket")
+	}
+	if _, err := store.Stat("questions/a.jpg"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat of a deleted file: %v", err)
+	}
+	// A file put back after being deleted is found again at once, even
+	// though its absence was cached.
+	if err := store.Put("questions/a.jpg", photo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Stat("questions/a.jpg"); err != nil {
+		t.Errorf("stat of a re-added file: %v", err)
+	}
+	if got := store.URL("signs/b.png"); got != "/media/signs/b.png" {
+		t.Errorf("URL %q", got)
+	}
+	signed, err := openMediaStore("s3://key:secret@" + host + "/media/app?secure=false&region=us-east-1&urls=signed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := signed.URL("signs/b.png"); !strings.HasPrefix(got, srv.URL+"/media/app/signs/b.png?") || !strings.Contains(got, "X-Amz-Signature=") {
+		t.Errorf("signed URL %q", got)
+	}
+}
@@ -0,0 +1,654 @@
+package main
+
+// messagesRu is the Russian UI catalog.
+var messagesRu = map[string]string{
+	// Shared words
+	"common.app_tagline":       "Изучайте вопросы автотеста",
+	"common.back":              "Назад",
+	"common.save":              "Сохранить",
+	"common.edit":              "Редактировать",
+	"common.delete":            "Удалить",
+	"common.cancel":            "Отмена",
+	"common.add":               "Добавить",
+	"common.actions":           "Действия",
+	"common.date":              "Дата",
+	"common.type":              "Тип",
+	"common.question":          "Вопрос",
+	"common.questions":         "Вопросы",
+	"common.question_n":        "Вопрос №%d",
+	"common.image":             "Изображение",
+	"common.image_alt":         "Изображение вопроса",
+	"common.enlarge":           "Увеличить",
+	"common.topic":             "Тема",
+	"common.no_topic":          "Без темы",
+	"common.all_topics":        "Все темы",
+	"common.correct_answer":    "Правильный ответ",
+	"common.explanation":       "Пояснение",
+	"common.explanation_image": "Изображение пояснения",
+	"common.search":            "Поиск",
+	"common.score":             "Балл",
+	"common.correct":           "Верно",
+	"common.wrong":             "Неверно",
+	"common.exam":              "Экзамен",
+	"common.ticket":            "Билет",
+	"common.study":             "Повторение",
+	"common.practice":          "Практика",
+	"common.login":             "Логин",
+	"common.password":          "Пароль",
+	"common.system":            "система",
+	"qtype.single":             "Один ответ",
+	"qtype.multiple":           "Несколько ответов",
+	"qtype.ordering":           "Упорядочивание",
+	"qtype.hotspot":            "Точка на изображении",
+
+	// Navigation
+	"nav.admin_dashboard": "Управление",
+	"nav.questions":       "Вопросы",
+	"nav.categories":      "Темы",
+	"nav.tickets":         "Билеты",
+	"nav.handbook":        "Справочник",
+	"nav.users":           "Пользователи",
+	"nav.statistics":      "Статистика",
+	"nav.settings":        "Настройки",
+	"nav.profile":         "Профиль",
+	"nav.home":            "Главная",
+	"nav.search":          "Поиск",
+	"nav.test":            "Тест",
+	"nav.study":           "Повторение",
+	"nav.bookmarks":       "Избранное",
+	"nav.logout":          "Выход",
+	"bookmark.saved":      "В избранном",
+	"bookmark.save":       "В избранное",
+
+	// Login page
+	"login.title":                "Вход",
+	"login.subtitle":             "Вход в систему",
+	"login.username_placeholder": "Введите логин",
+	"login.password_placeholder": "Введите пароль",
+	"login.submit":               "Войти",
+
+	// Student dashboard
+	"dashboard.title":            "Главная",
+	"dashboard.welcome":          "Добро пожаловать, %s!",
+	"dashboard.total_questions":  "Всего вопросов",
+	"dashboard.bookmarks":        "Избранное",
+	"dashboard.tests":            "Тесты",
+	"dashboard.avg_score":        "Средний балл",
+	"dashboard.due_today":        "Повторить сегодня",
+	"dashboard.unfinished":       "Незавершённые тесты",
+	"dashboard.answered":         "Отвечено",
+	"dashboard.continue":         "Продолжить",
+	"dashboard.abandon_confirm":  "Отменить тест? Ответы будут удалены.",
+	"dashboard.quick_actions":    "Быстрые действия",
+	"dashboard.all_questions":    "Все вопросы",
+	"dashboard.start_test":       "Начать тест",
+	"dashboard.exam_tickets":     "Экзаменационные билеты",
+	"dashboard.search_questions": "Поиск вопросов",
+	"dashboard.saved_questions":  "Избранные вопросы",
+
+	// Messages set by handlers
+	"error.csrf":                      "Ошибка безопасности. Попробуйте ещё раз.",
+	"error.login_failed":              "Неверный логин или пароль!",
+	"error.bad_request":               "Неверный запрос",
+	"error.answer_already_recorded":   "Ответ уже принят",
+	"error.no_questions_in_topics":    "В выбранных темах нет вопросов!",
+	"error.no_questions_in_source":    "В выбранном наборе нет вопросов!",
+	"error.username_taken":            "Такой логин уже существует!",
+	"error.category_name_required":    "Введите название темы!",
+	"error.category_name_taken":       "Тема с таким названием уже существует!",
+	"error.invalid_values":            "Неверные значения!",
+	"error.save_failed":               "Ошибка при сохранении!",
+	"error.restore_question_failed":   "Не удалось восстановить: вопрос с таким номером, возможно, уже существует.",
+	"error.restore_trash_failed":      "Не удалось восстановить: номер вопроса или логин, возможно, уже заняты.",
+	"error.ticket_number_required":    "Введите корректный номер билета!",
+	"error.ticket_question_numbers":   "Номера вопросов: %s",
+	"error.ticket_questions_required": "Введите хотя бы один номер вопроса!",
+	"error.ticket_questions_missing":  "Таких вопросов нет: %s",
+	"error.ticket_number_taken":       "Билет с таким номером уже существует!",
+	"error.invalid_range":             "неверный диапазон: %s",
+	"error.invalid_number":            "неверный номер: %s",
+	"error.chapter_number_taken":      "Глава с таким номером уже существует!",
+	"error.chapter_number_required":   "Введите корректный номер главы!",
+	"error.chapter_title_required":    "Введите название главы!",
+	"error.chapter_required":          "Выберите главу!",
+	"error.article_title_required":    "Введите номер или заголовок статьи!",
+	"error.article_body_required":     "Введите текст статьи!",
+	"error.article_position_invalid":  "Введите корректный порядковый номер!",
+	"error.article_save_failed":       "Не удалось сохранить статью: %v",
+	"error.sign_required":             "Введите номер и название знака!",
+	"error.sign_code_taken":           "Знак с таким номером уже существует!",
+	"error.import_file_required":      "Выберите файл!",
+	"error.import_stopped":            "Импорт остановлен: %s",
+	"success.profile_updated":         "Данные успешно обновлены!",
+	"success.user_updated":            "Пользователь успешно обновлён!",
+	"success.settings_saved":          "Настройки сохранены!",
+	"success.trash_purged":            "Удалены записи с истёкшим сроком: %s",
+	"success.translation_saved":       "Перевод сохранён",
+	"error.image_too_large":           "Изображение слишком большое: не более %d МБ",
+	"error.image_type":                "Файл не является изображением: загрузите JPEG, PNG, GIF или WebP",
+	"error.image_corrupt":             "Не удалось прочитать изображение, файл повреждён",
+	"error.image_dimensions":          "Слишком большое разрешение изображения: %d×%d пикселей",
+	"error.correct_answer_invalid":    "Правильный ответ должен быть одним из вариантов",
+	"error.ordering_variants":         "В вопросе на упорядочивание должно быть не меньше 2 вариантов",
+	"error.hotspot_image":             "Для вопроса с точкой на изображении нужно изображение",
+	"error.hotspot_missing":           "Отметьте на изображении хотя бы одну правильную область",
+	"error.hotspot_format":            "Неверная область: %q (x,y,ширина,высота в процентах, 0-100)",
+	"error.question_type":             "Неизвестный тип вопроса: %q",
+	"error.merge_failed":              "Не удалось объединить вопросы",
+	"error.renumber_failed":           "Не удалось перенумеровать вопросы",
+	"error.review_comment_required":   "Напишите комментарий: при возврате вопроса объясните автору причину",
+	"error.review_failed":             "Не удалось изменить статус вопроса",
+	"success.question_reviewed":       "Проверка сохранена",
+
+	// Question import
+	"import.json_unreadable":       "Не удалось прочитать JSON: %v",
+	"import.zip_unreadable":        "Не удалось прочитать ZIP: %v",
+	"import.zip_several_banks":     "В ZIP несколько файлов с вопросами: %s и %s",
+	"import.zip_no_bank":           "В ZIP не найден файл CSV, JSON или XLSX",
+	"import.csv_unreadable":        "Не удалось прочитать CSV: %v",
+	"import.column_missing":        "Столбец %q не найден",
+	"import.xlsx_unreadable":       "Не удалось прочитать XLSX: %v",
+	"import.xlsx_sheet_unreadable": "Не удалось прочитать лист XLSX: %v",
+	"import.invalid_number":        "неверный номер: %v",
+	"import.invalid_status":        "неизвестный статус %q (допустимы draft, review, published, retired)",
+	"import.empty_text":            "текст вопроса пуст",
+	"import.variant_count":         "вариантов должно быть от 2 до %d",
+	"import.correct_not_variant":   "правильного ответа %q нет среди вариантов",
+	"import.empty_variant":         "вариант %c пуст",
+	"import.image_missing":         "изображение не найдено: %s",
+	"import.duplicate_number":      "номер %d повторяется в файле (строка %d)",
+	"import.number_in_trash":       "вопрос №%d в корзине: сначала восстановите его или удалите окончательно",
+	"import.number_exists":         "вопрос №%d уже существует",
+	"import.image_save_failed":     "строка %d: изображение не сохранено: %v",
+	"import.row_failed":            "строка %d: %v",
+	"import.unsupported_type":      "неподдерживаемый тип файла: %q (нужен CSV, JSON, XLSX или ZIP)",
+	"import.file_empty":            "файл пуст",
+	"import.category_failed":       "тема %q не создана: %v",
+
+	// Question lists
+	"questions.title":      "Все вопросы",
+	"questions.heading":    "Все вопросы (%d)",
+	"questions.empty":      "Вопросов пока нет",
+	"search.title":         "Поиск",
+	"search.heading":       "Поиск вопросов",
+	"search.placeholder":   "Введите текст или номер вопроса...",
+	"search.result_count":  "Найдено результатов: %d",
+	"search.nothing_found": "Ничего не найдено",
+	"search.enter_query":   "Введите слово для поиска",
+	"bookmarks.title":      "Избранные вопросы",
+	"bookmarks.heading":    "Избранные вопросы (%d)",
+	"bookmarks.remove":     "Убрать",
+	"bookmarks.empty":      "Избранных вопросов нет",
+	"bookmarks.browse":     "Смотреть вопросы",
+	"question.articles":    "В справочнике:",
+
+	// Starting a test
+	"start.title":             "Начать тест",
+	"start.random_heading":    "Случайный тест",
+	"start.random_intro":      "Вопросы выбираются в случайном порядке. После выбора ответа сразу видно, верный он или нет.",
+	"start.available":         "Доступно вопросов:",
+	"start.time_per_question": "Время: на каждый вопрос",
+	"start.one_minute":        "1 минута",
+	"start.source":            "Набор вопросов:",
+	"start.source_random":     "Случайные",
+	"start.source_mistakes":   "Мои ошибки (%d)",
+	"start.source_bookmarks":  "Избранные (%d)",
+	"start.source_unanswered": "Ещё не решённые (%d)",
+	"start.source_weakest":    "Самые трудные для меня",
+	"start.topics":            "Темы (если не выбраны — все):",
+	"start.shuffle":           "Перемешивать варианты ответов",
+	"start.count":             "Выберите количество вопросов:",
+	"start.questions_unit":    "вопросов",
+	"start.all":               "Все",
+	"start.no_questions":      "Вопросы ещё не добавлены",
+	"start.exam_heading":      "Режим экзамена",
+	"start.exam_intro":        "Условия настоящего экзамена: время контролирует сервер, при превышении допустимого числа ошибок экзамен сразу завершается.",
+	"start.exam_questions":    "Вопросов:",
+	"start.exam_time":         "Время:",
+	"start.minutes":           "%d мин",
+	"start.exam_mistakes":     "Допустимо ошибок:",
+	"start.exam_start":        "Начать экзамен",
+
+	// Taking a test and its result
+	"test.title":          "Тест",
+	"test.exam_badge":     "Экзамен: не более %d ошибок",
+	"test.question_n":     "Вопрос %d",
+	"test.prev":           "Назад",
+	"test.next":           "Далее",
+	"test.finish":         "Завершить",
+	"test.hint_multiple":  "Выберите все правильные ответы",
+	"test.hint_ordering":  "Расставьте варианты в правильном порядке",
+	"test.hint_hotspot":   "Нажмите на правильное место на изображении",
+	"test.move_up":        "Выше",
+	"test.move_down":      "Ниже",
+	"test.confirm_answer": "Подтвердить ответ",
+	"test.partial":        "Частично верно: %d%%",
+
+	// Test results and statistics
+	"result.title":            "Результат теста",
+	"result.exam_passed":      "Экзамен: сдан",
+	"result.exam_failed":      "Экзамен: не сдан",
+	"result.exam_mistakes":    "(ошибок: %d, допустимо: %d)",
+	"result.ticket_passed":    "Билет %d: сдан",
+	"result.ticket_failed":    "Билет %d: не сдан (больше 2 ошибок)",
+	"result.correct":          "Верно: %d",
+	"result.wrong":            "Неверно: %d",
+	"result.time":             "Время: %d с",
+	"result.total":            "Всего: %d вопросов",
+	"result.details":          "Подробные результаты",
+	"result.question_changed": "Вопрос изменён после ответа; здесь показан вариант на момент ответа.",
+	"result.retry":            "Пройти ещё раз",
+	"stats.title":             "Статистика",
+	"stats.total_tests":       "Всего тестов",
+	"stats.avg_score":         "Средний балл",
+	"stats.best_score":        "Лучший балл",
+	"stats.correct_answers":   "Правильных ответов",
+	"stats.exams_passed":      "Сданные экзамены",
+	"stats.tickets_passed":    "Сданные билеты",
+	"stats.recent":            "Последние тесты",
+	"stats.questions":         "Вопросы",
+	"stats.time":              "Время",
+	"stats.ticket_n":          "Билет %d",
+	"stats.view":              "Открыть",
+	"stats.no_tests":          "Тесты не найдены",
+	"result.partial":          "Частично: %d%%",
+	"result.belongs_at":       "правильное место: %d",
+
+	// Profile, tickets and study
+	"profile.title":                    "Профиль",
+	"profile.joined":                   "Зарегистрирован: %s",
+	"profile.admin":                    "Администратор",
+	"profile.username_placeholder":     "Введите новый логин",
+	"profile.new_password":             "Новый пароль (если оставить пустым, не изменится)",
+	"profile.new_password_placeholder": "Введите новый пароль",
+	"profile.content_lang":             "Язык вопросов",
+	"profile.locale":                   "Язык интерфейса",
+	"tickets.title":                    "Экзаменационные билеты",
+	"tickets.intro":                    "В каждом билете фиксированный набор вопросов, как на экзамене. Билет считается сданным, если допущено не более 2 ошибок.",
+	"tickets.question_count":           "Вопросов: %d",
+	"tickets.passed":                   "Сдан",
+	"tickets.not_passed":               "Не сдан",
+	"tickets.retry":                    "Ещё раз",
+	"tickets.start":                    "Начать",
+	"tickets.empty":                    "Билеты ещё не составлены",
+	"study.title":                      "Повторение",
+	"study.due_heading":                "Вопросы к повторению",
+	"study.due_intro":                  "Каждый вопрос планируется к повторению в зависимости от того, насколько хорошо вы его знаете: с каждым правильным ответом интервал растёт, а после ошибки вопрос скоро вернётся.",
+	"study.due_now":                    "Повторить сейчас:",
+	"study.due_today":                  "Всего сегодня:",
+	"study.start":                      "Начать повторение",
+	"study.nothing_due":                "Пока нечего повторять",
+	"study.new_heading":                "Новые вопросы",
+	"study.new_intro":                  "Изучите до %d ещё не решённых вопросов и добавьте их в расписание повторения.",
+	"study.new_count":                  "Новых вопросов:",
+	"study.learn_new":                  "Изучить новые",
+
+	// Handbook and road signs
+	"sign.group_1":                "Предупреждающие знаки",
+	"sign.group_2":                "Знаки приоритета",
+	"sign.group_3":                "Запрещающие знаки",
+	"sign.group_4":                "Предписывающие знаки",
+	"sign.group_5":                "Информационно-указательные знаки",
+	"sign.group_6":                "Знаки сервиса",
+	"sign.group_7":                "Знаки дополнительной информации",
+	"sign.group_other":            "Прочие знаки",
+	"handbook.title":              "Справочник",
+	"handbook.heading":            "Правила дорожного движения",
+	"handbook.signs":              "Дорожные знаки",
+	"handbook.signs_count":        "Дорожные знаки (%d)",
+	"handbook.search_placeholder": "Поиск по правилам и знакам...",
+	"handbook.chapter_empty":      "В этой главе пока нет статей",
+	"handbook.empty":              "Справочник пока не заполнен",
+	"handbook.contents":           "Содержание",
+	"handbook.related_questions":  "Вопросы по этой статье (%d)",
+	"handbook.answered_of":        "Вы ответили на %[2]d из %[1]d вопросов",
+	"handbook.accuracy":           "точность:",
+	"handbook.number":             "Номер",
+	"handbook.answers":            "Ответы",
+	"handbook.accuracy_heading":   "Точность",
+	"handbook.not_answered":       "Нет ответов",
+	"handbook.search_title":       "Поиск по справочнику",
+	"handbook.found":              "Найдено статей: %d, знаков: %d",
+	"handbook.articles":           "Статьи",
+	"handbook.signs_empty":        "Каталог знаков пока не заполнен",
+
+	// Admin panel
+	"admin.title":           "Панель администратора",
+	"admin.heading":         "Панель управления",
+	"admin.users":           "Пользователи",
+	"admin.total_tests":     "Всего тестов",
+	"admin.quick_actions":   "Быстрые действия",
+	"admin.add_question":    "Добавить вопрос",
+	"admin.add_user":        "Добавить пользователя",
+	"admin.view_statistics": "Посмотреть статистику",
+	"admin.recent_tests":    "Последние тесты",
+	"admin.user":            "Пользователь",
+	"aq.title":              "Управление вопросами",
+	"aq.heading":            "Вопросы (%d)",
+	"aq.import":             "Импорт",
+	"aq.export":             "Экспорт",
+	"aq.translations":       "Переводы",
+	"aq.changes":            "Изменения",
+	"aq.trash":              "Корзина",
+	"aq.delete_confirm":     "Переместить вопрос в корзину?",
+	"aq.empty":              "Вопросы не найдены",
+	"aq.duplicates":         "Дубликаты",
+	"aq.renumber":           "Нумерация",
+
+	// Question form
+	"aq.add_title":                 "Добавить вопрос",
+	"aq.edit_title":                "Редактирование вопроса",
+	"aq.edit_heading":              "Редактирование вопроса №%d",
+	"aq.history":                   "История",
+	"aq.lang_uz_latin":             "Узбекский (латиница)",
+	"aq.text":                      "Текст вопроса:",
+	"aq.text_placeholder":          "Введите вопрос...",
+	"aq.image_optional":            "Изображение (необязательно):",
+	"aq.image":                     "Изображение:",
+	"aq.current_image":             "Текущее изображение",
+	"aq.remove_image":              "Удалить изображение",
+	"aq.topic":                     "Тема:",
+	"aq.variants":                  "Варианты (2-10):",
+	"aq.add_variant":               "Добавить вариант",
+	"aq.remove_variant":            "Убрать",
+	"aq.variant_placeholder":       "Введите вариант %s",
+	"aq.correct_answer":            "Правильный ответ:",
+	"aq.rule_ref":                  "Пункт правил:",
+	"aq.rule_ref_placeholder":      "Например: ПДД п. 10.1",
+	"aq.explanation":               "Текст пояснения (Markdown: **жирный**, *курсив*, списки, [ссылка](https://...)):",
+	"aq.explanation_image":         "Изображение пояснения:",
+	"aq.current_explanation_image": "Текущее изображение пояснения",
+	"aq.handbook":                  "Справочник",
+	"aq.articles":                  "Связанные статьи (несколько — с Ctrl):",
+	"aq.question_type":             "Тип вопроса:",
+	"aq.correct_answers":           "Правильные ответы:",
+	"aq.ordering_hint":             "Введите варианты в правильном порядке; в тесте они будут перемешаны.",
+	"aq.hotspots":                  "Правильные области:",
+	"aq.hotspots_hint":             "Выделите прямоугольник мышью на изображении или введите «x,y,ширина,высота» в процентах, разделяя области точкой с запятой.",
+
+	// Import and export pages
+	"import.title":             "Импорт вопросов",
+	"import.applied":           "Импорт завершён: новых вопросов — %d, обновлённых — %d.",
+	"import.has_errors":        "Ошибки в строках: %d. Исправьте файл и загрузите его снова, ничего не сохранено.",
+	"import.preview":           "Проверка: новых вопросов — %d, будет обновлено — %d.",
+	"import.new_categories":    "Новые темы:",
+	"import.apply_file":        "Импортировать файл %s",
+	"import.file":              "Файл (CSV, JSON, XLSX или ZIP с изображениями):",
+	"import.upsert":            "Обновлять вопросы с существующими номерами",
+	"import.check":             "Проверить",
+	"import.apply_now":         "Импортировать сразу",
+	"import.help_columns":      "Первая строка файлов CSV и XLSX — названия столбцов:",
+	"import.help_optional":     "необязательные",
+	"import.help_json":         "JSON-файл — список объектов с этими полями, а варианты —",
+	"import.help_json_array":   "массив.",
+	"import.help_number":       "Если номер пуст, присваивается следующий свободный; изображение указывается именем файла внутри ZIP.",
+	"import.help_status":       "Столбец status (draft, review, published, retired) приходит из экспорта и учитывается только при импорте администратором; если он пуст, вопрос публикуется, а импорт автора добавляет черновики.",
+	"import.line":              "Строка",
+	"import.answer":            "Ответ",
+	"import.status":            "Статус",
+	"import.will_update":       "Будет обновлён",
+	"import.new":               "Новый",
+	"import.unchanged":         "Без изменений",
+	"import.unchanged_count":   "Без изменений: %d.",
+	"export.title":             "Экспорт вопросов",
+	"export.query":             "Поиск (необязательно):",
+	"export.query_placeholder": "Текст или номер вопроса",
+	"export.categories":        "Темы (если не выбраны — все, всего вопросов: %d):",
+	"export.format":            "Формат:",
+	"export.zip":               "ZIP (JSON + изображения)",
+	"export.download":          "Скачать",
+	"export.help":              "Экспортированный файл можно загрузить на другой сервер через страницу импорта.",
+	"import.help_types":        "Тип: single (по умолчанию), multiple — в correct_answer все верные буквы, ordering — варианты в правильном порядке, hotspot — вместо вариантов столбец hotspots с «x,y,w,h; ...» в процентах.",
+
+	// Question history
+	"revision.number":            "Номер",
+	"revision.text":              "Вопрос",
+	"revision.image":             "Изображение",
+	"revision.category":          "Тема",
+	"revision.correct_answer":    "Правильный ответ",
+	"revision.explanation":       "Пояснение",
+	"revision.explanation_image": "Изображение пояснения",
+	"revision.rule_ref":          "Пункт правил",
+	"revision.variant":           "Вариант %s",
+	"revision.title":             "История вопроса",
+	"revision.heading":           "История вопроса №%d",
+	"revision.all_changes":       "Все изменения",
+	"revision.deleted":           "Этот вопрос удалён. Можно восстановить любую версию.",
+	"revision.restore_confirm":   "Вернуть вопрос к этой версии?",
+	"revision.restore":           "Восстановить",
+	"revision.unchanged":         "Содержание не менялось.",
+	"revision.action_create":     "Создан",
+	"revision.action_update":     "Изменён",
+	"revision.action_delete":     "Удалён",
+	"revision.action_restore":    "Восстановлен",
+	"revision.changes_title":     "Изменения вопросов",
+	"revision.change":            "Изменение",
+	"revision.who":               "Кто",
+	"revision.none":              "Изменений нет",
+	"revision.question_type":     "Тип вопроса",
+	"revision.hotspots":          "Правильные области",
+
+	// Categories and tickets
+	"category.title":                   "Темы",
+	"category.heading":                 "Темы (%d)",
+	"category.name":                    "Название темы:",
+	"category.name_placeholder":        "Например: Дорожные знаки",
+	"category.description_optional":    "Описание (необязательно):",
+	"category.description_placeholder": "Краткое описание",
+	"category.description":             "Описание:",
+	"category.add":                     "Добавить тему",
+	"category.col_name":                "Название",
+	"category.col_description":         "Описание",
+	"category.delete_confirm":          "Удалить тему? Вопросы не удаляются.",
+	"category.empty":                   "Темы не найдены",
+	"category.edit_title":              "Редактирование темы",
+	"category.question_count":          "Вопросов в этой теме: %d.",
+	"aticket.title":                    "Билеты",
+	"aticket.heading":                  "Билеты (%d)",
+	"aticket.add":                      "Добавить билет",
+	"aticket.edit_title":               "Редактирование билета",
+	"aticket.generate_confirm":         "Все билеты будут составлены заново. Продолжить?",
+	"aticket.generate_size":            "Автоматическое составление: разбить %d вопросов по номерам на билеты. Вопросов в билете:",
+	"aticket.generate":                 "Составить билеты",
+	"aticket.attempts":                 "Попытки",
+	"aticket.passed":                   "Сдано",
+	"aticket.delete_confirm":           "Удалить билет?",
+	"aticket.empty":                    "Билеты не найдены",
+	"aticket.number":                   "Номер билета:",
+	"aticket.name_optional":            "Название (необязательно):",
+	"aticket.name_placeholder":         "Например: Билет 1",
+	"aticket.questions":                "Номера вопросов (по порядку, например: 1, 5, 10-15):",
+
+	// Users and trash
+	"user.heading":                 "Пользователи (%d)",
+	"user.joined":                  "Дата регистрации",
+	"user.delete_confirm":          "Переместить пользователя в корзину?",
+	"user.empty":                   "Пользователи не найдены",
+	"user.edit_title":              "Редактирование пользователя",
+	"user.login_label":             "Логин:",
+	"user.login_placeholder":       "Введите логин",
+	"user.password_label":          "Пароль:",
+	"user.password_placeholder":    "Введите пароль",
+	"user.new_password_label":      "Новый пароль (если оставить пустым, не изменится):",
+	"trash.purge_confirm":          "Удалить навсегда записи с истёкшим сроком?",
+	"trash.purge":                  "Очистить просроченные",
+	"trash.retention":              "Удалённые вопросы и пользователи хранятся в корзине %d дн., затем удаляются навсегда. Срок можно изменить в настройках.",
+	"trash.deleted":                "Удалено",
+	"trash.purged_at":              "Будет удалено навсегда",
+	"trash.purge_one":              "Удалить навсегда",
+	"trash.purge_question_confirm": "Удалить навсегда вопрос, его историю и все ответы?",
+	"trash.purge_user_confirm":     "Удалить навсегда пользователя и все его результаты?",
+	"trash.no_questions":           "В корзине нет вопросов",
+	"trash.no_users":               "В корзине нет пользователей",
+	"user.role_label":              "Роль",
+	"user.own_role_hint":           "Свою роль изменить нельзя",
+	"user.staff_heading":           "Сотрудники (%d)",
+	"role.student":                 "Ученик",
+	"role.admin":                   "Администратор",
+	"role.author":                  "Автор",
+	"role.reviewer":                "Рецензент",
+
+	// Handbook administration
+	"ahb.heading":                    "Справочник (глав: %d)",
+	"ahb.view":                       "Просмотр",
+	"ahb.add_article":                "Добавить статью",
+	"ahb.chapter_number":             "Номер главы:",
+	"ahb.chapter_title":              "Название главы:",
+	"ahb.chapter_title_placeholder":  "Например: Общие положения",
+	"ahb.add_chapter":                "Добавить главу",
+	"ahb.chapter_delete_confirm":     "Удалить главу со всеми статьями? Вопросы не удаляются.",
+	"ahb.position":                   "Порядок",
+	"ahb.heading_col":                "Заголовок",
+	"ahb.updated":                    "Обновлено",
+	"ahb.article_delete_confirm":     "Удалить статью?",
+	"ahb.no_articles":                "Статей нет",
+	"ahb.edit_chapter":               "Редактирование главы",
+	"ahb.chapter_articles":           "Статей в этой главе: %d.",
+	"ahb.edit_article":               "Редактирование статьи",
+	"ahb.chapter":                    "Глава:",
+	"ahb.article_number":             "Номер статьи:",
+	"ahb.article_number_placeholder": "Например: 10.1",
+	"ahb.position_new":               "Порядок (если пусто — в конец главы):",
+	"ahb.position_label":             "Порядок:",
+	"ahb.title":                      "Заголовок:",
+	"ahb.body":                       "Текст (Markdown: **жирный**, *курсив*, списки, [ссылка](https://...)):",
+	"ahb.linked_questions":           "Связанные вопросы:",
+	"ahb.no_linked_questions":        "нет. Вопросы связываются на странице редактирования вопроса.",
+
+	// Road sign administration
+	"asign.heading":          "Дорожные знаки (%d)",
+	"asign.code":             "Номер знака:",
+	"asign.code_placeholder": "Например: 2.1",
+	"asign.name":             "Название:",
+	"asign.name_placeholder": "Например: Главная дорога",
+	"asign.description":      "Описание:",
+	"asign.add":              "Добавить знак",
+	"asign.group":            "Группа",
+	"asign.delete_confirm":   "Удалить знак?",
+	"asign.empty":            "Знаки не найдены",
+	"asign.edit_title":       "Редактирование знака",
+
+	// Admin statistics and settings
+	"astats.title":                   "Статистика",
+	"astats.heading":                 "Статистика пользователей",
+	"astats.tests":                   "Количество тестов",
+	"astats.avg_score":               "Средний балл",
+	"astats.empty":                   "Данные не найдены",
+	"astats.by_ticket":               "По билетам",
+	"astats.best_score":              "Лучший балл",
+	"settings.title":                 "Настройки",
+	"settings.exam":                  "Режим экзамена",
+	"settings.question_count":        "Количество вопросов:",
+	"settings.time_limit":            "Время (минут):",
+	"settings.max_mistakes":          "Допустимое число ошибок:",
+	"settings.hide_feedback":         "Не показывать правильные ответы до конца экзамена",
+	"settings.shuffle_variants":      "Перемешивать варианты ответов в каждом экзамене",
+	"settings.practice":              "Режим практики",
+	"settings.practice_explanations": "Показывать пояснение сразу после ответа (кроме экзамена)",
+	"settings.mistakes":              "Работа над ошибками",
+	"settings.mistakes_clear_streak": "Сколько правильных ответов подряд нужно, чтобы вопрос ушёл из списка «Мои ошибки»:",
+	"settings.trash":                 "Корзина",
+	"settings.trash_retention_days":  "Срок хранения удалённых вопросов и пользователей в корзине (дней):",
+
+	// Question translations
+	"translation.status_missing":  "Нет",
+	"translation.status_auto":     "Автоматически",
+	"translation.status_partial":  "Частично",
+	"translation.status_outdated": "Устарел",
+	"translation.status_complete": "Готов",
+	"translation.title":           "Перевод вопроса",
+	"translation.heading":         "Вопрос №%d: %s",
+	"translation.report_title":    "Состояние переводов",
+	"translation.outdated_alert":  "Вопрос изменился после перевода. Проверьте перевод и сохраните его снова.",
+	"translation.hint":            "В пустых полях показывается исходный текст",
+	"translation.hint_cyrillic":   ", а на кириллице он транслитерируется автоматически",
+	"translation.hint_clear":      "Если сохранить все поля пустыми, перевод будет удалён.",
+	"translation.fill":            "Заполнить пустые поля транслитерацией",
+	"translation.variants":        "Варианты:",
+	"translation.explanation":     "Пояснение (Markdown):",
+	"translation.only_needed":     "Только требующие перевода",
+	"translation.show_all":        "Показать все",
+	"translation.count_complete":  "готово: %d",
+	"translation.count_partial":   "частично: %d",
+	"translation.count_outdated":  "устарело: %d",
+	"translation.count_missing":   "нет: %d",
+	"translation.report_hint":     "Вопросы без перевода на кириллице транслитерируются автоматически, а на других языках показываются в исходном виде.",
+	"translation.all_done":        "Все вопросы переведены",
+
+	// Admin media files
+	"media.title":                "Медиафайлы",
+	"media.collect":              "Удалить ненужные",
+	"media.collect_confirm":      "Удалить навсегда файлы, которые нигде не используются?",
+	"media.summary":              "Всего файлов: %d, %s",
+	"media.recent":               "Файлы, загруженные за последний час (%d), пока не проверяются.",
+	"media.orphans":              "Неиспользуемые файлы (%d, %s)",
+	"media.missing":              "Отсутствующие файлы (%d)",
+	"media.file":                 "Файл",
+	"media.size":                 "Размер",
+	"media.modified":             "Изменён",
+	"media.used_by":              "Где используется",
+	"media.ref_question":         "вопрос №%s",
+	"media.ref_revision":         "история вопроса №%s",
+	"media.ref_sign":             "знак %s",
+	"media.no_orphans":           "Неиспользуемых файлов нет",
+	"media.no_missing":           "Все файлы на месте",
+	"settings.media":             "Медиафайлы",
+	"error.media_scan_failed":    "Не удалось прочитать папку media: %v",
+	"error.media_collect_failed": "Не удалось удалить файлы",
+	"success.media_collected":    "Удалено файлов: %s, освобождено %s",
+
+	// Admin duplicate questions
+	"dup.title":                "Дубликаты вопросов",
+	"dup.intro":                "Вопросы с похожими текстом, вариантами и изображением. Регистр, знаки препинания и алфавит (латиница или кириллица) не учитываются. При объединении закладки и ответы в тестах переносятся на оставленный вопрос, остальные попадают в корзину.",
+	"dup.threshold":            "Порог сходства, %:",
+	"dup.find":                 "Найти",
+	"dup.heading":              "Группы похожих (%d)",
+	"dup.similarity":           "Сходство %d%%",
+	"dup.answers_differ":       "Правильные ответы различаются",
+	"dup.keep":                 "Оставить",
+	"dup.merge":                "Объединить",
+	"dup.merge_confirm":        "Оставить отмеченный вопрос и объединить с ним остальные?",
+	"dup.none":                 "Дубликаты не найдены",
+	"success.questions_merged": "Объединено вопросов: %s, оставлен вопрос №%s",
+
+	// Admin question renumbering
+	"renumber.title":               "Порядок и номера вопросов",
+	"renumber.intro":               "Расставьте вопросы перетаскиванием или указав новую позицию и сохраните: они будут перенумерованы с 1 в этом порядке. Вопросы из корзины переходят в конец. Билеты, составленные по порядку номеров, следуют новому порядку, составленные вручную не меняются.",
+	"renumber.new_number":          "Новый номер",
+	"renumber.old_number":          "Текущий номер",
+	"renumber.move_to":             "Переместить на",
+	"renumber.save":                "Сохранить порядок",
+	"renumber.save_confirm":        "Перенумеровать вопросы в этом порядке?",
+	"renumber.compact":             "Убрать пропуски",
+	"renumber.compact_confirm":     "Пронумеровать вопросы подряд с 1, сохранив порядок?",
+	"success.questions_renumbered": "Изменены номера вопросов: %s",
+	"qstatus.heading":              "Статус",
+	"qstatus.all":                  "Все",
+	"qstatus.draft":                "Черновик",
+	"qstatus.review":               "На проверке",
+	"qstatus.published":            "Опубликован",
+	"qstatus.retired":              "Снят",
+	"admin.in_review":              "Вопросы на проверке",
+	"review.save_draft":            "Сохранить черновик",
+	"review.save_submit":           "Сохранить и отправить на проверку",
+	"review.save_publish":          "Сохранить и опубликовать",
+	"review.published_edit_hint":   "Этот вопрос опубликован: после сохранения он вернётся на проверку и не будет показываться ученикам, пока его не одобрят.",
+	"review.heading":               "Проверка",
+	"review.author":                "Автор: %s",
+	"review.unknown_user":          "Удалённый пользователь",
+	"review.empty":                 "Комментариев и смен статуса пока нет.",
+	"review.comment":               "Комментарий",
+	"review.comment_placeholder":   "Комментарий для автора (обязателен при возврате)",
+	"review.action_submit":         "отправил(а) на проверку",
+	"review.action_approve":        "опубликовал(а)",
+	"review.action_return":         "вернул(а) автору",
+	"review.action_retire":         "снял(а) с использования",
+	"review.action_reopen":         "вернул(а) в черновики",
+	"review.action_comment":        "оставил(а) комментарий",
+	"review.do_comment":            "Оставить комментарий",
+	"review.do_submit":             "Отправить на проверку",
+	"review.do_approve":            "Одобрить и опубликовать",
+	"review.do_return":             "Вернуть автору",
+	"review.do_retire":             "Снять с использования",
+	"review.do_reopen":             "Вернуть в черновики",
+}
@@ -0,0 +1,655 @@
+package main
+
+// messagesUz is the Uzbek (Latin) UI catalog. It is the default locale:
+// every key used in handlers and templates must be here.
+var messagesUz = map[string]string{
+	// Shared words
+	"common.app_tagline":       "Avtotest savollarini o'rganing",
+	"common.back":              "Orqaga",
+	"common.save":              "Saqlash",
+	"common.edit":              "Tahrirlash",
+	"common.delete":            "O'chirish",
+	"common.cancel":            "Bekor qilish",
+	"common.add":               "Qo'shish",
+	"common.actions":           "Harakatlar",
+	"common.date":              "Sana",
+	"common.type":              "Turi",
+	"common.question":          "Savol",
+	"common.questions":         "Savollar",
+	"common.question_n":        "Savol #%d",
+	"common.image":             "Rasm",
+	"common.image_alt":         "Savol rasmi",
+	"common.enlarge":           "Kattalashtirish",
+	"common.topic":             "Mavzu",
+	"common.no_topic":          "Mavzusiz",
+	"common.all_topics":        "Barcha mavzular",
+	"common.correct_answer":    "To'g'ri javob",
+	"common.explanation":       "Izoh",
+	"common.explanation_image": "Izoh rasmi",
+	"common.search":            "Qidirish",
+	"common.score":             "Ball",
+	"common.correct":           "To'g'ri",
+	"common.wrong":             "Noto'g'ri",
+	"common.exam":              "Imtihon",
+	"common.ticket":            "Bilet",
+	"common.study":             "Takrorlash",
+	"common.practice":          "Mashq",
+	"common.login":             "Login",
+	"common.password":          "Parol",
+	"common.system":            "tizim",
+	"qtype.single":             "Bitta javob",
+	"qtype.multiple":           "Bir nechta javob",
+	"qtype.ordering":           "Tartiblash",
+	"qtype.hotspot":            "Rasmdagi nuqta",
+
+	// Navigation
+	"nav.admin_dashboard": "Boshqaruv",
+	"nav.questions":       "Savollar",
+	"nav.categories":      "Mavzular",
+	"nav.tickets":         "Biletlar",
+	"nav.handbook":        "Qo'llanma",
+	"nav.users":           "Foydalanuvchilar",
+	"nav.statistics":      "Statistika",
+	"nav.settings":        "Sozlamalar",
+	"nav.profile":         "Profil",
+	"nav.home":            "Bosh sahifa",
+	"nav.search":          "Qidirish",
+	"nav.test":            "Test",
+	"nav.study":           "Takrorlash",
+	"nav.bookmarks":       "Saqlangan",
+	"nav.logout":          "Chiqish",
+	"bookmark.saved":      "Saqlangan",
+	"bookmark.save":       "Saqlash",
+
+	// Login page
+	"login.title":                "Kirish",
+	"login.subtitle":             "Tizimga kirish",
+	"login.username_placeholder": "Loginni kiriting",
+	"login.password_placeholder": "Parolni kiriting",
+	"login.submit":               "Kirish",
+
+	// Student dashboard
+	"dashboard.title":            "Bosh sahifa",
+	"dashboard.welcome":          "Xush kelibsiz, %s!",
+	"dashboard.total_questions":  "Jami savollar",
+	"dashboard.bookmarks":        "Saqlangan",
+	"dashboard.tests":            "Testlar",
+	"dashboard.avg_score":        "O'rtacha ball",
+	"dashboard.due_today":        "Bugun takrorlash",
+	"dashboard.unfinished":       "Tugallanmagan testlar",
+	"dashboard.answered":         "Javob berilgan",
+	"dashboard.continue":         "Davom ettirish",
+	"dashboard.abandon_confirm":  "Testni bekor qilasizmi? Javoblar o'chiriladi.",
+	"dashboard.quick_actions":    "Tezkor harakatlar",
+	"dashboard.all_questions":    "Barcha savollar",
+	"dashboard.start_test":       "Test boshlash",
+	"dashboard.exam_tickets":     "Imtihon biletlari",
+	"dashboard.search_questions": "Savol qidirish",
+	"dashboard.saved_questions":  "Saqlangan savollar",
+
+	// Messages set by handlers
+	"error.csrf":                      "Xavfsizlik xatosi. Qayta urinib ko'ring.",
+	"error.login_failed":              "Login yoki parol xato!",
+	"error.bad_request":               "Noto'g'ri so'rov",
+	"error.answer_already_recorded":   "Javob allaqachon qabul qilingan",
+	"error.no_questions_in_topics":    "Tanlangan mavzularda savollar yo'q!",
+	"error.no_questions_in_source":    "Tanlangan to'plamda savollar yo'q!",
+	"error.username_taken":            "Bu login allaqachon mavjud!",
+	"error.category_name_required":    "Mavzu nomini kiriting!",
+	"error.category_name_taken":       "Bu nomdagi mavzu allaqachon mavjud!",
+	"error.invalid_values":            "Qiymatlar noto'g'ri!",
+	"error.save_failed":               "Saqlashda xatolik!",
+	"error.restore_question_failed":   "Tiklab bo'lmadi: bu raqamli savol allaqachon mavjud bo'lishi mumkin.",
+	"error.restore_trash_failed":      "Tiklab bo'lmadi: bu raqamli savol yoki login allaqachon band bo'lishi mumkin.",
+	"error.ticket_number_required":    "Bilet raqamini to'g'ri kiriting!",
+	"error.ticket_question_numbers":   "Savol raqamlari: %s",
+	"error.ticket_questions_required": "Kamida bitta savol raqamini kiriting!",
+	"error.ticket_questions_missing":  "Bunday savollar yo'q: %s",
+	"error.ticket_number_taken":       "Bu raqamli bilet allaqachon mavjud!",
+	"error.invalid_range":             "noto'g'ri oraliq: %s",
+	"error.invalid_number":            "noto'g'ri raqam: %s",
+	"error.chapter_number_taken":      "Bu raqamli bob allaqachon mavjud!",
+	"error.chapter_number_required":   "Bob raqamini to'g'ri kiriting!",
+	"error.chapter_title_required":    "Bob nomini kiriting!",
+	"error.chapter_required":          "Bobni tanlang!",
+	"error.article_title_required":    "Modda raqami yoki sarlavhasini kiriting!",
+	"error.article_body_required":     "Modda matnini kiriting!",
+	"error.article_position_invalid":  "Tartib raqamini to'g'ri kiriting!",
+	"error.article_save_failed":       "Moddani saqlab bo'lmadi: %v",
+	"error.sign_required":             "Belgi raqami va nomini kiriting!",
+	"error.sign_code_taken":           "Bu raqamli belgi allaqachon mavjud!",
+	"error.import_file_required":      "Faylni tanlang!",
+	"error.import_stopped":            "Import to'xtatildi: %s",
+	"success.profile_updated":         "Ma'lumotlar muvaffaqiyatli yangilandi!",
+	"success.user_updated":            "Foydalanuvchi muvaffaqiyatli yangilandi!",
+	"success.settings_saved":          "Sozlamalar saqlandi!",
+	"success.trash_purged":            "Muddati o'tgan yozuvlar o'chirildi: %s",
+	"success.translation_saved":       "Tarjima saqlandi",
+	"error.image_too_large":           "Rasm juda katta: %d MB dan oshmasligi kerak",
+	"error.image_type":                "Fayl rasm emas: JPEG, PNG, GIF yoki WebP yuklang",
+	"error.image_corrupt":             "Rasmni o'qib bo'lmadi, fayl buzilgan",
+	"error.image_dimensions":          "Rasm o'lchami juda katta: %d×%d piksel",
+	"error.correct_answer_invalid":    "To'g'ri javob variantlardan biri bo'lishi kerak",
+	"error.ordering_variants":         "Tartiblash savolida kamida 2 ta variant bo'lishi kerak",
+	"error.hotspot_image":             "Rasmdagi nuqta savoliga rasm kerak",
+	"error.hotspot_missing":           "Rasmda kamida bitta to'g'ri sohani belgilang",
+	"error.hotspot_format":            "Noto'g'ri soha: %q (x,y,kenglik,balandlik foizda, 0-100)",
+	"error.question_type":             "Noma'lum savol turi: %q",
+	"error.merge_failed":              "Savollarni birlashtirib bo'lmadi",
+	"error.renumber_failed":           "Savollarni qayta raqamlab bo'lmadi",
+	"error.review_comment_required":   "Izoh yozing: savolni qaytarishda muallifga sababini tushuntiring",
+	"error.review_failed":             "Savol holatini o'zgartirib bo'lmadi",
+	"success.question_reviewed":       "Tekshiruv saqlandi",
+
+	// Question import
+	"import.json_unreadable":       "JSON o'qilmadi: %v",
+	"import.zip_unreadable":        "ZIP o'qilmadi: %v",
+	"import.zip_several_banks":     "ZIP ichida bir nechta savollar fayli bor: %s va %s",
+	"import.zip_no_bank":           "ZIP ichida CSV, JSON yoki XLSX fayl topilmadi",
+	"import.csv_unreadable":        "CSV o'qilmadi: %v",
+	"import.column_missing":        "%q ustuni topilmadi",
+	"import.xlsx_unreadable":       "XLSX o'qilmadi: %v",
+	"import.xlsx_sheet_unreadable": "XLSX varag'i o'qilmadi: %v",
+	"import.invalid_number":        "noto'g'ri raqam: %v",
+	"import.invalid_status":        "noma'lum holat %q (draft, review, published yoki retired bo'lishi kerak)",
+	"import.empty_text":            "savol matni bo'sh",
+	"import.variant_count":         "variantlar soni 2 dan %d gacha bo'lishi kerak",
+	"import.correct_not_variant":   "to'g'ri javob %q variantlar orasida yo'q",
+	"import.empty_variant":         "%c variant bo'sh",
+	"import.image_missing":         "rasm topilmadi: %s",
+	"import.duplicate_number":      "%d raqami faylda takrorlangan (%d-qator)",
+	"import.number_in_trash":       "%d raqamli savol savatda turibdi: avval uni tiklang yoki butunlay o'chiring",
+	"import.number_exists":         "%d raqamli savol allaqachon mavjud",
+	"import.image_save_failed":     "%d-qator: rasm saqlanmadi: %v",
+	"import.row_failed":            "%d-qator: %v",
+	"import.unsupported_type":      "qo'llab-quvvatlanmaydigan fayl turi: %q (CSV, JSON, XLSX yoki ZIP bo'lishi kerak)",
+	"import.file_empty":            "fayl bo'sh",
+	"import.category_failed":       "mavzu %q yaratilmadi: %v",
+
+	// Question lists
+	"questions.title":      "Barcha savollar",
+	"questions.heading":    "Barcha savollar (%d)",
+	"questions.empty":      "Hozircha savollar mavjud emas",
+	"search.title":         "Qidirish",
+	"search.heading":       "Savol qidirish",
+	"search.placeholder":   "Savol matni yoki raqamini kiriting...",
+	"search.result_count":  "%d ta natija topildi",
+	"search.nothing_found": "Hech narsa topilmadi",
+	"search.enter_query":   "Qidirish uchun so'z kiriting",
+	"bookmarks.title":      "Saqlangan savollar",
+	"bookmarks.heading":    "Saqlangan savollar (%d)",
+	"bookmarks.remove":     "Olib tashlash",
+	"bookmarks.empty":      "Saqlangan savollar yo'q",
+	"bookmarks.browse":     "Savollarni ko'rish",
+	"question.articles":    "Qo'llanmada:",
+
+	// Starting a test
+	"start.title":             "Test boshlash",
+	"start.random_heading":    "Tasodifiy test",
+	"start.random_intro":      "Savollar tasodifiy tartibda tanlanadi. Javob tanlaganingizda darhol to'g'ri yoki noto'g'ri ekani ko'rsatiladi.",
+	"start.available":         "Mavjud savollar:",
+	"start.time_per_question": "Vaqt: har bir savol uchun",
+	"start.one_minute":        "1 daqiqa",
+	"start.source":            "Savollar to'plami:",
+	"start.source_random":     "Tasodifiy",
+	"start.source_mistakes":   "Xatolarim (%d)",
+	"start.source_bookmarks":  "Saqlanganlar (%d)",
+	"start.source_unanswered": "Hali yechilmaganlar (%d)",
+	"start.source_weakest":    "Eng qiyinlarim",
+	"start.topics":            "Mavzular (tanlanmasa - barchasi):",
+	"start.shuffle":           "Javob variantlari tartibini aralashtirish",
+	"start.count":             "Savollar sonini tanlang:",
+	"start.questions_unit":    "savol",
+	"start.all":               "Hammasi",
+	"start.no_questions":      "Savollar hali qo'shilmagan",
+	"start.exam_heading":      "Imtihon rejimi",
+	"start.exam_intro":        "Haqiqiy imtihon sharoiti: vaqt server tomonidan nazorat qilinadi, ruxsat etilgan xatolar sonidan oshsa imtihon darhol yakunlanadi.",
+	"start.exam_questions":    "Savollar:",
+	"start.exam_time":         "Vaqt:",
+	"start.minutes":           "%d daqiqa",
+	"start.exam_mistakes":     "Ruxsat etilgan xatolar:",
+	"start.exam_start":        "Imtihonni boshlash",
+
+	// Taking a test and its result
+	"test.title":          "Test",
+	"test.exam_badge":     "Imtihon: ko'pi bilan %d ta xato",
+	"test.question_n":     "Savol %d",
+	"test.prev":           "Oldingi",
+	"test.next":           "Keyingi",
+	"test.finish":         "Yakunlash",
+	"test.hint_multiple":  "Barcha to'g'ri javoblarni tanlang",
+	"test.hint_ordering":  "Variantlarni to'g'ri tartibga keltiring",
+	"test.hint_hotspot":   "Rasmda to'g'ri joyni bosing",
+	"test.move_up":        "Yuqoriga",
+	"test.move_down":      "Pastga",
+	"test.confirm_answer": "Javobni tasdiqlash",
+	"test.partial":        "Qisman to'g'ri: %d%%",
+
+	// Test results and statistics
+	"result.title":            "Test natijasi",
+	"result.exam_passed":      "Imtihon: o'tdingiz",
+	"result.exam_failed":      "Imtihon: o'ta olmadingiz",
+	"result.exam_mistakes":    "(%d ta xato, ruxsat etilgan: %d)",
+	"result.ticket_passed":    "Bilet %d: topshirildi",
+	"result.ticket_failed":    "Bilet %d: topshirilmadi (2 tadan ortiq xato)",
+	"result.correct":          "To'g'ri: %d",
+	"result.wrong":            "Noto'g'ri: %d",
+	"result.time":             "Vaqt: %d soniya",
+	"result.total":            "Jami: %d savol",
+	"result.details":          "Batafsil natijalar",
+	"result.question_changed": "Savol javob berilganidan keyin o'zgartirilgan; bu yerda javob berilgan paytdagi holati ko'rsatilgan.",
+	"result.retry":            "Qayta test",
+	"stats.title":             "Statistika",
+	"stats.total_tests":       "Jami testlar",
+	"stats.avg_score":         "O'rtacha ball",
+	"stats.best_score":        "Eng yaxshi ball",
+	"stats.correct_answers":   "To'g'ri javoblar",
+	"stats.exams_passed":      "Topshirilgan imtihonlar",
+	"stats.tickets_passed":    "Topshirilgan biletlar",
+	"stats.recent":            "Oxirgi testlar",
+	"stats.questions":         "Savollar",
+	"stats.time":              "Vaqt",
+	"stats.ticket_n":          "Bilet %d",
+	"stats.view":              "Ko'rish",
+	"stats.no_tests":          "Testlar topilmadi",
+	"result.partial":          "Qisman: %d%%",
+	"result.belongs_at":       "to'g'ri o'rni: %d",
+
+	// Profile, tickets and study
+	"profile.title":                    "Profil",
+	"profile.joined":                   "Ro'yxatdan o'tgan: %s",
+	"profile.admin":                    "Admin",
+	"profile.username_placeholder":     "Yangi loginni kiriting",
+	"profile.new_password":             "Yangi parol (bo'sh qoldirsa o'zgarmaydi)",
+	"profile.new_password_placeholder": "Yangi parolni kiriting",
+	"profile.content_lang":             "Savollar tili",
+	"profile.locale":                   "Interfeys tili",
+	"tickets.title":                    "Imtihon biletlari",
+	"tickets.intro":                    "Har bir biletda imtihondagidek qat'iy savollar to'plami. Ko'pi bilan 2 ta xato qilsangiz, bilet topshirilgan hisoblanadi.",
+	"tickets.question_count":           "%d savol",
+	"tickets.passed":                   "Topshirilgan",
+	"tickets.not_passed":               "Topshirilmagan",
+	"tickets.retry":                    "Qayta",
+	"tickets.start":                    "Boshlash",
+	"tickets.empty":                    "Biletlar hali tuzilmagan",
+	"study.title":                      "Takrorlash",
+	"study.due_heading":                "Vaqti kelgan savollar",
+	"study.due_intro":                  "Har bir savol siz uni qanchalik yaxshi bilishingizga qarab takrorlashga rejalashtiriladi: to'g'ri javob berganingiz sari oraliq uzayadi, xato qilsangiz savol tez orada qaytadi.",
+	"study.due_now":                    "Hozir takrorlash kerak:",
+	"study.due_today":                  "Bugun jami:",
+	"study.start":                      "Takrorlashni boshlash",
+	"study.nothing_due":                "Hozircha takrorlanadigan savollar yo'q",
+	"study.new_heading":                "Yangi savollar",
+	"study.new_intro":                  "Hali yechilmagan savollardan %d tagacha o'rganib, ularni takrorlash jadvaliga qo'shing.",
+	"study.new_count":                  "Yangi savollar:",
+	"study.learn_new":                  "Yangilarini o'rganish",
+
+	// Handbook and road signs
+	"sign.group_1":                "Ogohlantiruvchi belgilar",
+	"sign.group_2":                "Imtiyoz belgilari",
+	"sign.group_3":                "Taqiqlovchi belgilar",
+	"sign.group_4":                "Buyuruvchi belgilar",
+	"sign.group_5":                "Axborot-ishora belgilari",
+	"sign.group_6":                "Servis belgilari",
+	"sign.group_7":                "Qo'shimcha axborot belgilari",
+	"sign.group_other":            "Boshqa belgilar",
+	"handbook.title":              "Qo'llanma",
+	"handbook.heading":            "Yo'l harakati qoidalari",
+	"handbook.signs":              "Yo'l belgilari",
+	"handbook.signs_count":        "Yo'l belgilari (%d)",
+	"handbook.search_placeholder": "Qoidalar va belgilardan qidirish...",
+	"handbook.chapter_empty":      "Bu bobda hali moddalar yo'q",
+	"handbook.empty":              "Qo'llanma hali to'ldirilmagan",
+	"handbook.contents":           "Mundarija",
+	"handbook.related_questions":  "Shu moddaga oid savollar (%d)",
+	"handbook.answered_of":        "%d ta savoldan %d tasiga javob bergansiz",
+	"handbook.accuracy":           "aniqlik:",
+	"handbook.number":             "Raqam",
+	"handbook.answers":            "Javoblar",
+	"handbook.accuracy_heading":   "Aniqlik",
+	"handbook.not_answered":       "Javob berilmagan",
+	"handbook.search_title":       "Qo'llanmadan qidirish",
+	"handbook.found":              "%d ta modda va %d ta belgi topildi",
+	"handbook.articles":           "Moddalar",
+	"handbook.signs_empty":        "Belgilar katalogi hali to'ldirilmagan",
+
+	// Admin panel
+	"admin.title":           "Admin panel",
+	"admin.heading":         "Boshqaruv paneli",
+	"admin.users":           "Foydalanuvchilar",
+	"admin.total_tests":     "Jami testlar",
+	"admin.quick_actions":   "Tezkor harakatlar",
+	"admin.add_question":    "Savol qo'shish",
+	"admin.add_user":        "Foydalanuvchi qo'shish",
+	"admin.view_statistics": "Statistikani ko'rish",
+	"admin.recent_tests":    "Oxirgi testlar",
+	"admin.user":            "Foydalanuvchi",
+	"aq.title":              "Savollarni boshqarish",
+	"aq.heading":            "Savollar (%d)",
+	"aq.import":             "Import",
+	"aq.export":             "Eksport",
+	"aq.translations":       "Tarjimalar",
+	"aq.changes":            "O'zgarishlar",
+	"aq.trash":              "Savat",
+	"aq.delete_confirm":     "Savol savatga o'tkazilsinmi?",
+	"aq.empty":              "Savollar topilmadi",
+	"aq.duplicates":         "Dublikatlar",
+	"aq.renumber":           "Raqamlash",
+
+	// Question form
+	"aq.add_title":                 "Savol qo'shish",
+	"aq.edit_title":                "Savolni tahrirlash",
+	"aq.edit_heading":              "Savolni tahrirlash #%d",
+	"aq.history":                   "Tarix",
+	"aq.lang_uz_latin":             "O'zbekcha (lotin)",
+	"aq.text":                      "Savol matni:",
+	"aq.text_placeholder":          "Savolni kiriting...",
+	"aq.image_optional":            "Rasm (ixtiyoriy):",
+	"aq.image":                     "Rasm:",
+	"aq.current_image":             "Hozirgi rasm",
+	"aq.remove_image":              "Rasmni o'chirish",
+	"aq.topic":                     "Mavzu:",
+	"aq.variants":                  "Variantlar (2-10):",
+	"aq.add_variant":               "Variant qo'shish",
+	"aq.remove_variant":            "Olib tashlash",
+	"aq.variant_placeholder":       "%s variantni kiriting",
+	"aq.correct_answer":            "To'g'ri javob:",
+	"aq.rule_ref":                  "Qoida moddasi:",
+	"aq.rule_ref_placeholder":      "Masalan: YHQ 10.1-band",
+	"aq.explanation":               "Izoh matni (Markdown: **qalin**, *kursiv*, ro'yxatlar, [havola](https://...)):",
+	"aq.explanation_image":         "Izoh rasmi:",
+	"aq.current_explanation_image": "Hozirgi izoh rasmi",
+	"aq.handbook":                  "Qo'llanma",
+	"aq.articles":                  "Bog'langan moddalar (Ctrl bilan bir nechtasini tanlang):",
+	"aq.question_type":             "Savol turi:",
+	"aq.correct_answers":           "To'g'ri javoblar:",
+	"aq.ordering_hint":             "Variantlarni to'g'ri tartibda kiriting; testda ular aralashtirib ko'rsatiladi.",
+	"aq.hotspots":                  "To'g'ri sohalar:",
+	"aq.hotspots_hint":             "Rasm ustida sichqoncha bilan to'rtburchak chizing yoki \"x,y,kenglik,balandlik\" ni foizda kiriting, sohalarni nuqtali vergul bilan ajrating.",
+
+	// Import and export pages
+	"import.title":             "Savollarni import qilish",
+	"import.applied":           "Import yakunlandi: %d ta yangi, %d ta yangilangan savol.",
+	"import.has_errors":        "%d ta qatorda xato bor. Faylni tuzatib qayta yuklang, hech narsa saqlanmadi.",
+	"import.preview":           "Tekshiruv: %d ta yangi, %d ta yangilanadigan savol.",
+	"import.new_categories":    "Yangi mavzular:",
+	"import.apply_file":        "%s faylini import qilish",
+	"import.file":              "Fayl (CSV, JSON, XLSX yoki rasmlar bilan ZIP):",
+	"import.upsert":            "Mavjud raqamli savollarni yangilash",
+	"import.check":             "Tekshirish",
+	"import.apply_now":         "Darhol import qilish",
+	"import.help_columns":      "CSV va XLSX fayllarning birinchi qatori ustun nomlari:",
+	"import.help_optional":     "ixtiyoriy",
+	"import.help_json":         "JSON fayl shu maydonlarga ega obyektlar ro'yxati, variantlar esa",
+	"import.help_json_array":   "massivi.",
+	"import.help_number":       "Raqam bo'sh bo'lsa keyingi bo'sh raqam beriladi; rasm ZIP ichidagi fayl nomi bilan ko'rsatiladi.",
+	"import.help_status":       "status ustuni (draft, review, published, retired) eksportdan keladi va faqat administrator importida hisobga olinadi; bo'sh bo'lsa savol nashr qilinadi, muallif importi esa qoralama qo'shadi.",
+	"import.line":              "Qator",
+	"import.answer":            "Javob",
+	"import.status":            "Holat",
+	"import.will_update":       "Yangilanadi",
+	"import.new":               "Yangi",
+	"import.unchanged":         "O'zgarmagan",
+	"import.unchanged_count":   "O'zgarishsiz qoladi: %d ta savol.",
+	"export.title":             "Savollarni eksport qilish",
+	"export.query":             "Qidiruv (ixtiyoriy):",
+	"export.query_placeholder": "Savol matni yoki raqami",
+	"export.categories":        "Mavzular (tanlanmasa - barchasi, jami %d ta savol):",
+	"export.format":            "Format:",
+	"export.zip":               "ZIP (JSON + rasmlar)",
+	"export.download":          "Yuklab olish",
+	"export.help":              "Eksport qilingan fayl import sahifasi orqali boshqa serverga qayta yuklanishi mumkin.",
+	"import.help_types":        "Turi: single (standart), multiple — correct_answer da barcha to'g'ri harflar, ordering — variantlar to'g'ri tartibda, hotspot — variantlar o'rniga hotspots ustunida \"x,y,w,h; ...\" foizda.",
+
+	// Question history
+	"revision.number":            "Raqam",
+	"revision.text":              "Savol",
+	"revision.image":             "Rasm",
+	"revision.category":          "Mavzu",
+	"revision.correct_answer":    "To'g'ri javob",
+	"revision.explanation":       "Izoh",
+	"revision.explanation_image": "Izoh rasmi",
+	"revision.rule_ref":          "Qoida moddasi",
+	"revision.variant":           "Variant %s",
+	"revision.title":             "Savol tarixi",
+	"revision.heading":           "Savol #%d tarixi",
+	"revision.all_changes":       "Barcha o'zgarishlar",
+	"revision.deleted":           "Bu savol o'chirilgan. Istalgan versiyani tiklash mumkin.",
+	"revision.restore_confirm":   "Savol shu versiyaga qaytarilsinmi?",
+	"revision.restore":           "Tiklash",
+	"revision.unchanged":         "Mazmun o'zgarmagan.",
+	"revision.action_create":     "Yaratildi",
+	"revision.action_update":     "Tahrirlandi",
+	"revision.action_delete":     "O'chirildi",
+	"revision.action_restore":    "Tiklandi",
+	"revision.changes_title":     "Savollardagi o'zgarishlar",
+	"revision.change":            "O'zgarish",
+	"revision.who":               "Kim",
+	"revision.none":              "O'zgarishlar yo'q",
+	"revision.question_type":     "Savol turi",
+	"revision.hotspots":          "To'g'ri sohalar",
+
+	// Categories and tickets
+	"category.title":                   "Mavzular",
+	"category.heading":                 "Mavzular (%d)",
+	"category.name":                    "Mavzu nomi:",
+	"category.name_placeholder":        "Masalan: Yo'l belgilari",
+	"category.description_optional":    "Izoh (ixtiyoriy):",
+	"category.description_placeholder": "Qisqacha izoh",
+	"category.description":             "Izoh:",
+	"category.add":                     "Mavzu qo'shish",
+	"category.col_name":                "Nomi",
+	"category.col_description":         "Izoh",
+	"category.delete_confirm":          "Mavzuni o'chirasizmi? Savollar o'chirilmaydi.",
+	"category.empty":                   "Mavzular topilmadi",
+	"category.edit_title":              "Mavzuni tahrirlash",
+	"category.question_count":          "Bu mavzuda %d ta savol bor.",
+	"aticket.title":                    "Biletlar",
+	"aticket.heading":                  "Biletlar (%d)",
+	"aticket.add":                      "Bilet qo'shish",
+	"aticket.edit_title":               "Biletni tahrirlash",
+	"aticket.generate_confirm":         "Barcha biletlar qaytadan tuziladi. Davom etasizmi?",
+	"aticket.generate_size":            "Avtomatik tuzish: %d ta savolni raqami bo'yicha biletlarga bo'lish. Har biletdagi savollar soni:",
+	"aticket.generate":                 "Biletlarni tuzish",
+	"aticket.attempts":                 "Urinishlar",
+	"aticket.passed":                   "Topshirilgan",
+	"aticket.delete_confirm":           "Biletni o'chirasizmi?",
+	"aticket.empty":                    "Biletlar topilmadi",
+	"aticket.number":                   "Bilet raqami:",
+	"aticket.name_optional":            "Nomi (ixtiyoriy):",
+	"aticket.name_placeholder":         "Masalan: Bilet 1",
+	"aticket.questions":                "Savol raqamlari (tartib bo'yicha, masalan: 1, 5, 10-15):",
+
+	// Users and trash
+	"user.heading":                 "Foydalanuvchilar (%d)",
+	"user.joined":                  "Ro'yxatdan o'tgan",
+	"user.delete_confirm":          "Foydalanuvchi savatga o'tkazilsinmi?",
+	"user.empty":                   "Foydalanuvchilar topilmadi",
+	"user.edit_title":              "Foydalanuvchini tahrirlash",
+	"user.login_label":             "Login:",
+	"user.login_placeholder":       "Loginni kiriting",
+	"user.password_label":          "Parol:",
+	"user.password_placeholder":    "Parolni kiriting",
+	"user.new_password_label":      "Yangi parol (bo'sh qoldirsa o'zgarmaydi):",
+	"trash.purge_confirm":          "Muddati o'tgan yozuvlar butunlay o'chirilsinmi?",
+	"trash.purge":                  "Muddati o'tganlarni tozalash",
+	"trash.retention":              "O'chirilgan savollar va foydalanuvchilar %d kun savatda saqlanadi, so'ng butunlay o'chiriladi. Muddatni sozlamalarda o'zgartirish mumkin.",
+	"trash.deleted":                "O'chirilgan",
+	"trash.purged_at":              "Butunlay o'chiriladi",
+	"trash.purge_one":              "Butunlay o'chirish",
+	"trash.purge_question_confirm": "Savol, uning tarixi va barcha javoblar butunlay o'chirilsinmi?",
+	"trash.purge_user_confirm":     "Foydalanuvchi va uning barcha natijalari butunlay o'chirilsinmi?",
+	"trash.no_questions":           "Savatda savollar yo'q",
+	"trash.no_users":               "Savatda foydalanuvchilar yo'q",
+	"user.role_label":              "Roli",
+	"user.own_role_hint":           "O'z rolingizni o'zgartira olmaysiz",
+	"user.staff_heading":           "Xodimlar (%d)",
+	"role.student":                 "O'quvchi",
+	"role.admin":                   "Administrator",
+	"role.author":                  "Muallif",
+	"role.reviewer":                "Tekshiruvchi",
+
+	// Handbook administration
+	"ahb.heading":                    "Qo'llanma (%d bob)",
+	"ahb.view":                       "Ko'rish",
+	"ahb.add_article":                "Modda qo'shish",
+	"ahb.chapter_number":             "Bob raqami:",
+	"ahb.chapter_title":              "Bob nomi:",
+	"ahb.chapter_title_placeholder":  "Masalan: Umumiy qoidalar",
+	"ahb.add_chapter":                "Bob qo'shish",
+	"ahb.chapter_delete_confirm":     "Bob barcha moddalari bilan o'chirilsinmi? Savollar o'chirilmaydi.",
+	"ahb.position":                   "Tartib",
+	"ahb.heading_col":                "Sarlavha",
+	"ahb.updated":                    "Yangilangan",
+	"ahb.article_delete_confirm":     "Modda o'chirilsinmi?",
+	"ahb.no_articles":                "Moddalar yo'q",
+	"ahb.edit_chapter":               "Bobni tahrirlash",
+	"ahb.chapter_articles":           "Bu bobda %d ta modda bor.",
+	"ahb.edit_article":               "Moddani tahrirlash",
+	"ahb.chapter":                    "Bob:",
+	"ahb.article_number":             "Modda raqami:",
+	"ahb.article_number_placeholder": "Masalan: 10.1",
+	"ahb.position_new":               "Tartib (bo'sh qoldirilsa bob oxiriga):",
+	"ahb.position_label":             "Tartib:",
+	"ahb.title":                      "Sarlavha:",
+	"ahb.body":                       "Matn (Markdown: **qalin**, *kursiv*, ro'yxatlar, [havola](https://...)):",
+	"ahb.linked_questions":           "Bog'langan savollar:",
+	"ahb.no_linked_questions":        "yo'q. Savollarni savol tahrirlash sahifasida bog'lang.",
+
+	// Road sign administration
+	"asign.heading":          "Yo'l belgilari (%d)",
+	"asign.code":             "Belgi raqami:",
+	"asign.code_placeholder": "Masalan: 2.1",
+	"asign.name":             "Nomi:",
+	"asign.name_placeholder": "Masalan: Asosiy yo'l",
+	"asign.description":      "Tavsif:",
+	"asign.add":              "Belgi qo'shish",
+	"asign.group":            "Guruh",
+	"asign.delete_confirm":   "Belgi o'chirilsinmi?",
+	"asign.empty":            "Belgilar topilmadi",
+	"asign.edit_title":       "Belgini tahrirlash",
+
+	// Admin statistics and settings
+	"astats.title":                   "Statistika",
+	"astats.heading":                 "Foydalanuvchilar statistikasi",
+	"astats.tests":                   "Testlar soni",
+	"astats.avg_score":               "O'rtacha ball",
+	"astats.empty":                   "Ma'lumot topilmadi",
+	"astats.by_ticket":               "Biletlar bo'yicha",
+	"astats.best_score":              "Eng yaxshi ball",
+	"settings.title":                 "Sozlamalar",
+	"settings.exam":                  "Imtihon rejimi",
+	"settings.question_count":        "Savollar soni:",
+	"settings.time_limit":            "Vaqt (daqiqa):",
+	"settings.max_mistakes":          "Ruxsat etilgan xatolar soni:",
+	"settings.hide_feedback":         "To'g'ri javoblarni imtihon tugaguncha ko'rsatmaslik",
+	"settings.shuffle_variants":      "Javob variantlari tartibini har bir imtihonda aralashtirish",
+	"settings.practice":              "Mashq rejimi",
+	"settings.practice_explanations": "Javob berilgach savol izohini darhol ko'rsatish (imtihondan tashqari)",
+	"settings.mistakes":              "Xatolar ustida ishlash",
+	"settings.mistakes_clear_streak": "Savol \"Xatolarim\" ro'yxatidan chiqishi uchun ketma-ket to'g'ri javoblar soni:",
+	"settings.trash":                 "Savat",
+	"settings.trash_retention_days":  "O'chirilgan savol va foydalanuvchilar savatda saqlanadigan muddat (kun):",
+
+	// Question translations
+	"translation.status_missing":  "Yo'q",
+	"translation.status_auto":     "Avtomatik",
+	"translation.status_partial":  "Qisman",
+	"translation.status_outdated": "Eskirgan",
+	"translation.status_complete": "Tayyor",
+	"translation.title":           "Savol tarjimasi",
+	"translation.heading":         "Savol #%d: %s",
+	"translation.report_title":    "Tarjimalar holati",
+	"translation.outdated_alert":  "Savol tarjimadan keyin o'zgargan. Tarjimani tekshirib, qayta saqlang.",
+	"translation.hint":            "Bo'sh qoldirilgan maydonlarda asl matn ko'rsatiladi",
+	"translation.hint_cyrillic":   ", kirill yozuvida esa u avtomatik transliteratsiya qilinadi",
+	"translation.hint_clear":      "Hamma maydonni bo'shatib saqlasangiz, tarjima o'chiriladi.",
+	"translation.fill":            "Bo'sh maydonlarni transliteratsiya bilan to'ldirish",
+	"translation.variants":        "Variantlar:",
+	"translation.explanation":     "Izoh (Markdown):",
+	"translation.only_needed":     "Faqat tarjima kerak bo'lganlar",
+	"translation.show_all":        "Hammasini ko'rsatish",
+	"translation.count_complete":  "%d tayyor",
+	"translation.count_partial":   "%d qisman",
+	"translation.count_outdated":  "%d eskirgan",
+	"translation.count_missing":   "%d yo'q",
+	"translation.report_hint":     "Tarjimasi yo'q savollar kirill yozuvida avtomatik transliteratsiya qilinib, boshqa tillarda esa asl matnda ko'rsatiladi.",
+	"translation.all_done":        "Barcha savollar tarjima qilingan",
+
+	// Admin media files
+	"media.title":                "Media fayllar",
+	"media.collect":              "Keraksizlarini o'chirish",
+	"media.collect_confirm":      "Hech qayerda ishlatilmayotgan fayllar butunlay o'chirilsinmi?",
+	"media.summary":              "Jami %d ta fayl, %s",
+	"media.recent":               "Oxirgi bir soatda yuklangan %d ta fayl hali tekshirilmaydi.",
+	"media.orphans":              "Ishlatilmayotgan fayllar (%d, %s)",
+	"media.missing":              "Topilmagan fayllar (%d)",
+	"media.file":                 "Fayl",
+	"media.size":                 "Hajmi",
+	"media.modified":             "O'zgartirilgan",
+	"media.used_by":              "Qayerda ishlatiladi",
+	"media.ref_question":         "savol #%s",
+	"media.ref_revision":         "savol #%s tarixi",
+	"media.ref_sign":             "belgi %s",
+	"media.no_orphans":           "Ishlatilmayotgan fayllar yo'q",
+	"media.no_missing":           "Barcha fayllar joyida",
+	"settings.media":             "Media fayllar",
+	"error.media_scan_failed":    "Media papkasini o'qib bo'lmadi: %v",
+	"error.media_collect_failed": "Fayllarni o'chirib bo'lmadi",
+	"success.media_collected":    "%s ta fayl o'chirildi, %s bo'shadi",
+
+	// Admin duplicate questions
+	"dup.title":                "Savol dublikatlari",
+	"dup.intro":                "Matni, variantlari va rasmi bir-biriga o'xshash savollar. Katta-kichik harf, tinish belgilari va yozuv (lotin yoki kirill) hisobga olinmaydi. Birlashtirilganda xatcho'plar va test javoblari qoldirilgan savolga o'tkaziladi, qolganlari savatga tushadi.",
+	"dup.threshold":            "O'xshashlik chegarasi, %:",
+	"dup.find":                 "Qidirish",
+	"dup.heading":              "O'xshash guruhlar (%d)",
+	"dup.similarity":           "%d%% o'xshash",
+	"dup.answers_differ":       "To'g'ri javoblar farq qiladi",
+	"dup.keep":                 "Qoldirish",
+	"dup.merge":                "Birlashtirish",
+	"dup.merge_confirm":        "Belgilangan savol qoldirilsin, qolganlari unga birlashtirilsinmi?",
+	"dup.none":                 "Dublikatlar topilmadi",
+	"success.questions_merged": "%s ta savol #%s savolga birlashtirildi",
+
+	// Admin question renumbering
+	"renumber.title":               "Savollar tartibi va raqamlari",
+	"renumber.intro":               "Savollarni sudrab yoki yangi o'rnini kiritib joylashtiring, so'ng saqlang: ular shu tartibda 1 dan boshlab qayta raqamlanadi. Savatdagi savollar oxiriga o'tadi. Savol raqamlari tartibida tuzilgan biletlar yangi tartibga moslanadi, qo'lda tuzilganlari o'zgarmaydi.",
+	"renumber.new_number":          "Yangi raqam",
+	"renumber.old_number":          "Hozirgi raqam",
+	"renumber.move_to":             "O'rniga o'tkazish",
+	"renumber.save":                "Tartibni saqlash",
+	"renumber.save_confirm":        "Savollar shu tartibda qayta raqamlansinmi?",
+	"renumber.compact":             "Bo'shliqlarni yopish",
+	"renumber.compact_confirm":     "Tartib saqlangan holda savollar 1 dan ketma-ket raqamlansinmi?",
+	"success.questions_renumbered": "%s ta savol raqami o'zgardi",
+	"qstatus.heading":              "Holati",
+	"qstatus.all":                  "Hammasi",
+	"qstatus.draft":                "Qoralama",
+	"qstatus.review":               "Tekshiruvda",
+	"qstatus.published":            "E'lon qilingan",
+	"qstatus.retired":              "Foydalanishdan chiqarilgan",
+	"admin.in_review":              "Tekshiruvdagi savollar",
+	"review.save_draft":            "Qoralama sifatida saqlash",
+	"review.save_submit":           "Saqlash va tekshiruvga yuborish",
+	"review.save_publish":          "Saqlash va e'lon qilish",
+	"review.published_edit_hint":   "Bu savol e'lon qilingan: saqlaganingizdan so'ng u tekshiruvga qaytadi va tasdiqlanguncha o'quvchilarga ko'rsatilmaydi.",
+	"review.heading":               "Tekshiruv",
+	"review.author":                "Muallif: %s",
+	"review.unknown_user":          "O'chirilgan foydalanuvchi",
+	"review.empty":                 "Hali izoh yoki holat o'zgarishlari yo'q.",
+	"review.comment":               "Izoh",
+	"review.comment_placeholder":   "Muallif uchun izoh (qaytarishda majburiy)",
+	"review.action_submit":         "tekshiruvga yubordi",
+	"review.action_approve":        "e'lon qildi",
+	"review.action_return":         "muallifga qaytardi",
+	"review.action_retire":         "foydalanishdan chiqardi",
+	"review.action_reopen":         "qoralamaga qaytardi",
+	"review.action_comment":        "izoh qoldirdi",
+	"review.do_comment":            "Izoh qoldirish",
+	"review.do_submit":             "Tekshiruvga yuborish",
+	"review.do_approve":            "Tasdiqlash va e'lon qilish",
+	"review.do_return":             "Muallifga qaytarish",
+	"review.do_retire":             "Foydalanishdan chiqarish",
+	"review.do_reopen":             "Qoralamaga qaytarish",
+}
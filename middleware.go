@@ -15,7 +15,7 @@ func getCurrentUser(r *http.Request) *User {
         if !ok {
                 return nil
         }
-        return getUserByID(userID)
+        return db.GetUserByID(userID)
 }
 
 func setCurrentUser(w http.ResponseWriter, r *http.Request, userID int) {
@@ -56,6 +56,31 @@ func adminRequired(handler http.HandlerFunc) http.HandlerFunc {
         }
 }
 
+// postRoleRequired is roleRequired for the forms of a page every staff
+// member may view, such as a list with an "add" form above it: only POSTs
+// need the role.
+func postRoleRequired(can func(*User) bool, handler http.HandlerFunc) http.HandlerFunc {
+        return adminRequired(func(w http.ResponseWriter, r *http.Request) {
+                if r.Method == "POST" && !can(getCurrentUser(r)) {
+                        http.Error(w, "Forbidden", http.StatusForbidden)
+                        return
+                }
+                handler(w, r)
+        })
+}
+
+// roleRequired is adminRequired for pages that only some staff roles may
+// use; can is the check, such as (*User).CanWrite.
+func roleRequired(can func(*User) bool, handler http.HandlerFunc) http.HandlerFunc {
+        return adminRequired(func(w http.ResponseWriter, r *http.Request) {
+                if !can(getCurrentUser(r)) {
+                        http.Error(w, "Forbidden", http.StatusForbidden)
+                        return
+                }
+                handler(w, r)
+        })
+}
+
 func recoveryMiddleware(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
                 defer func() {
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// migration is one numbered, reversible schema change. Statements are
+// written for PostgreSQL and adapted to SQLite by sqlStore.ddl.
+type migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// migrations must stay ordered by Version with no gaps. Never edit a
+// migration that has shipped; add a new one instead.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				username VARCHAR(255) UNIQUE NOT NULL,
+				password_hash VARCHAR(255) NOT NULL,
+				is_staff BOOLEAN DEFAULT FALSE,
+				date_joined TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE IF NOT EXISTS questions (
+				id SERIAL PRIMARY KEY,
+				number INTEGER UNIQUE NOT NULL,
+				text TEXT NOT NULL,
+				image VARCHAR(500) DEFAULT '',
+				variants_json TEXT DEFAULT '[]',
+				correct_answer VARCHAR(1) NOT NULL,
+				variant_a VARCHAR(500) DEFAULT '',
+				variant_b VARCHAR(500) DEFAULT '',
+				variant_c VARCHAR(500) DEFAULT '',
+				variant_d VARCHAR(500) DEFAULT '',
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE IF NOT EXISTS bookmarks (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+				question_id INTEGER REFERENCES questions(id) ON DELETE CASCADE,
+				created_at TIMESTAMP DEFAULT NOW(),
+				UNIQUE(user_id, question_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS test_sessions (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+				total_questions INTEGER NOT NULL,
+				correct_answers INTEGER DEFAULT 0,
+				wrong_answers INTEGER DEFAULT 0,
+				time_spent INTEGER DEFAULT 0,
+				completed BOOLEAN DEFAULT FALSE,
+				question_ids TEXT DEFAULT '[]',
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE IF NOT EXISTS test_answers (
+				id SERIAL PRIMARY KEY,
+				session_id INTEGER REFERENCES test_sessions(id) ON DELETE CASCADE,
+				question_id INTEGER REFERENCES questions(id) ON DELETE CASCADE,
+				selected_answer VARCHAR(1) DEFAULT '',
+				is_correct BOOLEAN DEFAULT FALSE
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE test_answers`,
+			`DROP TABLE test_sessions`,
+			`DROP TABLE bookmarks`,
+			`DROP TABLE questions`,
+			`DROP TABLE users`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "question categories",
+		Up: []string{
+			`CREATE TABLE categories (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) UNIQUE NOT NULL,
+				description TEXT DEFAULT '',
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`ALTER TABLE questions ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL`,
+			`CREATE INDEX idx_questions_category ON questions(category_id)`,
+		},
+		Down: []string{
+			`DROP INDEX idx_questions_category`,
+			`ALTER TABLE questions DROP COLUMN category_id`,
+			`DROP TABLE categories`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "exam tickets",
+		Up: []string{
+			`CREATE TABLE tickets (
+				id SERIAL PRIMARY KEY,
+				number INTEGER UNIQUE NOT NULL,
+				title VARCHAR(255) DEFAULT '',
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE ticket_questions (
+				ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+				question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				PRIMARY KEY (ticket_id, question_id)
+			)`,
+			`ALTER TABLE test_sessions ADD COLUMN ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL`,
+			`CREATE INDEX idx_test_sessions_ticket ON test_sessions(ticket_id)`,
+		},
+		Down: []string{
+			`DROP INDEX idx_test_sessions_ticket`,
+			`ALTER TABLE test_sessions DROP COLUMN ticket_id`,
+			`DROP TABLE ticket_questions`,
+			`DROP TABLE tickets`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "exam mode",
+		Up: []string{
+			`CREATE TABLE settings (
+				name VARCHAR(100) PRIMARY KEY,
+				value TEXT NOT NULL DEFAULT ''
+			)`,
+			`ALTER TABLE test_sessions ADD COLUMN mode VARCHAR(20) NOT NULL DEFAULT 'practice'`,
+			`ALTER TABLE test_sessions ADD COLUMN time_limit INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE test_sessions ADD COLUMN max_mistakes INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE test_sessions ADD COLUMN started_at TIMESTAMP`,
+			`ALTER TABLE test_sessions ADD COLUMN passed BOOLEAN NOT NULL DEFAULT FALSE`,
+			`UPDATE test_sessions SET started_at = created_at`,
+		},
+		Down: []string{
+			`ALTER TABLE test_sessions DROP COLUMN passed`,
+			`ALTER TABLE test_sessions DROP COLUMN started_at`,
+			`ALTER TABLE test_sessions DROP COLUMN max_mistakes`,
+			`ALTER TABLE test_sessions DROP COLUMN time_limit`,
+			`ALTER TABLE test_sessions DROP COLUMN mode`,
+			`DROP TABLE settings`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "per-answer checking",
+		Up: []string{
+			`DELETE FROM test_answers WHERE id NOT IN (
+				SELECT MIN(id) FROM test_answers GROUP BY session_id, question_id
+			)`,
+			`CREATE UNIQUE INDEX idx_test_answers_session_question ON test_answers(session_id, question_id)`,
+			`ALTER TABLE test_sessions ADD COLUMN hide_feedback BOOLEAN NOT NULL DEFAULT FALSE`,
+		},
+		Down: []string{
+			`ALTER TABLE test_sessions DROP COLUMN hide_feedback`,
+			`DROP INDEX idx_test_answers_session_question`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "spaced repetition",
+		Up: []string{
+			`CREATE TABLE user_question_state (
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+				repetitions INTEGER NOT NULL DEFAULT 0,
+				interval_days INTEGER NOT NULL DEFAULT 0,
+				ease DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+				due_at TIMESTAMP NOT NULL,
+				reviewed_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (user_id, question_id)
+			)`,
+			`CREATE INDEX idx_user_question_state_due ON user_question_state(user_id, due_at)`,
+		},
+		Down: []string{
+			`DROP INDEX idx_user_question_state_due`,
+			`DROP TABLE user_question_state`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "shuffled variants",
+		Up: []string{
+			`ALTER TABLE test_sessions ADD COLUMN shuffle_seed BIGINT NOT NULL DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE test_sessions DROP COLUMN shuffle_seed`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "question revisions",
+		Up: []string{
+			`CREATE TABLE question_revisions (
+				id SERIAL PRIMARY KEY,
+				question_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				action VARCHAR(20) NOT NULL,
+				number INTEGER NOT NULL,
+				text TEXT NOT NULL,
+				image VARCHAR(500) DEFAULT '',
+				variants_json TEXT DEFAULT '[]',
+				correct_answer VARCHAR(1) NOT NULL,
+				variant_a VARCHAR(500) DEFAULT '',
+				variant_b VARCHAR(500) DEFAULT '',
+				variant_c VARCHAR(500) DEFAULT '',
+				variant_d VARCHAR(500) DEFAULT '',
+				category_id INTEGER,
+				user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				UNIQUE (question_id, revision)
+			)`,
+			`CREATE INDEX idx_question_revisions_created ON question_revisions(created_at)`,
+			`ALTER TABLE questions ADD COLUMN revision INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE test_answers ADD COLUMN question_revision INTEGER NOT NULL DEFAULT 0`,
+			`INSERT INTO question_revisions (question_id, revision, action, number, text, image, variants_json,
+				correct_answer, variant_a, variant_b, variant_c, variant_d, category_id, created_at)
+				SELECT id, 1, 'create', number, text, COALESCE(image, ''), variants_json,
+				correct_answer, variant_a, variant_b, variant_c, variant_d, category_id, updated_at
+				FROM questions`,
+			`UPDATE questions SET revision = 1`,
+		},
+		Down: []string{
+			`ALTER TABLE test_answers DROP COLUMN question_revision`,
+			`ALTER TABLE questions DROP COLUMN revision`,
+			`DROP INDEX idx_question_revisions_created`,
+			`DROP TABLE question_revisions`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "soft delete",
+		Up: []string{
+			`ALTER TABLE questions ADD COLUMN deleted_at TIMESTAMP`,
+			`ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP`,
+		},
+		Down: []string{
+			`DELETE FROM question_revisions WHERE question_id IN (SELECT id FROM questions WHERE deleted_at IS NOT NULL)`,
+			`DELETE FROM questions WHERE deleted_at IS NOT NULL`,
+			`DELETE FROM users WHERE deleted_at IS NOT NULL`,
+			`ALTER TABLE users DROP COLUMN deleted_at`,
+			`ALTER TABLE questions DROP COLUMN deleted_at`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "question explanations",
+		Up: []string{
+			`ALTER TABLE questions ADD COLUMN explanation TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE questions ADD COLUMN explanation_image VARCHAR(500) NOT NULL DEFAULT ''`,
+			`ALTER TABLE questions ADD COLUMN rule_ref VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE question_revisions ADD COLUMN explanation TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE question_revisions ADD COLUMN explanation_image VARCHAR(500) NOT NULL DEFAULT ''`,
+			`ALTER TABLE question_revisions ADD COLUMN rule_ref VARCHAR(255) NOT NULL DEFAULT ''`,
+		},
+		Down: []string{
+			`ALTER TABLE question_revisions DROP COLUMN rule_ref`,
+			`ALTER TABLE question_revisions DROP COLUMN explanation_image`,
+			`ALTER TABLE question_revisions DROP COLUMN explanation`,
+			`ALTER TABLE questions DROP COLUMN rule_ref`,
+			`ALTER TABLE questions DROP COLUMN explanation_image`,
+			`ALTER TABLE questions DROP COLUMN explanation`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "handbook",
+		Up: []string{
+			`CREATE TABLE handbook_chapters (
+				id SERIAL PRIMARY KEY,
+				number INTEGER UNIQUE NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE handbook_articles (
+				id SERIAL PRIMARY KEY,
+				chapter_id INTEGER NOT NULL REFERENCES handbook_chapters(id) ON DELETE CASCADE,
+				number VARCHAR(20) NOT NULL DEFAULT '',
+				title VARCHAR(255) NOT NULL DEFAULT '',
+				body TEXT NOT NULL DEFAULT '',
+				position INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE INDEX idx_handbook_articles_chapter ON handbook_articles(chapter_id, position)`,
+			`CREATE TABLE road_signs (
+				id SERIAL PRIMARY KEY,
+				code VARCHAR(20) UNIQUE NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				image VARCHAR(500) NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE TABLE question_articles (
+				question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+				article_id INTEGER NOT NULL REFERENCES handbook_articles(id) ON DELETE CASCADE,
+				PRIMARY KEY (question_id, article_id)
+			)`,
+			`CREATE INDEX idx_question_articles_article ON question_articles(article_id)`,
+		},
+		Down: []string{
+			`DROP INDEX idx_question_articles_article`,
+			`DROP TABLE question_articles`,
+			`DROP TABLE road_signs`,
+			`DROP INDEX idx_handbook_articles_chapter`,
+			`DROP TABLE handbook_articles`,
+			`DROP TABLE handbook_chapters`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "question translations",
+		Up: []string{
+			`CREATE TABLE question_translations (
+				question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+				lang VARCHAR(10) NOT NULL,
+				text TEXT NOT NULL DEFAULT '',
+				variants_json TEXT NOT NULL DEFAULT '[]',
+				explanation TEXT NOT NULL DEFAULT '',
+				updated_at TIMESTAMP DEFAULT NOW(),
+				PRIMARY KEY (question_id, lang)
+			)`,
+			`ALTER TABLE users ADD COLUMN content_lang VARCHAR(10) NOT NULL DEFAULT ''`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN content_lang`,
+			`DROP TABLE question_translations`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "user interface locale",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT ''`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN locale`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "question types and partial credit",
+		Up: []string{
+			`ALTER TABLE questions ADD COLUMN question_type VARCHAR(20) NOT NULL DEFAULT 'single'`,
+			`ALTER TABLE questions ADD COLUMN hotspots_json TEXT NOT NULL DEFAULT '[]'`,
+			`ALTER TABLE questions ALTER COLUMN correct_answer TYPE VARCHAR(20)`,
+			`ALTER TABLE question_revisions ADD COLUMN question_type VARCHAR(20) NOT NULL DEFAULT 'single'`,
+			`ALTER TABLE question_revisions ADD COLUMN hotspots_json TEXT NOT NULL DEFAULT '[]'`,
+			`ALTER TABLE question_revisions ALTER COLUMN correct_answer TYPE VARCHAR(20)`,
+			`ALTER TABLE test_answers ALTER COLUMN selected_answer TYPE VARCHAR(20)`,
+			`ALTER TABLE test_answers ADD COLUMN credit REAL NOT NULL DEFAULT 0`,
+			`UPDATE test_answers SET credit = 1 WHERE is_correct`,
+			`ALTER TABLE test_sessions ADD COLUMN points REAL NOT NULL DEFAULT 0`,
+			`UPDATE test_sessions SET points = correct_answers`,
+		},
+		Down: []string{
+			`ALTER TABLE test_sessions DROP COLUMN points`,
+			`ALTER TABLE test_answers DROP COLUMN credit`,
+			`UPDATE test_answers SET selected_answer = '' WHERE LENGTH(selected_answer) > 1`,
+			`ALTER TABLE test_answers ALTER COLUMN selected_answer TYPE VARCHAR(1)`,
+			`UPDATE question_revisions SET correct_answer = SUBSTR(correct_answer, 1, 1)`,
+			`ALTER TABLE question_revisions ALTER COLUMN correct_answer TYPE VARCHAR(1)`,
+			`ALTER TABLE question_revisions DROP COLUMN hotspots_json`,
+			`ALTER TABLE question_revisions DROP COLUMN question_type`,
+			`UPDATE questions SET correct_answer = SUBSTR(correct_answer, 1, 1)`,
+			`ALTER TABLE questions ALTER COLUMN correct_answer TYPE VARCHAR(1)`,
+			`ALTER TABLE questions DROP COLUMN hotspots_json`,
+			`ALTER TABLE questions DROP COLUMN question_type`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "question review workflow",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT ''`,
+			`UPDATE users SET role = 'admin' WHERE is_staff`,
+			`ALTER TABLE questions ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published'`,
+			`ALTER TABLE questions ADD COLUMN author_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+			`UPDATE questions SET author_id = (SELECT r.user_id FROM question_revisions r
+				WHERE r.question_id = questions.id ORDER BY r.revision LIMIT 1)`,
+			`CREATE INDEX idx_questions_status ON questions(status)`,
+			`CREATE TABLE question_reviews (
+				id SERIAL PRIMARY KEY,
+				question_id INTEGER NOT NULL,
+				user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				action VARCHAR(20) NOT NULL,
+				comment TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT NOW()
+			)`,
+			`CREATE INDEX idx_question_reviews_question ON question_reviews(question_id)`,
+		},
+		Down: []string{
+			`DROP INDEX idx_question_reviews_question`,
+			`DROP TABLE question_reviews`,
+			`DROP INDEX idx_questions_status`,
+			`ALTER TABLE questions DROP COLUMN author_id`,
+			`ALTER TABLE questions DROP COLUMN status`,
+			`ALTER TABLE users DROP COLUMN role`,
+		},
+	},
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// migrationStatus describes one known migration and whether it is applied.
+type migrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrator is implemented by stores that have a versioned SQL schema.
+type migrator interface {
+	SchemaVersion() (int, error)
+	MigrationStatus() ([]migrationStatus, error)
+	MigrateTo(target int) error
+}
+
+func (s *sqlStore) ensureMigrationsTable() error {
+	_, err := s.db.Exec(s.ddl(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP DEFAULT NOW()
+	)`))
+	return err
+}
+
+func (s *sqlStore) SchemaVersion() (int, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+	var v sql.NullInt64
+	if err := s.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+func (s *sqlStore) MigrationStatus() ([]migrationStatus, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	var result []migrationStatus
+	for _, m := range migrations {
+		at, ok := applied[m.Version]
+		result = append(result, migrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return result, nil
+}
+
+// Migrate brings the schema up to the latest version known to this binary
+// and refuses to run against a schema written by a newer one.
+func (s *sqlStore) Migrate() error {
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > latestSchemaVersion() {
+		return fmt.Errorf("database schema is at version %d but this binary only knows up to %d; upgrade the binary", current, latestSchemaVersion())
+	}
+	return s.MigrateTo(latestSchemaVersion())
+}
+
+// MigrateTo applies or reverts migrations one at a time, each in its own
+// transaction, until the schema is at target.
+func (s *sqlStore) MigrateTo(target int) error {
+	if target < 0 || target > latestSchemaVersion() {
+		return fmt.Errorf("unknown schema version %d (latest is %d)", target, latestSchemaVersion())
+	}
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > latestSchemaVersion() {
+		return fmt.Errorf("database schema is at version %d but this binary only knows up to %d", current, latestSchemaVersion())
+	}
+	for _, m := range migrations {
+		if m.Version > current && m.Version <= target {
+			if err := s.applyMigration(m, true); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= current && m.Version > target {
+			if err := s.applyMigration(m, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) applyMigration(m migration, up bool) error {
+	stmts, verb := m.Up, "up"
+	if !up {
+		stmts, verb = m.Down, "down"
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, q := range stmts {
+		if _, err := tx.Exec(s.ddl(q)); err != nil {
+			return fmt.Errorf("migration %d (%s) %s: %w", m.Version, m.Name, verb, err)
+		}
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version=$1", m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Migration %d (%s) %s", m.Version, m.Name, verb)
+	return nil
+}
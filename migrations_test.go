@@ -0,0 +1,141 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sqliteSchema lists the tables and indexes of a SQLite store as created,
+// apart from schema_migrations itself.
+func sqliteSchema(t *testing.T, s *sqlStore) string {
+	t.Helper()
+	rows, err := s.db.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations' ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var b strings.Builder
+	for rows.Next() {
+		var kind, name, stmt string
+		rows.Scan(&kind, &name, &stmt)
+		b.WriteString(kind + " " + name + ": " + stmt + "\n")
+	}
+	return b.String()
+}
+
+// expectSchemaVersion checks that exactly the migrations up to version are
+// recorded in schema_migrations.
+func expectSchemaVersion(t *testing.T, s *sqlStore, version int) {
+	t.Helper()
+	if v, err := s.SchemaVersion(); err != nil || v != version {
+		t.Fatalf("schema version %d (%v), want %d", v, err, version)
+	}
+	status, err := s.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range status {
+		if st.Applied != (st.Version <= version) {
+			t.Fatalf("at version %d, migration %d applied: %v", version, st.Version, st.Applied)
+		}
+	}
+}
+
+func TestMigrationsUpDownUp(t *testing.T) {
+	s, err := newSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	expectSchemaVersion(t, s, 0)
+
+	var schemas []string
+	for _, m := range migrations {
+		if err := s.MigrateTo(m.Version); err != nil {
+			t.Fatal(err)
+		}
+		expectSchemaVersion(t, s, m.Version)
+		schemas = append(schemas, sqliteSchema(t, s))
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if err := s.MigrateTo(migrations[i].Version - 1); err != nil {
+			t.Fatal(err)
+		}
+		expectSchemaVersion(t, s, migrations[i].Version-1)
+		if i > 0 && sqliteSchema(t, s) != schemas[i-1] {
+			t.Errorf("reverting migration %d leaves a schema unlike version %d's", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+	if schema := sqliteSchema(t, s); schema != "" {
+		t.Errorf("schema left at version 0:\n%s", schema)
+	}
+
+	if err := s.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	expectSchemaVersion(t, s, latestSchemaVersion())
+	if sqliteSchema(t, s) != schemas[len(schemas)-1] {
+		t.Error("re-applied schema differs from the first one")
+	}
+	if err := s.MigrateTo(latestSchemaVersion() + 1); err == nil {
+		t.Error("migrated to an unknown version")
+	}
+
+	// The re-applied schema still holds a bank.
+	saved := db
+	defer func() { db = saved }()
+	db = s
+	q := &Question{Number: 1, Text: "Stop sign?", CorrectAnswer: "A",
+		VariantsList: []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}}}
+	if err := db.CreateQuestion(q, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.GetQuestionByID(q.ID); got == nil || got.Text != q.Text {
+		t.Fatalf("question read back as %+v", got)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	t.Setenv("DATABASE_URL", "sqlite://"+path)
+	saved := db
+	defer func() { db = saved }()
+
+	version := func() int {
+		t.Helper()
+		s, err := newSQLiteStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		v, err := s.SchemaVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	latest := latestSchemaVersion()
+	for _, step := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"up"}, latest},
+		{[]string{"down"}, latest - 1},
+		{[]string{"to", "3"}, 3},
+		{[]string{"to", "0"}, 0},
+		{[]string{"down"}, 0},
+		{[]string{"up"}, latest},
+	} {
+		if code := migrateCommand(step.args); code != 0 {
+			t.Fatalf("migrate %v: exit code %d", step.args, code)
+		}
+		if v := version(); v != step.want {
+			t.Fatalf("after migrate %v: version %d, want %d", step.args, v, step.want)
+		}
+	}
+	if code := migrateCommand([]string{"to", "x"}); code == 0 {
+		t.Error("migrate to x succeeded")
+	}
+}
@@ -2,13 +2,13 @@ package main
 
 import (
 	"crypto/rand"
-	"database/sql"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"log"
+	"math"
 	"math/big"
-	"strings"
+	mrand "math/rand/v2"
+	"slices"
+	"strconv"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -20,11 +20,25 @@ type User struct {
 	PassHash   string
 	IsStaff    bool
 	DateJoined time.Time
+	DeletedAt  time.Time
+
+	// ContentLang is the language questions are shown in; empty means the
+	// default, Uzbek Latin.
+	ContentLang string
+	// Locale is the language of the interface; empty means it is taken
+	// from the language switch or the browser.
+	Locale string
+	// Role is one of staffRoles for staff and empty for students.
+	Role string
 }
 
+// Variant is one answer option. Letter is the canonical letter stored with
+// the question; Label is the letter shown in a test session, which differs
+// when the session shuffles variants.
 type Variant struct {
 	Letter string `json:"letter"`
 	Text   string `json:"text"`
+	Label  string `json:"-"`
 }
 
 type Question struct {
@@ -41,6 +55,40 @@ type Question struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	VariantsList  []Variant
+	CategoryID    int
+	Revision      int
+	DeletedAt     time.Time
+
+	// Explanation is Markdown shown once the question has been answered,
+	// optionally with an image; RuleRef names the traffic rule it is based on.
+	Explanation      string
+	ExplanationImage string
+	RuleRef          string
+
+	// Type is one of questionTypes and decides how CorrectAnswer is read;
+	// Hotspots are the areas to click on the image of a hotspot question.
+	Type     string
+	Hotspots []Hotspot
+
+	// Status is one of questionStatuses; only published questions reach
+	// students. AuthorID is the staff member who wrote the question.
+	Status   string
+	AuthorID int
+}
+
+type Category struct {
+	ID            int
+	Name          string
+	Description   string
+	CreatedAt     time.Time
+	QuestionCount int
+}
+
+// QuestionFilter narrows question listings. Empty fields match everything.
+type QuestionFilter struct {
+	Query       string
+	CategoryIDs []int
+	Status      string
 }
 
 type Bookmark struct {
@@ -51,17 +99,163 @@ type Bookmark struct {
 }
 
 type TestSession struct {
-	ID              int
-	UserID          int
-	TotalQuestions  int
-	CorrectAnswers  int
-	WrongAnswers    int
-	TimeSpent       int
-	Completed       bool
-	QuestionIDs     string
-	CreatedAt       time.Time
-	Username        string
-	ScorePercent    int
+	ID             int
+	UserID         int
+	TotalQuestions int
+	CorrectAnswers int
+	WrongAnswers   int
+	TimeSpent      int
+	Completed      bool
+	QuestionIDs    string
+	CreatedAt      time.Time
+	Username       string
+	ScorePercent   int
+	// Points is the sum of the answers' credit, which partial answers add
+	// to; ScorePercent is computed from it.
+	Points        float64
+	TicketID      int
+	Mode          string
+	TimeLimit     int
+	MaxMistakes   int
+	StartedAt     time.Time
+	Passed        bool
+	HideFeedback  bool
+	AnsweredCount int
+	ShuffleSeed   int64
+}
+
+const (
+	sessionModePractice = "practice"
+	sessionModeExam     = "exam"
+	sessionModeStudy    = "study"
+)
+
+// submitGracePeriod is how long after the deadline a submission is still
+// accepted, to cover network latency and the client auto-submit.
+const submitGracePeriod = 15 * time.Second
+
+// staleSessionAge is how long a session without a time limit may stay open
+// before the sweeper closes it.
+const staleSessionAge = 24 * time.Hour
+
+// ExamRules are the admin-configurable conditions of exam mode. TimeLimit is
+// in minutes; HideFeedback keeps correctness hidden until the exam is over.
+type ExamRules struct {
+	QuestionCount   int
+	TimeLimit       int
+	MaxMistakes     int
+	HideFeedback    bool
+	ShuffleVariants bool
+}
+
+// defaultExamRules mirror the official driving theory exam.
+var defaultExamRules = ExamRules{QuestionCount: 20, TimeLimit: 25, MaxMistakes: 2}
+
+const (
+	settingExamQuestionCount = "exam_question_count"
+	settingExamTimeLimit     = "exam_time_limit"
+	settingExamMaxMistakes   = "exam_max_mistakes"
+	settingExamHideFeedback  = "exam_hide_feedback"
+	settingExamShuffle       = "exam_shuffle_variants"
+
+	settingMistakesClearStreak = "mistakes_clear_streak"
+
+	settingTrashRetentionDays = "trash_retention_days"
+
+	settingPracticeExplanations = "practice_explanations"
+)
+
+// defaultMistakesClearStreak is how many correct answers in a row take a
+// question out of the "my mistakes" pool.
+const defaultMistakesClearStreak = 3
+
+// Test sources offered on the start page besides plain random selection.
+const (
+	testSourceRandom     = "random"
+	testSourceMistakes   = "mistakes"
+	testSourceBookmarks  = "bookmarks"
+	testSourceUnanswered = "unanswered"
+	testSourceWeakest    = "weakest"
+)
+
+// ticketMaxMistakes is how many wrong answers a ticket attempt may have and
+// still count as passed, as in the real exam.
+const ticketMaxMistakes = 2
+
+// Ticket is an official exam ticket ("bilet"): a fixed, ordered set of
+// questions.
+type Ticket struct {
+	ID            int
+	Number        int
+	Title         string
+	CreatedAt     time.Time
+	QuestionIDs   []int
+	QuestionCount int
+}
+
+// TicketStat summarises completed attempts at one ticket.
+type TicketStat struct {
+	TicketID  int
+	Attempts  int
+	Passed    int
+	BestScore int
+}
+
+// QuestionState is one user's spaced-repetition schedule for one question,
+// following SM-2: Interval is in days and Ease scales it after each correct
+// review.
+type QuestionState struct {
+	UserID      int
+	QuestionID  int
+	Repetitions int
+	Interval    int
+	Ease        float64
+	DueAt       time.Time
+	ReviewedAt  time.Time
+}
+
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+	// relearnDelay is when a question answered wrongly comes due again.
+	relearnDelay = 10 * time.Minute
+	// studyBatchSize is how many questions one study session serves.
+	studyBatchSize = 20
+)
+
+// Review applies one answer to the schedule. Answers are graded 5 (correct)
+// or 2 (wrong) on the SM-2 quality scale.
+func (st *QuestionState) Review(correct bool, now time.Time) {
+	if st.Ease == 0 {
+		st.Ease = defaultEase
+	}
+	quality := 2.0
+	if correct {
+		quality = 5
+	}
+	st.Ease += 0.1 - (5-quality)*(0.08+(5-quality)*0.02)
+	if st.Ease < minEase {
+		st.Ease = minEase
+	}
+
+	now = now.UTC().Truncate(time.Second)
+	st.ReviewedAt = now
+	if !correct {
+		st.Repetitions = 0
+		st.Interval = 0
+		st.DueAt = now.Add(relearnDelay)
+		return
+	}
+	switch st.Repetitions {
+	case 0:
+		st.Interval = 1
+	case 1:
+		st.Interval = 6
+	default:
+		st.Interval = int(math.Round(float64(st.Interval) * st.Ease))
+	}
+	st.Repetitions++
+	st.DueAt = now.AddDate(0, 0, st.Interval)
 }
 
 type TestAnswer struct {
@@ -70,7 +264,37 @@ type TestAnswer struct {
 	QuestionID     int
 	SelectedAnswer string
 	IsCorrect      bool
-	Question       *Question
+	// Credit is the share of the question's point the answer earned, from
+	// 0 to 1; IsCorrect means full credit.
+	Credit   float64
+	Question *Question
+	// DisplayedAnswer is SelectedAnswer as the letter the student saw.
+	DisplayedAnswer string
+	// QuestionRevision is the revision of the question the answer was given
+	// against; 0 for answers recorded before revisions were kept.
+	QuestionRevision int
+}
+
+// Question revision actions.
+const (
+	revisionCreate  = "create"
+	revisionUpdate  = "update"
+	revisionDelete  = "delete"
+	revisionRestore = "restore"
+)
+
+// QuestionRevision is a snapshot of a question taken on every change.
+// Revisions are numbered per question from 1; a delete revision keeps the
+// last content so the question can be restored from it.
+type QuestionRevision struct {
+	ID         int
+	QuestionID int
+	Revision   int
+	Action     string
+	UserID     int
+	Username   string
+	CreatedAt  time.Time
+	Question   *Question
 }
 
 func generateRandomString(n int) string {
@@ -100,436 +324,232 @@ func (q *Question) ComputeVariants() {
 	q.VariantsList = variants
 }
 
-func (s *TestSession) CalcScorePercent() {
-	if s.TotalQuestions == 0 {
-		s.ScorePercent = 0
-		return
-	}
-	s.ScorePercent = int(float64(s.CorrectAnswers) / float64(s.TotalQuestions) * 100)
+func (q *Question) HasExplanation() bool {
+	return q.Explanation != "" || q.ExplanationImage != "" || q.RuleRef != ""
 }
 
-func getUserByID(id int) *User {
-	u := &User{}
-	err := db.QueryRow("SELECT id, username, password_hash, is_staff, date_joined FROM users WHERE id=$1", id).
-		Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined)
-	if err != nil {
-		return nil
-	}
-	return u
+func (s *TestSession) QuestionIDList() []int {
+	var ids []int
+	json.Unmarshal([]byte(s.QuestionIDs), &ids)
+	return ids
 }
 
-func getUserByUsername(username string) *User {
-	u := &User{}
-	err := db.QueryRow("SELECT id, username, password_hash, is_staff, date_joined FROM users WHERE username=$1", username).
-		Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined)
-	if err != nil {
-		return nil
-	}
-	return u
+func (s *TestSession) IsExam() bool {
+	return s.Mode == sessionModeExam
 }
 
-func authenticateUser(username, password string) *User {
-	u := getUserByUsername(username)
-	if u == nil {
-		return nil
-	}
-	err := bcrypt.CompareHashAndPassword([]byte(u.PassHash), []byte(password))
-	if err != nil {
-		return nil
+// Deadline is when the session stops accepting answers. Only exams have
+// one; practice sessions and exams without a time limit never expire.
+func (s *TestSession) Deadline() time.Time {
+	if !s.IsExam() || s.TimeLimit <= 0 || s.StartedAt.IsZero() {
+		return time.Time{}
 	}
-	return u
+	return s.StartedAt.Add(time.Duration(s.TimeLimit) * time.Second)
 }
 
-func createUser(username, password string, isStaff bool) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+// RemainingSeconds is the time left before the deadline, never negative.
+func (s *TestSession) RemainingSeconds(now time.Time) int {
+	deadline := s.Deadline()
+	if deadline.IsZero() {
+		return s.TimeLimit
 	}
-	_, err = db.Exec("INSERT INTO users (username, password_hash, is_staff) VALUES ($1, $2, $3)",
-		username, string(hash), isStaff)
-	return err
-}
-
-func updateUserUsername(id int, username string) error {
-	_, err := db.Exec("UPDATE users SET username=$1 WHERE id=$2", username, id)
-	return err
+	if left := int(deadline.Sub(now).Seconds()); left > 0 {
+		return left
+	}
+	return 0
 }
 
-func updateUserPassword(id int, password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-	_, err = db.Exec("UPDATE users SET password_hash=$1 WHERE id=$2", string(hash), id)
-	return err
+// Expired reports whether the deadline, plus the grace period, has passed.
+func (s *TestSession) Expired(now time.Time) bool {
+	deadline := s.Deadline()
+	return !deadline.IsZero() && now.After(deadline.Add(submitGracePeriod))
 }
 
-func getNonStaffUsers() []*User {
-	rows, err := db.Query("SELECT id, username, password_hash, is_staff, date_joined FROM users WHERE is_staff=FALSE ORDER BY id")
-	if err != nil {
-		log.Printf("Error getting users: %v", err)
-		return nil
+// DisplayVariants returns q's variants in the order this session shows
+// them, labelled A, B, C... by position. The order is derived from the
+// session seed and the question id, so it is the same on every page load.
+// Sessions without a seed keep the stored order and letters, except for
+// ordering questions: their stored order is the answer, so they are always
+// shuffled, and never left in the right order.
+func (s *TestSession) DisplayVariants(q *Question) []Variant {
+	variants := make([]Variant, len(q.VariantsList))
+	copy(variants, q.VariantsList)
+	seed := s.ShuffleSeed
+	if q.IsOrdering() && seed == 0 {
+		seed = int64(s.ID) + 1
 	}
-	defer rows.Close()
-	var users []*User
-	for rows.Next() {
-		u := &User{}
-		rows.Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined)
-		users = append(users, u)
+	if seed == 0 {
+		for i := range variants {
+			variants[i].Label = variants[i].Letter
+		}
+		return variants
 	}
-	return users
-}
-
-func countNonStaffUsers() int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM users WHERE is_staff=FALSE").Scan(&count)
-	return count
-}
-
-func deleteUser(id int) error {
-	_, err := db.Exec("DELETE FROM users WHERE id=$1 AND is_staff=FALSE", id)
-	return err
-}
-
-func usernameExists(username string, excludeID int) bool {
-	var count int
-	if excludeID > 0 {
-		db.QueryRow("SELECT COUNT(*) FROM users WHERE username=$1 AND id!=$2", username, excludeID).Scan(&count)
-	} else {
-		db.QueryRow("SELECT COUNT(*) FROM users WHERE username=$1", username).Scan(&count)
+	r := mrand.New(mrand.NewPCG(uint64(seed), uint64(q.ID)))
+	for i := len(variants) - 1; i > 0; i-- {
+		j := int(r.Uint64() % uint64(i+1))
+		variants[i], variants[j] = variants[j], variants[i]
 	}
-	return count > 0
-}
-
-func scanQuestion(row interface{ Scan(...interface{}) error }) *Question {
-	q := &Question{}
-	var image sql.NullString
-	err := row.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
-		&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt)
-	if err != nil {
-		return nil
+	if q.IsOrdering() && len(variants) > 1 && slices.EqualFunc(variants, q.VariantsList, func(a, b Variant) bool { return a.Letter == b.Letter }) {
+		variants = append(variants[1:], variants[0])
 	}
-	if image.Valid {
-		q.Image = image.String
+	for i := range variants {
+		variants[i].Label = string(rune('A' + i))
 	}
-	q.ComputeVariants()
-	return q
+	return variants
 }
 
-func getAllQuestions() []*Question {
-	rows, err := db.Query("SELECT id, number, text, image, variants_json, correct_answer, variant_a, variant_b, variant_c, variant_d, created_at, updated_at FROM questions ORDER BY number")
-	if err != nil {
-		log.Printf("Error getting questions: %v", err)
-		return nil
-	}
-	defer rows.Close()
-	var questions []*Question
-	for rows.Next() {
-		q := &Question{}
-		var image sql.NullString
-		rows.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
-			&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt)
-		if image.Valid {
-			q.Image = image.String
+// CanonicalLetter maps a letter the student saw back to the stored variant
+// letter, or "" if no variant has that label.
+func (s *TestSession) CanonicalLetter(q *Question, label string) string {
+	for _, v := range s.DisplayVariants(q) {
+		if v.Label == label {
+			return v.Letter
 		}
-		q.ComputeVariants()
-		questions = append(questions, q)
 	}
-	return questions
-}
-
-func getQuestionByID(id int) *Question {
-	row := db.QueryRow("SELECT id, number, text, image, variants_json, correct_answer, variant_a, variant_b, variant_c, variant_d, created_at, updated_at FROM questions WHERE id=$1", id)
-	return scanQuestion(row)
-}
-
-func countQuestions() int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM questions").Scan(&count)
-	return count
+	return ""
 }
 
-func getNextQuestionNumber() int {
-	var maxNum sql.NullInt64
-	db.QueryRow("SELECT MAX(number) FROM questions").Scan(&maxNum)
-	if maxNum.Valid {
-		return int(maxNum.Int64) + 1
+// DisplayLetter maps a stored variant letter to the letter shown in this
+// session.
+func (s *TestSession) DisplayLetter(q *Question, letter string) string {
+	for _, v := range s.DisplayVariants(q) {
+		if v.Letter == letter {
+			return v.Label
+		}
 	}
-	return 1
+	return ""
 }
 
-func createQuestion(q *Question) error {
-	varJSON, _ := json.Marshal(q.VariantsList)
-	_, err := db.Exec(`INSERT INTO questions (number, text, image, variants_json, correct_answer, variant_a, variant_b, variant_c, variant_d) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-		q.Number, q.Text, q.Image, string(varJSON), q.CorrectAnswer,
-		q.VariantA, q.VariantB, q.VariantC, q.VariantD)
-	return err
+// Stale reports whether an open session should be closed: its time is up,
+// or it has no deadline and was abandoned long ago.
+func (s *TestSession) Stale(now time.Time) bool {
+	if s.Expired(now) {
+		return true
+	}
+	return s.Deadline().IsZero() && now.Sub(s.StartedAt) > staleSessionAge
 }
 
-func updateQuestion(q *Question) error {
-	varJSON, _ := json.Marshal(q.VariantsList)
-	_, err := db.Exec(`UPDATE questions SET text=$1, image=$2, variants_json=$3, correct_answer=$4, 
-		variant_a=$5, variant_b=$6, variant_c=$7, variant_d=$8, updated_at=NOW() WHERE id=$9`,
-		q.Text, q.Image, string(varJSON), q.CorrectAnswer,
-		q.VariantA, q.VariantB, q.VariantC, q.VariantD, q.ID)
-	return err
+// Finish records the final counts and points, measures the time spent on the
+// server and stores the exam verdict. Partly correct answers add to the points
+// but count as mistakes.
+func (s *TestSession) Finish(correct, wrong int, points float64, now time.Time) {
+	s.CorrectAnswers = correct
+	s.WrongAnswers = wrong
+	s.Points = points
+	s.Completed = true
+	if !s.StartedAt.IsZero() {
+		s.TimeSpent = int(now.Sub(s.StartedAt).Seconds())
+		if s.TimeLimit > 0 && s.TimeSpent > s.TimeLimit {
+			s.TimeSpent = s.TimeLimit
+		}
+	}
+	s.Passed = s.IsExam() && wrong <= s.MaxMistakes
+	s.CalcScorePercent()
 }
 
-func deleteQuestion(id int) error {
-	_, err := db.Exec("DELETE FROM questions WHERE id=$1", id)
-	return err
+func (s *TestSession) TicketPassed() bool {
+	return s.Completed && s.WrongAnswers <= ticketMaxMistakes
 }
 
-func searchQuestions(query string) []*Question {
-	searchPattern := "%" + strings.ToLower(query) + "%"
-	rows, err := db.Query(`SELECT id, number, text, image, variants_json, correct_answer, 
-		variant_a, variant_b, variant_c, variant_d, created_at, updated_at 
-		FROM questions WHERE LOWER(text) LIKE $1 OR LOWER(variant_a) LIKE $1 OR LOWER(variant_b) LIKE $1 
-		OR LOWER(variant_c) LIKE $1 OR LOWER(variant_d) LIKE $1 OR CAST(number AS TEXT) LIKE $1
-		ORDER BY number`, searchPattern)
-	if err != nil {
-		log.Printf("Error searching questions: %v", err)
-		return nil
-	}
-	defer rows.Close()
-	var questions []*Question
-	for rows.Next() {
-		q := &Question{}
-		var image sql.NullString
-		rows.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
-			&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt)
-		if image.Valid {
-			q.Image = image.String
-		}
-		q.ComputeVariants()
-		questions = append(questions, q)
+func (s *TestSession) CalcScorePercent() {
+	if s.TotalQuestions == 0 {
+		s.ScorePercent = 0
+		return
 	}
-	return questions
+	s.ScorePercent = int(s.Points / float64(s.TotalQuestions) * 100)
 }
 
-func getQuestionsByIDs(ids []int) map[int]*Question {
-	if len(ids) == 0 {
-		return make(map[int]*Question)
-	}
-	placeholders := make([]string, len(ids))
-	args := make([]interface{}, len(ids))
-	for i, id := range ids {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		args[i] = id
+func authenticateUser(username, password string) *User {
+	u := db.GetUserByUsername(username)
+	if u == nil {
+		return nil
 	}
-	query := fmt.Sprintf(`SELECT id, number, text, image, variants_json, correct_answer, 
-		variant_a, variant_b, variant_c, variant_d, created_at, updated_at 
-		FROM questions WHERE id IN (%s)`, strings.Join(placeholders, ","))
-	rows, err := db.Query(query, args...)
+	err := bcrypt.CompareHashAndPassword([]byte(u.PassHash), []byte(password))
 	if err != nil {
-		log.Printf("Error getting questions by IDs: %v", err)
-		return make(map[int]*Question)
-	}
-	defer rows.Close()
-	result := make(map[int]*Question)
-	for rows.Next() {
-		q := &Question{}
-		var image sql.NullString
-		rows.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
-			&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt)
-		if image.Valid {
-			q.Image = image.String
-		}
-		q.ComputeVariants()
-		result[q.ID] = q
+		return nil
 	}
-	return result
+	return u
 }
 
-func getRandomQuestionIDs(limit int) []int {
-	rows, err := db.Query("SELECT id FROM questions ORDER BY RANDOM() LIMIT $1", limit)
+// createUser adds an account; role is one of staffRoles for staff and
+// empty for students.
+func createUser(username, password, role string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil
-	}
-	defer rows.Close()
-	var ids []int
-	for rows.Next() {
-		var id int
-		rows.Scan(&id)
-		ids = append(ids, id)
+		return err
 	}
-	return ids
+	return db.CreateUser(username, string(hash), role)
 }
 
-func getUserBookmarkIDs(userID int) map[int]bool {
-	rows, err := db.Query("SELECT question_id FROM bookmarks WHERE user_id=$1", userID)
+func updateUserPassword(id int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return make(map[int]bool)
-	}
-	defer rows.Close()
-	result := make(map[int]bool)
-	for rows.Next() {
-		var qid int
-		rows.Scan(&qid)
-		result[qid] = true
+		return err
 	}
-	return result
+	return db.UpdateUserPassword(id, string(hash))
 }
 
-func toggleBookmark(userID, questionID int) string {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE user_id=$1 AND question_id=$2", userID, questionID).Scan(&count)
-	if count > 0 {
-		db.Exec("DELETE FROM bookmarks WHERE user_id=$1 AND question_id=$2", userID, questionID)
-		return "removed"
+// getExamRules reads the exam settings, falling back to the defaults for
+// anything unset or invalid.
+func getExamRules() ExamRules {
+	rules := defaultExamRules
+	settings := db.GetSettings()
+	if n, err := strconv.Atoi(settings[settingExamQuestionCount]); err == nil && n > 0 {
+		rules.QuestionCount = n
 	}
-	db.Exec("INSERT INTO bookmarks (user_id, question_id) VALUES ($1, $2)", userID, questionID)
-	return "added"
-}
-
-func getBookmarkedQuestions(userID int) []*Question {
-	rows, err := db.Query(`SELECT q.id, q.number, q.text, q.image, q.variants_json, q.correct_answer, 
-		q.variant_a, q.variant_b, q.variant_c, q.variant_d, q.created_at, q.updated_at 
-		FROM questions q JOIN bookmarks b ON q.id = b.question_id 
-		WHERE b.user_id=$1 ORDER BY q.number`, userID)
-	if err != nil {
-		return nil
+	if n, err := strconv.Atoi(settings[settingExamTimeLimit]); err == nil && n > 0 {
+		rules.TimeLimit = n
 	}
-	defer rows.Close()
-	var questions []*Question
-	for rows.Next() {
-		q := &Question{}
-		var image sql.NullString
-		rows.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
-			&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt)
-		if image.Valid {
-			q.Image = image.String
-		}
-		q.ComputeVariants()
-		questions = append(questions, q)
+	if n, err := strconv.Atoi(settings[settingExamMaxMistakes]); err == nil && n >= 0 {
+		rules.MaxMistakes = n
 	}
-	return questions
-}
-
-func countBookmarks(userID int) int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE user_id=$1", userID).Scan(&count)
-	return count
-}
-
-func createTestSession(userID, totalQuestions int, questionIDs []int) (*TestSession, error) {
-	idsJSON, _ := json.Marshal(questionIDs)
-	var id int
-	err := db.QueryRow(`INSERT INTO test_sessions (user_id, total_questions, question_ids) 
-		VALUES ($1, $2, $3) RETURNING id`, userID, totalQuestions, string(idsJSON)).Scan(&id)
-	if err != nil {
-		return nil, err
+	if v, err := strconv.ParseBool(settings[settingExamHideFeedback]); err == nil {
+		rules.HideFeedback = v
 	}
-	return &TestSession{
-		ID:             id,
-		UserID:         userID,
-		TotalQuestions: totalQuestions,
-		QuestionIDs:    string(idsJSON),
-	}, nil
-}
-
-func getTestSession(id, userID int) *TestSession {
-	s := &TestSession{}
-	err := db.QueryRow(`SELECT id, user_id, total_questions, correct_answers, wrong_answers, 
-		time_spent, completed, question_ids, created_at FROM test_sessions WHERE id=$1 AND user_id=$2`,
-		id, userID).Scan(&s.ID, &s.UserID, &s.TotalQuestions, &s.CorrectAnswers, &s.WrongAnswers,
-		&s.TimeSpent, &s.Completed, &s.QuestionIDs, &s.CreatedAt)
-	if err != nil {
-		return nil
+	if v, err := strconv.ParseBool(settings[settingExamShuffle]); err == nil {
+		rules.ShuffleVariants = v
 	}
-	s.CalcScorePercent()
-	return s
+	return rules
 }
 
-func updateTestSession(s *TestSession) error {
-	_, err := db.Exec(`UPDATE test_sessions SET correct_answers=$1, wrong_answers=$2, time_spent=$3, completed=$4 WHERE id=$5`,
-		s.CorrectAnswers, s.WrongAnswers, s.TimeSpent, s.Completed, s.ID)
-	return err
-}
-
-func getUserCompletedSessions(userID int) []*TestSession {
-	rows, err := db.Query(`SELECT id, user_id, total_questions, correct_answers, wrong_answers, 
-		time_spent, completed, question_ids, created_at FROM test_sessions 
-		WHERE user_id=$1 AND completed=TRUE ORDER BY created_at DESC`, userID)
-	if err != nil {
-		return nil
-	}
-	defer rows.Close()
-	var sessions []*TestSession
-	for rows.Next() {
-		s := &TestSession{}
-		rows.Scan(&s.ID, &s.UserID, &s.TotalQuestions, &s.CorrectAnswers, &s.WrongAnswers,
-			&s.TimeSpent, &s.Completed, &s.QuestionIDs, &s.CreatedAt)
-		s.CalcScorePercent()
-		sessions = append(sessions, s)
+func getMistakesClearStreak() int {
+	if n, err := strconv.Atoi(db.GetSettings()[settingMistakesClearStreak]); err == nil && n > 0 {
+		return n
 	}
-	return sessions
+	return defaultMistakesClearStreak
 }
 
-func countUserCompletedSessions(userID int) int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM test_sessions WHERE user_id=$1 AND completed=TRUE", userID).Scan(&count)
-	return count
-}
+// defaultTrashRetentionDays is how long deleted questions and users stay in
+// the trash before they are purged for good.
+const defaultTrashRetentionDays = 30
 
-func getRecentCompletedSessions(limit int) []*TestSession {
-	rows, err := db.Query(`SELECT ts.id, ts.user_id, ts.total_questions, ts.correct_answers, ts.wrong_answers, 
-		ts.time_spent, ts.completed, ts.question_ids, ts.created_at, u.username 
-		FROM test_sessions ts JOIN users u ON ts.user_id = u.id 
-		WHERE ts.completed=TRUE ORDER BY ts.created_at DESC LIMIT $1`, limit)
-	if err != nil {
-		return nil
+func getTrashRetentionDays() int {
+	if n, err := strconv.Atoi(db.GetSettings()[settingTrashRetentionDays]); err == nil && n > 0 {
+		return n
 	}
-	defer rows.Close()
-	var sessions []*TestSession
-	for rows.Next() {
-		s := &TestSession{}
-		rows.Scan(&s.ID, &s.UserID, &s.TotalQuestions, &s.CorrectAnswers, &s.WrongAnswers,
-			&s.TimeSpent, &s.Completed, &s.QuestionIDs, &s.CreatedAt, &s.Username)
-		s.CalcScorePercent()
-		sessions = append(sessions, s)
-	}
-	return sessions
+	return defaultTrashRetentionDays
 }
 
-func countCompletedSessions() int {
-	var count int
-	db.QueryRow("SELECT COUNT(*) FROM test_sessions WHERE completed=TRUE").Scan(&count)
-	return count
+// showPracticeExplanations reports whether explanations appear right after
+// answering outside exams. It is on unless an admin turned it off.
+func showPracticeExplanations() bool {
+	v, err := strconv.ParseBool(db.GetSettings()[settingPracticeExplanations])
+	return err != nil || v
 }
 
-func createTestAnswer(sessionID, questionID int, selectedAnswer string, isCorrect bool) error {
-	_, err := db.Exec("INSERT INTO test_answers (session_id, question_id, selected_answer, is_correct) VALUES ($1, $2, $3, $4)",
-		sessionID, questionID, selectedAnswer, isCorrect)
-	return err
+func saveExamRules(rules ExamRules) error {
+	return db.SaveSettings(map[string]string{
+		settingExamQuestionCount: strconv.Itoa(rules.QuestionCount),
+		settingExamTimeLimit:     strconv.Itoa(rules.TimeLimit),
+		settingExamMaxMistakes:   strconv.Itoa(rules.MaxMistakes),
+		settingExamHideFeedback:  strconv.FormatBool(rules.HideFeedback),
+		settingExamShuffle:       strconv.FormatBool(rules.ShuffleVariants),
+	})
 }
 
-func getSessionAnswers(sessionID int) []*TestAnswer {
-	rows, err := db.Query(`SELECT ta.id, ta.session_id, ta.question_id, ta.selected_answer, ta.is_correct,
-		q.id, q.number, q.text, q.image, q.variants_json, q.correct_answer, 
-		q.variant_a, q.variant_b, q.variant_c, q.variant_d, q.created_at, q.updated_at
-		FROM test_answers ta JOIN questions q ON ta.question_id = q.id 
-		WHERE ta.session_id=$1 ORDER BY ta.id`, sessionID)
-	if err != nil {
-		return nil
-	}
-	defer rows.Close()
-	var answers []*TestAnswer
-	for rows.Next() {
-		a := &TestAnswer{Question: &Question{}}
-		var image sql.NullString
-		rows.Scan(&a.ID, &a.SessionID, &a.QuestionID, &a.SelectedAnswer, &a.IsCorrect,
-			&a.Question.ID, &a.Question.Number, &a.Question.Text, &image, &a.Question.VariantsJSON,
-			&a.Question.CorrectAnswer, &a.Question.VariantA, &a.Question.VariantB,
-			&a.Question.VariantC, &a.Question.VariantD, &a.Question.CreatedAt, &a.Question.UpdatedAt)
-		if image.Valid {
-			a.Question.Image = image.String
-		}
-		a.Question.ComputeVariants()
-		answers = append(answers, a)
-	}
-	return answers
+// newShuffleSeed returns a random nonzero seed for TestSession.ShuffleSeed.
+func newShuffleSeed() int64 {
+	return int64(getRandomInt(math.MaxInt32)) + 1
 }
 
 func getRandomInt(max int) int {
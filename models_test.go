@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPracticeSessionHasNoDeadline(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := newTestSession(1, 20)
+	s.StartedAt = start
+	if !s.Deadline().IsZero() {
+		t.Fatalf("practice deadline %v, want none", s.Deadline())
+	}
+	if s.Expired(start.Add(2 * time.Hour)) {
+		t.Fatal("practice session expired")
+	}
+	if s.Stale(start.Add(2 * time.Hour)) {
+		t.Fatal("practice session left for two hours is stale")
+	}
+	if !s.Stale(start.Add(staleSessionAge + time.Minute)) {
+		t.Fatal("practice session abandoned for a day is not stale")
+	}
+
+	// Practice sessions saved with a per-question limit stay untimed.
+	s.TimeLimit = 20 * 60
+	if s.Expired(start.Add(time.Hour)) {
+		t.Fatal("practice session with a stored time limit expired")
+	}
+}
+
+func TestExamSessionDeadline(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := &TestSession{Mode: sessionModeExam, TimeLimit: 25 * 60, StartedAt: start}
+	if want := start.Add(25 * time.Minute); !s.Deadline().Equal(want) {
+		t.Fatalf("deadline %v, want %v", s.Deadline(), want)
+	}
+	if got := s.RemainingSeconds(start.Add(10 * time.Minute)); got != 15*60 {
+		t.Fatalf("remaining %d s, want %d", got, 15*60)
+	}
+	if s.Expired(start.Add(25*time.Minute + submitGracePeriod/2)) {
+		t.Fatal("expired within the grace period")
+	}
+	if !s.Expired(start.Add(25*time.Minute + submitGracePeriod + time.Second)) {
+		t.Fatal("not expired after the grace period")
+	}
+	if !s.Stale(start.Add(time.Hour)) {
+		t.Fatal("expired exam is not stale")
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Question types. How CorrectAnswer is read depends on the type:
+//
+//	single    the one correct variant letter, "B"
+//	multiple  every correct letter in variant order, "AC"
+//	ordering  all letters in the right order, "CAB"
+//	hotspot   unused; the answer is a click inside one of the Hotspots
+//
+// Answers are stored the same way, a hotspot answer as the click "x,y" in
+// percent of the image size.
+const (
+	questionSingle   = "single"
+	questionMultiple = "multiple"
+	questionOrdering = "ordering"
+	questionHotspot  = "hotspot"
+)
+
+var questionTypes = []string{questionSingle, questionMultiple, questionOrdering, questionHotspot}
+
+// Hotspot is a rectangle on the question image. Coordinates are percent of
+// the image width and height, so they hold at any display size.
+type Hotspot struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Point is where a hotspot question was answered, in percent like Hotspot.
+type Point struct {
+	X, Y float64
+}
+
+func (h Hotspot) Contains(p Point) bool {
+	return p.X >= h.X && p.X <= h.X+h.W && p.Y >= h.Y && p.Y <= h.Y+h.H
+}
+
+func (h Hotspot) String() string {
+	return formatPercent(h.X) + "," + formatPercent(h.Y) + "," + formatPercent(h.W) + "," + formatPercent(h.H)
+}
+
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// parseHotspotsJSON reads the hotspots_json column.
+func parseHotspotsJSON(s string) []Hotspot {
+	var hotspots []Hotspot
+	if s != "" && s != "[]" {
+		json.Unmarshal([]byte(s), &hotspots)
+	}
+	return hotspots
+}
+
+func hotspotsJSON(hotspots []Hotspot) string {
+	if len(hotspots) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(hotspots)
+	return string(b)
+}
+
+// formatHotspotList writes hotspots the way the editor and CSV files take
+// them: "x,y,w,h" rectangles separated by semicolons.
+func formatHotspotList(hotspots []Hotspot) string {
+	var parts []string
+	for _, h := range hotspots {
+		parts = append(parts, h.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseHotspotList reads formatHotspotList's format; newlines work as
+// separators too. Every rectangle has to lie within the image.
+func parseHotspotList(s string) ([]Hotspot, error) {
+	var hotspots []Hotspot
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ",")
+		if len(fields) != 4 {
+			return nil, newMessageError("error.hotspot_format", part)
+		}
+		var v [4]float64
+		for i, f := range fields {
+			n, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil || n < 0 || n > 100 {
+				return nil, newMessageError("error.hotspot_format", part)
+			}
+			v[i] = n
+		}
+		h := Hotspot{X: v[0], Y: v[1], W: v[2], H: v[3]}
+		if h.W <= 0 || h.H <= 0 || h.X+h.W > 100 || h.Y+h.H > 100 {
+			return nil, newMessageError("error.hotspot_format", part)
+		}
+		hotspots = append(hotspots, h)
+	}
+	return hotspots, nil
+}
+
+// parsePoint reads a hotspot answer, "x,y" in percent.
+func parsePoint(s string) (Point, bool) {
+	xs, ys, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, false
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if errX != nil || errY != nil || x < 0 || x > 100 || y < 0 || y > 100 {
+		return Point{}, false
+	}
+	return Point{X: x, Y: y}, true
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%.1f,%.1f", p.X, p.Y)
+}
+
+// QuestionType is Type with questions saved before there were types
+// counted as single choice.
+func (q *Question) QuestionType() string {
+	if q.Type == "" {
+		return questionSingle
+	}
+	return q.Type
+}
+
+func (q *Question) IsSingle() bool   { return q.QuestionType() == questionSingle }
+func (q *Question) IsMultiple() bool { return q.QuestionType() == questionMultiple }
+func (q *Question) IsOrdering() bool { return q.QuestionType() == questionOrdering }
+func (q *Question) IsHotspot() bool  { return q.QuestionType() == questionHotspot }
+
+// IsCorrectLetter reports whether the variant is one to choose in a single
+// or multiple choice question.
+func (q *Question) IsCorrectLetter(letter string) bool {
+	if q.IsOrdering() || q.IsHotspot() || len(letter) != 1 {
+		return false
+	}
+	return strings.Contains(q.CorrectAnswer, letter)
+}
+
+// VariantsInOrder returns q's variants ordered by letters, a sequence of
+// stored letters such as an ordering answer; variants it leaves out follow
+// in their usual order.
+func (q *Question) VariantsInOrder(letters string) []Variant {
+	variants := slices.Clone(q.VariantsList)
+	rank := func(v Variant) int {
+		if i := strings.Index(letters, v.Letter); i >= 0 && v.Letter != "" {
+			return i
+		}
+		return len(letters)
+	}
+	slices.SortStableFunc(variants, func(a, b Variant) int { return rank(a) - rank(b) })
+	return variants
+}
+
+// CorrectPosition is the 1-based place of a variant in the right order of
+// an ordering question.
+func (q *Question) CorrectPosition(letter string) int {
+	return strings.Index(q.CorrectAnswer, letter) + 1
+}
+
+// HotspotList is the hotspots as the editor shows them.
+func (q *Question) HotspotList() string {
+	return formatHotspotList(q.Hotspots)
+}
+
+// Credit scores an answer given in stored letters from 0 to 1. Only 1
+// counts as a correct answer; anything less is a mistake that still adds
+// its share to the score:
+//
+//	single, hotspot  all or nothing
+//	multiple         each correct letter chosen earns 1/n of the n correct
+//	                 ones and each wrong letter chosen takes as much away,
+//	                 down to 0
+//	ordering         the share of variants put in their right place
+func (q *Question) Credit(answer string) float64 {
+	if answer == "" {
+		return 0
+	}
+	switch q.QuestionType() {
+	case questionMultiple:
+		if len(q.CorrectAnswer) == 0 {
+			return 0
+		}
+		score := 0
+		for _, c := range answer {
+			if strings.ContainsRune(q.CorrectAnswer, c) {
+				score++
+			} else {
+				score--
+			}
+		}
+		return max(0, float64(score)/float64(len(q.CorrectAnswer)))
+	case questionOrdering:
+		if len(answer) != len(q.CorrectAnswer) || len(answer) == 0 {
+			return 0
+		}
+		inPlace := 0
+		for i := range answer {
+			if answer[i] == q.CorrectAnswer[i] {
+				inPlace++
+			}
+		}
+		return float64(inPlace) / float64(len(answer))
+	case questionHotspot:
+		p, ok := parsePoint(answer)
+		if !ok {
+			return 0
+		}
+		for _, h := range q.Hotspots {
+			if h.Contains(p) {
+				return 1
+			}
+		}
+		return 0
+	}
+	if answer == q.CorrectAnswer {
+		return 1
+	}
+	return 0
+}
+
+// normalizeAnswerKey brings CorrectAnswer into the form its type uses and
+// checks it against the variants: ordering questions take the variants'
+// own order, multiple choice letters are sorted, hotspot questions need an
+// image and at least one hotspot instead of variants.
+func (q *Question) normalizeAnswerKey() error {
+	letters := ""
+	for _, v := range q.VariantsList {
+		letters += v.Letter
+	}
+	switch q.QuestionType() {
+	case questionSingle:
+		if len(q.CorrectAnswer) != 1 || !strings.Contains(letters, q.CorrectAnswer) {
+			return newMessageError("error.correct_answer_invalid")
+		}
+	case questionMultiple:
+		key := ""
+		for _, c := range letters {
+			if strings.ContainsRune(strings.ToUpper(q.CorrectAnswer), c) {
+				key += string(c)
+			}
+		}
+		if key == "" {
+			return newMessageError("error.correct_answer_invalid")
+		}
+		q.CorrectAnswer = key
+	case questionOrdering:
+		if len(q.VariantsList) < 2 {
+			return newMessageError("error.ordering_variants")
+		}
+		q.CorrectAnswer = letters
+	case questionHotspot:
+		if q.Image == "" {
+			return newMessageError("error.hotspot_image")
+		}
+		if len(q.Hotspots) == 0 {
+			return newMessageError("error.hotspot_missing")
+		}
+		q.CorrectAnswer = ""
+		q.VariantsList = nil
+	default:
+		return newMessageError("error.question_type", q.Type)
+	}
+	if !q.IsHotspot() {
+		q.Hotspots = nil
+	}
+	return nil
+}
+
+// CanonicalAnswer turns an answer as posted from the test page, in the
+// letters this session shows, into stored letters, or "" if it is not a
+// valid answer to q.
+func (s *TestSession) CanonicalAnswer(q *Question, posted string) string {
+	posted = strings.ToUpper(strings.TrimSpace(posted))
+	switch q.QuestionType() {
+	case questionHotspot:
+		p, ok := parsePoint(posted)
+		if !ok {
+			return ""
+		}
+		return p.String()
+	case questionSingle:
+		return s.CanonicalLetter(q, posted)
+	}
+	chosen := make(map[string]bool)
+	letters := ""
+	for _, label := range strings.Split(strings.ReplaceAll(posted, ",", ""), "") {
+		letter := s.CanonicalLetter(q, label)
+		if letter == "" || chosen[letter] {
+			return ""
+		}
+		chosen[letter] = true
+		letters += letter
+	}
+	if q.IsOrdering() {
+		if len(letters) != len(q.VariantsList) {
+			return ""
+		}
+		return letters
+	}
+	sorted := ""
+	for _, v := range q.VariantsList {
+		if chosen[v.Letter] {
+			sorted += v.Letter
+		}
+	}
+	return sorted
+}
+
+// DisplayAnswer is the inverse of CanonicalAnswer: a stored answer in the
+// letters this session shows. Multiple choice letters come out sorted.
+func (s *TestSession) DisplayAnswer(q *Question, stored string) string {
+	switch q.QuestionType() {
+	case questionHotspot:
+		return stored
+	case questionSingle:
+		return s.DisplayLetter(q, stored)
+	}
+	var labels []string
+	for _, c := range stored {
+		labels = append(labels, s.DisplayLetter(q, string(c)))
+	}
+	if q.IsMultiple() {
+		slices.Sort(labels)
+	}
+	return strings.Join(labels, "")
+}
+
+// IsPartial reports whether the answer earned some credit without being
+// fully correct.
+func (a *TestAnswer) IsPartial() bool {
+	return !a.IsCorrect && a.Credit > 0
+}
+
+// CreditPercent is the credit as a whole percentage.
+func (a *TestAnswer) CreditPercent() int {
+	return int(a.Credit*100 + 0.5)
+}
+
+// Chose reports whether a single or multiple choice answer includes the
+// variant.
+func (a *TestAnswer) Chose(letter string) bool {
+	return len(letter) == 1 && a.Question != nil && !a.Question.IsOrdering() && !a.Question.IsHotspot() &&
+		strings.Contains(a.SelectedAnswer, letter)
+}
+
+// Click is where a hotspot question was answered.
+func (a *TestAnswer) Click() *Point {
+	if p, ok := parsePoint(a.SelectedAnswer); ok {
+		return &p
+	}
+	return nil
+}
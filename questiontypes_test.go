@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func testVariants(letters string) []Variant {
+	var variants []Variant
+	for _, c := range letters {
+		variants = append(variants, Variant{Letter: string(c), Text: "variant " + string(c)})
+	}
+	return variants
+}
+
+func TestCredit(t *testing.T) {
+	single := &Question{CorrectAnswer: "B", VariantsList: testVariants("ABCD")}
+	multiple := &Question{Type: questionMultiple, CorrectAnswer: "ACD", VariantsList: testVariants("ABCDE")}
+	ordering := &Question{Type: questionOrdering, CorrectAnswer: "ABCD", VariantsList: testVariants("ABCD")}
+	hotspot := &Question{Type: questionHotspot, Image: "questions/x.jpg",
+		Hotspots: []Hotspot{{X: 10, Y: 10, W: 20, H: 20}, {X: 60, Y: 50, W: 10, H: 10}}}
+
+	tests := []struct {
+		name   string
+		q      *Question
+		answer string
+		want   float64
+	}{
+		{"single right", single, "B", 1},
+		{"single wrong", single, "A", 0},
+		{"single empty", single, "", 0},
+		{"multiple all", multiple, "ACD", 1},
+		{"multiple one of three", multiple, "A", 1.0 / 3},
+		{"multiple two right one wrong", multiple, "ABC", 1.0 / 3},
+		{"multiple one right two wrong", multiple, "ABE", 0},
+		{"multiple everything", multiple, "ABCDE", 1.0 / 3},
+		{"ordering right", ordering, "ABCD", 1},
+		{"ordering two swapped", ordering, "ABDC", 0.5},
+		{"ordering reversed", ordering, "DCBA", 0},
+		{"ordering too short", ordering, "ABC", 0},
+		{"hotspot inside", hotspot, "15.0,25.0", 1},
+		{"hotspot second area", hotspot, "65,55", 1},
+		{"hotspot on the edge", hotspot, "30,30", 1},
+		{"hotspot outside", hotspot, "50,50", 0},
+		{"hotspot garbage", hotspot, "A", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Credit(tt.answer); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Credit(%q) = %v, want %v", tt.name, tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAnswerKey(t *testing.T) {
+	tests := []struct {
+		q       Question
+		want    string
+		wantErr bool
+	}{
+		{Question{CorrectAnswer: "C", VariantsList: testVariants("ABC")}, "C", false},
+		{Question{CorrectAnswer: "D", VariantsList: testVariants("ABC")}, "", true},
+		{Question{CorrectAnswer: "AB", VariantsList: testVariants("ABC")}, "", true},
+		{Question{Type: questionMultiple, CorrectAnswer: "ca", VariantsList: testVariants("ABC")}, "AC", false},
+		{Question{Type: questionMultiple, CorrectAnswer: "XY", VariantsList: testVariants("ABC")}, "", true},
+		{Question{Type: questionOrdering, CorrectAnswer: "", VariantsList: testVariants("ABC")}, "ABC", false},
+		{Question{Type: questionOrdering, VariantsList: testVariants("A")}, "", true},
+		{Question{Type: questionHotspot, Hotspots: []Hotspot{{X: 1, Y: 1, W: 5, H: 5}}}, "", true},
+		{Question{Type: questionHotspot, Image: "q.jpg"}, "", true},
+		{Question{Type: "essay", CorrectAnswer: "A", VariantsList: testVariants("AB")}, "", true},
+	}
+	for i, tt := range tests {
+		err := tt.q.normalizeAnswerKey()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%d: error %v, want error %v", i, err, tt.wantErr)
+			continue
+		}
+		if err == nil && tt.q.CorrectAnswer != tt.want {
+			t.Errorf("%d: answer key %q, want %q", i, tt.q.CorrectAnswer, tt.want)
+		}
+	}
+
+	// A hotspot question keeps only its areas.
+	q := &Question{Type: questionHotspot, Image: "q.jpg", CorrectAnswer: "A", VariantsList: testVariants("AB"),
+		Hotspots: []Hotspot{{X: 1, Y: 1, W: 5, H: 5}}}
+	if err := q.normalizeAnswerKey(); err != nil || q.CorrectAnswer != "" || q.VariantsList != nil {
+		t.Errorf("hotspot question normalized to %q %v (%v)", q.CorrectAnswer, q.VariantsList, err)
+	}
+}
+
+func TestParseHotspotList(t *testing.T) {
+	hotspots, err := parseHotspotList("10,20,30,40; 0,0,100,100\n5.5, 6, 7, 8")
+	if err != nil || len(hotspots) != 3 || hotspots[2] != (Hotspot{X: 5.5, Y: 6, W: 7, H: 8}) {
+		t.Fatalf("got %v, %v", hotspots, err)
+	}
+	if got := formatHotspotList(hotspots); got != "10,20,30,40; 0,0,100,100; 5.5,6,7,8" {
+		t.Errorf("formatted as %q", got)
+	}
+	for _, bad := range []string{"1,2,3", "a,b,c,d", "90,0,20,10", "0,0,0,10", "-1,0,5,5"} {
+		if _, err := parseHotspotList(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestSessionAnswerLetters(t *testing.T) {
+	s := &TestSession{ID: 7, ShuffleSeed: 42}
+	multiple := &Question{ID: 3, Type: questionMultiple, CorrectAnswer: "BD", VariantsList: testVariants("ABCD")}
+	ordering := &Question{ID: 4, Type: questionOrdering, CorrectAnswer: "ABCD", VariantsList: testVariants("ABCD")}
+
+	// Answering with the labels shown for the right variants is right.
+	correct := s.DisplayAnswer(multiple, "BD")
+	if got := s.CanonicalAnswer(multiple, correct); got != "BD" {
+		t.Errorf("multiple: shown %q, stored %q", correct, got)
+	}
+	if got := s.CanonicalAnswer(multiple, "A,A"); got != "" {
+		t.Errorf("repeated letter stored as %q", got)
+	}
+	shown := s.DisplayAnswer(ordering, "ABCD")
+	if got := s.CanonicalAnswer(ordering, shown); got != "ABCD" || ordering.Credit(got) != 1 {
+		t.Errorf("ordering: shown %q, stored %q", shown, got)
+	}
+	if got := s.CanonicalAnswer(ordering, "ABC"); got != "" {
+		t.Errorf("incomplete order stored as %q", got)
+	}
+
+	// An ordering question never shows its variants in the right order,
+	// even without a shuffle seed.
+	plain := &TestSession{ID: 1}
+	var order string
+	for _, v := range plain.DisplayVariants(ordering) {
+		order += v.Letter
+	}
+	if order == ordering.CorrectAnswer {
+		t.Errorf("ordering question shown in its right order %q", order)
+	}
+	if got := plain.CanonicalAnswer(&Question{CorrectAnswer: "B", VariantsList: testVariants("AB")}, "b"); got != "B" {
+		t.Errorf("unshuffled single answer stored as %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// renumberPlan works out the numbers of a bank reordered as order, a list
+// of live question ids. They take 1, 2, ... in that order; live questions
+// order leaves out follow in their current order, and trashed questions
+// come last, keeping numbers of their own so that they can be restored.
+// It returns the new number of every question whose number changes.
+func renumberPlan(questions []*Question, order []int) map[int]int {
+	questions = slices.Clone(questions)
+	sort.Slice(questions, func(i, j int) bool { return questions[i].Number < questions[j].Number })
+	live := make(map[int]*Question)
+	for _, q := range questions {
+		if q.DeletedAt.IsZero() {
+			live[q.ID] = q
+		}
+	}
+
+	var sequence []*Question
+	placed := make(map[int]bool)
+	for _, id := range order {
+		if q := live[id]; q != nil && !placed[id] {
+			placed[id] = true
+			sequence = append(sequence, q)
+		}
+	}
+	for _, q := range questions {
+		if live[q.ID] != nil && !placed[q.ID] {
+			sequence = append(sequence, q)
+		}
+	}
+	for _, q := range questions {
+		if live[q.ID] == nil {
+			sequence = append(sequence, q)
+		}
+	}
+
+	plan := make(map[int]int)
+	for i, q := range sequence {
+		if q.Number != i+1 {
+			plan[q.ID] = i + 1
+		}
+	}
+	return plan
+}
+
+// resortTicket returns a ticket's question ids sorted by their new numbers
+// if they were sorted by the old ones, so that tickets cut from the bank
+// in order keep following it. Tickets put together by hand keep their
+// order; ok is false for them and for tickets whose order stays.
+func resortTicket(ids []int, numbers, plan map[int]int) ([]int, bool) {
+	renumbered := func(id int) int {
+		if n, ok := plan[id]; ok {
+			return n
+		}
+		return numbers[id]
+	}
+	if !slices.IsSortedFunc(ids, func(a, b int) int { return numbers[a] - numbers[b] }) {
+		return nil, false
+	}
+	sorted := slices.Clone(ids)
+	slices.SortFunc(sorted, func(a, b int) int { return renumbered(a) - renumbered(b) })
+	if slices.Equal(sorted, ids) {
+		return nil, false
+	}
+	return sorted, true
+}
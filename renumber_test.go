@@ -0,0 +1,104 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenumberPlan(t *testing.T) {
+	trashed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	questions := []*Question{
+		{ID: 10, Number: 1},
+		{ID: 11, Number: 2},
+		{ID: 12, Number: 4, DeletedAt: trashed},
+		{ID: 13, Number: 5},
+		{ID: 14, Number: 7},
+	}
+	tests := []struct {
+		name  string
+		order []int
+		want  map[int]int
+	}{
+		{"gaps close, trash goes last", nil, map[int]int{13: 3, 14: 4, 12: 5}},
+		{"full order", []int{14, 13, 11, 10}, map[int]int{14: 1, 13: 2, 11: 3, 10: 4, 12: 5}},
+		{"partial order puts the rest after it", []int{13}, map[int]int{13: 1, 10: 2, 11: 3, 14: 4, 12: 5}},
+		{"unknown, trashed and repeated ids are ignored", []int{99, 12, 11, 11}, map[int]int{11: 1, 10: 2, 13: 3, 14: 4, 12: 5}},
+	}
+	for _, tt := range tests {
+		if got := renumberPlan(questions, tt.order); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: plan %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if questions[0].ID != 10 || questions[0].Number != 1 {
+		t.Error("renumberPlan changed its input")
+	}
+	if plan := renumberPlan([]*Question{{ID: 1, Number: 1}, {ID: 2, Number: 2}}, []int{1, 2}); len(plan) != 0 {
+		t.Errorf("bank already in order: plan %v", plan)
+	}
+}
+
+func TestResortTicket(t *testing.T) {
+	numbers := map[int]int{1: 1, 2: 2, 3: 3, 4: 4}
+	plan := map[int]int{1: 3, 3: 1}
+	if got, ok := resortTicket([]int{1, 2, 3}, numbers, plan); !ok || !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("bank-ordered ticket: %v, %v", got, ok)
+	}
+	if _, ok := resortTicket([]int{2, 1, 3}, numbers, plan); ok {
+		t.Error("hand-ordered ticket resorted")
+	}
+	if _, ok := resortTicket([]int{2, 4}, numbers, plan); ok {
+		t.Error("ticket whose order stays reported as changed")
+	}
+}
+
+func TestRenumberQuestions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		var ids []int
+		for n := 1; n <= 4; n++ {
+			q := &Question{Number: n * 10, Text: "Question", CorrectAnswer: "A",
+				VariantsList: []Variant{{Letter: "A", Text: "Yes"}, {Letter: "B", Text: "No"}}}
+			if err := db.CreateQuestion(q, 0); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, q.ID)
+		}
+		if err := db.DeleteQuestion(ids[1], 0); err != nil {
+			t.Fatal(err)
+		}
+		inOrder := &Ticket{Number: 1, QuestionIDs: []int{ids[0], ids[2], ids[3]}}
+		byHand := &Ticket{Number: 2, QuestionIDs: []int{ids[3], ids[0]}}
+		for _, ticket := range []*Ticket{inOrder, byHand} {
+			if err := db.CreateTicket(ticket); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		changed, err := db.RenumberQuestions([]int{ids[3], ids[0], ids[2]}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != 4 {
+			t.Errorf("%d questions renumbered, want 4", changed)
+		}
+		want := map[int]int{ids[3]: 1, ids[0]: 2, ids[2]: 3}
+		for _, q := range db.GetAllQuestions() {
+			if q.Number != want[q.ID] {
+				t.Errorf("question %d numbered %d, want %d", q.ID, q.Number, want[q.ID])
+			}
+		}
+		if trashed := db.GetDeletedQuestions(); len(trashed) != 1 || trashed[0].Number != 4 {
+			t.Errorf("trashed question numbered %d, want 4", trashed[0].Number)
+		}
+		if got := db.GetTicketByID(inOrder.ID).QuestionIDs; !slices.Equal(got, []int{ids[3], ids[0], ids[2]}) {
+			t.Errorf("bank-ordered ticket %v", got)
+		}
+		if got := db.GetTicketByID(byHand.ID).QuestionIDs; !slices.Equal(got, []int{ids[3], ids[0]}) {
+			t.Errorf("hand-ordered ticket %v", got)
+		}
+		if revs := db.GetQuestionRevisions(ids[3]); len(revs) != 2 || revs[0].Question.Number != 1 {
+			t.Errorf("renumbered question has %d revisions", len(revs))
+		}
+	})
+}
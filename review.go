@@ -0,0 +1,137 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// Question statuses. A question is written as a draft, goes to review,
+// is published by a reviewer and may later be retired. Students are only
+// ever served published questions; tests started before a question left
+// that state still show it.
+const (
+	questionDraft     = "draft"
+	questionInReview  = "review"
+	questionPublished = "published"
+	questionRetired   = "retired"
+)
+
+var questionStatuses = []string{questionDraft, questionInReview, questionPublished, questionRetired}
+
+// Staff roles. Authors write questions and send them to review; reviewers
+// publish, return or retire them but cannot publish their own; admins do
+// both and manage staff.
+const (
+	roleAdmin    = "admin"
+	roleAuthor   = "author"
+	roleReviewer = "reviewer"
+)
+
+var staffRoles = []string{roleAdmin, roleAuthor, roleReviewer}
+
+// Review actions, kept with the question as its review log. Returning a
+// question to its author needs a comment saying why.
+const (
+	reviewSubmit  = "submit"
+	reviewApprove = "approve"
+	reviewReturn  = "return"
+	reviewRetire  = "retire"
+	reviewReopen  = "reopen"
+	reviewComment = "comment"
+)
+
+// QuestionReview is one entry of a question's review log: a status change
+// or a comment.
+type QuestionReview struct {
+	ID         int
+	QuestionID int
+	UserID     int
+	Username   string
+	Action     string
+	Comment    string
+	CreatedAt  time.Time
+}
+
+// QuestionStatus is Status with questions saved before there was a review
+// workflow counted as published.
+func (q *Question) QuestionStatus() string {
+	if q.Status == "" {
+		return questionPublished
+	}
+	return q.Status
+}
+
+func (q *Question) IsPublished() bool { return q.QuestionStatus() == questionPublished }
+
+func (u *User) IsAdmin() bool   { return u.Role == roleAdmin }
+func (u *User) CanWrite() bool  { return u.Role == roleAdmin || u.Role == roleAuthor }
+func (u *User) CanReview() bool { return u.Role == roleAdmin || u.Role == roleReviewer }
+
+// reviewStatus is the status a review action moves q to and whether u may
+// take it now. A comment leaves the status as it is.
+func reviewStatus(u *User, q *Question, action string) (string, bool) {
+	status := q.QuestionStatus()
+	switch action {
+	case reviewComment:
+		return status, u.IsStaff
+	case reviewSubmit:
+		return questionInReview, u.CanWrite() && status == questionDraft
+	case reviewApprove:
+		own := q.AuthorID == u.ID && !u.IsAdmin()
+		pending := status == questionInReview || (status == questionDraft && u.IsAdmin())
+		return questionPublished, u.CanReview() && pending && !own
+	case reviewReturn:
+		return questionDraft, u.CanReview() && status == questionInReview
+	case reviewRetire:
+		return questionRetired, u.CanReview() && status == questionPublished
+	case reviewReopen:
+		return questionDraft, u.CanWrite() && status == questionRetired
+	}
+	return status, false
+}
+
+// reviewActions lists the status changes u may make to q, in the order the
+// editor offers them.
+func reviewActions(u *User, q *Question) []string {
+	var actions []string
+	for _, a := range []string{reviewSubmit, reviewApprove, reviewReturn, reviewRetire, reviewReopen} {
+		if _, ok := reviewStatus(u, q, a); ok {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+// savedStatus is the status a question in status current (empty for a new
+// one) takes when u saves it from the editor. choice is the button used:
+// "review" sends it to review and "publish" lets an admin publish it
+// directly. Otherwise a new question is a draft, and a published one edited
+// by anyone but an admin goes back to review, so that unchecked changes do
+// not reach students.
+func savedStatus(u *User, current, choice string) string {
+	switch {
+	case choice == "publish" && u.IsAdmin():
+		return questionPublished
+	case choice == "review" && slices.Contains([]string{"", questionDraft, questionPublished}, current):
+		return questionInReview
+	case current == "":
+		return questionDraft
+	case current == questionPublished && !u.IsAdmin():
+		return questionInReview
+	}
+	return current
+}
+
+// statusAction is the review action logged when saving from the editor or
+// an import moves a question to status.
+func statusAction(status string) string {
+	switch status {
+	case questionPublished:
+		return reviewApprove
+	case questionRetired:
+		return reviewRetire
+	case questionDraft:
+		return reviewReturn
+	}
+	return reviewSubmit
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestUpdateQuestionWithReview(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		q := &Question{Number: 1, Text: "Stop sign?", CorrectAnswer: "A", Status: questionPublished,
+			VariantsList: []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}}}
+		if err := db.CreateQuestion(q, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		// Without a review the status stays, whatever q says.
+		q.Text, q.Status = "Stop sign? (v2)", questionDraft
+		if err := db.UpdateQuestion(q, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := db.GetQuestionByID(q.ID); got.Text != q.Text || !got.IsPublished() {
+			t.Fatalf("plain update: %q %s", got.Text, got.Status)
+		}
+
+		q.Text, q.Status = "Stop sign? (v3)", questionInReview
+		review := &QuestionReview{QuestionID: q.ID, Action: reviewSubmit}
+		if err := db.UpdateQuestion(q, 0, review); err != nil {
+			t.Fatal(err)
+		}
+		if got := db.GetQuestionByID(q.ID); got.Text != q.Text || got.Status != questionInReview {
+			t.Fatalf("update sent to review: %q %s", got.Text, got.Status)
+		}
+		if reviews := db.GetQuestionReviews(q.ID); len(reviews) != 1 || reviews[0].Action != reviewSubmit {
+			t.Errorf("review log %+v", reviews)
+		}
+		if n := len(db.SearchQuestions(QuestionFilter{Status: questionPublished})); n != 0 {
+			t.Errorf("%d published questions, want 0", n)
+		}
+
+		if err := db.ReviewQuestion(&QuestionReview{QuestionID: q.ID, Action: reviewApprove}, questionPublished); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.RestoreQuestionRevision(q.ID, 1, 0, &QuestionReview{QuestionID: q.ID, Action: reviewSubmit}, questionInReview); err != nil {
+			t.Fatal(err)
+		}
+		if got := db.GetQuestionByID(q.ID); got.Text != "Stop sign?" || got.Status != questionInReview {
+			t.Fatalf("restore sent to review: %q %s", got.Text, got.Status)
+		}
+		if reviews := db.GetQuestionReviews(q.ID); len(reviews) != 3 {
+			t.Errorf("%d review entries, want 3", len(reviews))
+		}
+	})
+}
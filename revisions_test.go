@@ -0,0 +1,87 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	got := diffWords("Stop at the red light", "Stop before the red  light now")
+	want := []wordDiff{{"Stop", ""}, {"at", "del"}, {"before", "add"}, {"the red light", ""}, {"now", "add"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("diff %v, want %v", got, want)
+	}
+	if got := diffWords("", "New text"); !slices.Equal(got, []wordDiff{{"New text", "add"}}) {
+		t.Errorf("diff from nothing %v", got)
+	}
+}
+
+func TestRevisionChanges(t *testing.T) {
+	prev := &Question{Number: 1, Text: "Stop sign?", CategoryID: 1, CorrectAnswer: "A",
+		VariantsList: []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}}}
+	cur := *prev
+	cur.CategoryID, cur.CorrectAnswer = 2, "B"
+	cur.VariantsList = []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Halt"}, {Letter: "C", Text: "Wait"}}
+	var fields []string
+	for _, c := range revisionChanges(prev, &cur, map[int]string{1: "Signs", 2: "Rules"}) {
+		fields = append(fields, c.Field+c.Letter)
+	}
+	if want := []string{"revision.category", "revision.correct_answer", "revision.variantB", "revision.variantC"}; !slices.Equal(fields, want) {
+		t.Errorf("changes %v, want %v", fields, want)
+	}
+	if changes := revisionChanges(prev, prev, nil); len(changes) != 0 {
+		t.Errorf("unchanged question has changes %+v", changes)
+	}
+	if changes := revisionChanges(nil, prev, nil); len(changes) == 0 || changes[0].Field != "revision.number" {
+		t.Errorf("first revision changes %+v", changes)
+	}
+}
+
+func TestQuestionRevisions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		q := &Question{Number: 1, Text: "Stop sign?", CorrectAnswer: "A",
+			VariantsList: []Variant{{Letter: "A", Text: "Stop"}, {Letter: "B", Text: "Go"}}}
+		if err := db.CreateQuestion(q, 0); err != nil {
+			t.Fatal(err)
+		}
+		q.Text, q.CorrectAnswer = "Which sign means stop?", "B"
+		q.VariantsList = []Variant{{Letter: "A", Text: "Go"}, {Letter: "B", Text: "Stop"}}
+		if err := db.UpdateQuestion(q, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.DeleteQuestion(q.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		revisions := db.GetQuestionRevisions(q.ID)
+		var actions []string
+		for _, rev := range revisions {
+			actions = append(actions, rev.Action)
+		}
+		if want := []string{revisionDelete, revisionUpdate, revisionCreate}; !slices.Equal(actions, want) {
+			t.Fatalf("revisions %v, want %v newest first", actions, want)
+		}
+		if first := db.GetQuestionRevision(q.ID, 1); first == nil || first.Question.Text != "Stop sign?" || first.Question.CorrectAnswer != "A" {
+			t.Fatalf("revision 1: %+v", first)
+		}
+
+		// Restoring a revision of a deleted question brings it back under
+		// its id and number, as a new revision.
+		if err := db.RestoreQuestionRevision(q.ID, 1, 0, nil, ""); err != nil {
+			t.Fatal(err)
+		}
+		got := db.GetQuestionByID(q.ID)
+		if got == nil || got.Number != 1 || got.Text != "Stop sign?" || got.CorrectAnswer != "A" || got.VariantsList[0].Text != "Stop" {
+			t.Fatalf("restored question %+v", got)
+		}
+		if got.Revision != 4 || db.GetQuestionRevisions(q.ID)[0].Action != revisionRestore {
+			t.Errorf("restored at revision %d", got.Revision)
+		}
+		if err := db.RestoreQuestionRevision(q.ID, 9, 0, nil, ""); err == nil {
+			t.Error("restored a revision that does not exist")
+		}
+		if recent := db.GetRecentQuestionRevisions(2); len(recent) != 2 || recent[0].Action != revisionRestore {
+			t.Errorf("recent revisions %+v", recent)
+		}
+	})
+}
@@ -43,11 +43,11 @@ function toggleBookmark(element, questionId) {
         if (data.status === 'added') {
             element.classList.remove('btn-outline');
             element.classList.add('btn-warning');
-            element.innerHTML = '<i class="fas fa-bookmark"></i> Saqlangan';
+            element.innerHTML = '<i class="fas fa-bookmark"></i> ' + MESSAGES.bookmarkSaved;
         } else {
             element.classList.remove('btn-warning');
             element.classList.add('btn-outline');
-            element.innerHTML = '<i class="fas fa-bookmark"></i> Saqlash';
+            element.innerHTML = '<i class="fas fa-bookmark"></i> ' + MESSAGES.bookmarkSave;
         }
     });
 }
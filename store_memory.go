@@ -0,0 +1,1827 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps all data in process memory. It is meant for tests and
+// demos; everything is lost when the server stops. Every getter returns
+// copies so callers can modify results without touching stored rows.
+type memoryStore struct {
+	mu sync.RWMutex
+
+	nextID     map[string]int
+	users      map[int]*User
+	questions  map[int]*Question
+	categories map[int]*Category
+	tickets    map[int]*Ticket
+	bookmarks  map[int]map[int]time.Time
+	sessions   map[int]*TestSession
+	answers    []*TestAnswer
+	settings   map[string]string
+	states     map[int]map[int]*QuestionState
+	revisions  map[int][]*QuestionRevision
+	reviews    map[int][]*QuestionReview
+	chapters   map[int]*HandbookChapter
+	articles   map[int]*HandbookArticle
+	signs      map[int]*RoadSign
+	// questionArticles maps a question id to the ids of its linked articles.
+	questionArticles map[int]map[int]bool
+	// translations maps a question id to its translations by language.
+	translations map[int]map[string]*QuestionTranslation
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		nextID:     make(map[string]int),
+		users:      make(map[int]*User),
+		questions:  make(map[int]*Question),
+		categories: make(map[int]*Category),
+		tickets:    make(map[int]*Ticket),
+		bookmarks:  make(map[int]map[int]time.Time),
+		sessions:   make(map[int]*TestSession),
+		settings:   make(map[string]string),
+		states:     make(map[int]map[int]*QuestionState),
+		revisions:  make(map[int][]*QuestionRevision),
+		reviews:    make(map[int][]*QuestionReview),
+		chapters:   make(map[int]*HandbookChapter),
+		articles:   make(map[int]*HandbookArticle),
+		signs:      make(map[int]*RoadSign),
+
+		questionArticles: make(map[int]map[int]bool),
+		translations:     make(map[int]map[string]*QuestionTranslation),
+	}
+}
+
+func (m *memoryStore) Migrate() error { return nil }
+func (m *memoryStore) Close() error   { return nil }
+
+// newID hands out SERIAL-style ids per table. Callers must hold m.mu.
+func (m *memoryStore) newID(table string) int {
+	m.nextID[table]++
+	return m.nextID[table]
+}
+
+func copyUser(u *User) *User {
+	c := *u
+	return &c
+}
+
+func copyQuestion(q *Question) *Question {
+	c := *q
+	c.Hotspots = slices.Clone(q.Hotspots)
+	c.ComputeVariants()
+	return &c
+}
+
+func copySession(s *TestSession) *TestSession {
+	c := *s
+	c.CalcScorePercent()
+	return &c
+}
+
+// liveUser returns the user unless it is missing or in the trash. Callers
+// must hold m.mu.
+func (m *memoryStore) liveUser(id int) (*User, bool) {
+	u, ok := m.users[id]
+	if !ok || !u.DeletedAt.IsZero() {
+		return nil, false
+	}
+	return u, true
+}
+
+// liveQuestion returns the question unless it is missing or in the trash.
+// Callers must hold m.mu.
+func (m *memoryStore) liveQuestion(id int) (*Question, bool) {
+	q, ok := m.questions[id]
+	if !ok || !q.DeletedAt.IsZero() {
+		return nil, false
+	}
+	return q, true
+}
+
+// liveQuestionIDs drops trashed questions from ids. Callers must hold m.mu.
+func (m *memoryStore) liveQuestionIDs(ids []int) []int {
+	return slices.DeleteFunc(ids, func(id int) bool {
+		_, ok := m.liveQuestion(id)
+		return !ok
+	})
+}
+
+// publishedQuestion is liveQuestion for the questions students are served.
+// Callers must hold m.mu.
+func (m *memoryStore) publishedQuestion(id int) (*Question, bool) {
+	q, ok := m.liveQuestion(id)
+	if !ok || !q.IsPublished() {
+		return nil, false
+	}
+	return q, true
+}
+
+// publishedQuestionIDs keeps the ids of published questions. Callers must
+// hold m.mu.
+func (m *memoryStore) publishedQuestionIDs(ids []int) []int {
+	return slices.DeleteFunc(ids, func(id int) bool {
+		_, ok := m.publishedQuestion(id)
+		return !ok
+	})
+}
+
+func sortQuestionsByNumber(questions []*Question) {
+	sort.Slice(questions, func(i, j int) bool { return questions[i].Number < questions[j].Number })
+}
+
+func (m *memoryStore) GetUserByID(id int) *User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if u, ok := m.liveUser(id); ok {
+		return copyUser(u)
+	}
+	return nil
+}
+
+func (m *memoryStore) GetUserByUsername(username string) *User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if u.Username == username && u.DeletedAt.IsZero() {
+			return copyUser(u)
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) CreateUser(username, passHash, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Username == username {
+			return fmt.Errorf("username %q already exists", username)
+		}
+	}
+	id := m.newID("users")
+	m.users[id] = &User{ID: id, Username: username, PassHash: passHash, IsStaff: role != "", Role: role, DateJoined: time.Now()}
+	return nil
+}
+
+func (m *memoryStore) UpdateUserRole(id int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.Role = role
+		u.IsStaff = role != ""
+	}
+	return nil
+}
+
+func (m *memoryStore) UpdateUserUsername(id int, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Username == username && u.ID != id {
+			return fmt.Errorf("username %q already exists", username)
+		}
+	}
+	if u, ok := m.users[id]; ok {
+		u.Username = username
+	}
+	return nil
+}
+
+func (m *memoryStore) UpdateUserPassword(id int, passHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.PassHash = passHash
+	}
+	return nil
+}
+
+func (m *memoryStore) GetNonStaffUsers() []*User {
+	return m.liveUsers(false)
+}
+
+func (m *memoryStore) GetStaffUsers() []*User {
+	return m.liveUsers(true)
+}
+
+func (m *memoryStore) liveUsers(staff bool) []*User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var users []*User
+	for _, u := range m.users {
+		if u.IsStaff == staff && u.DeletedAt.IsZero() {
+			users = append(users, copyUser(u))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (m *memoryStore) CountNonStaffUsers() int {
+	return len(m.GetNonStaffUsers())
+}
+
+func (m *memoryStore) DeleteUser(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.liveUser(id); ok && !u.IsStaff {
+		u.DeletedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *memoryStore) GetDeletedUsers() []*User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var users []*User
+	for _, u := range m.users {
+		if !u.DeletedAt.IsZero() {
+			users = append(users, copyUser(u))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DeletedAt.After(users[j].DeletedAt) })
+	return users
+}
+
+func (m *memoryStore) RestoreUser(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.DeletedAt = time.Time{}
+	}
+	return nil
+}
+
+func (m *memoryStore) PurgeUser(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok && !u.DeletedAt.IsZero() {
+		m.purgeUserLocked(id)
+	}
+	return nil
+}
+
+// purgeUserLocked removes a user and everything that belongs to them.
+// Callers must hold m.mu.
+func (m *memoryStore) purgeUserLocked(id int) {
+	delete(m.users, id)
+	delete(m.bookmarks, id)
+	delete(m.states, id)
+	for sid, s := range m.sessions {
+		if s.UserID == id {
+			m.deleteSessionLocked(sid)
+		}
+	}
+}
+
+func (m *memoryStore) deleteSessionLocked(sessionID int) {
+	delete(m.sessions, sessionID)
+	kept := m.answers[:0]
+	for _, a := range m.answers {
+		if a.SessionID != sessionID {
+			kept = append(kept, a)
+		}
+	}
+	m.answers = kept
+}
+
+func (m *memoryStore) UsernameExists(username string, excludeID int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if u.Username == username && u.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) UpdateUserContentLang(id int, lang string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.ContentLang = lang
+	}
+	return nil
+}
+
+func (m *memoryStore) UpdateUserLocale(id int, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.Locale = locale
+	}
+	return nil
+}
+
+func (m *memoryStore) GetAllQuestions() []*Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var questions []*Question
+	for _, q := range m.questions {
+		if q.DeletedAt.IsZero() {
+			questions = append(questions, copyQuestion(q))
+		}
+	}
+	sortQuestionsByNumber(questions)
+	return questions
+}
+
+func (m *memoryStore) GetQuestionByID(id int) *Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if q, ok := m.liveQuestion(id); ok {
+		return copyQuestion(q)
+	}
+	return nil
+}
+
+func (m *memoryStore) CountQuestions() int {
+	return len(m.GetAllQuestions())
+}
+
+func (m *memoryStore) CountQuestionsByStatus() map[string]int {
+	counts := make(map[string]int)
+	for _, q := range m.GetAllQuestions() {
+		counts[q.QuestionStatus()]++
+	}
+	return counts
+}
+
+func (m *memoryStore) ReviewQuestion(r *QuestionReview, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviewQuestionLocked(r, status)
+	return nil
+}
+
+func (m *memoryStore) reviewQuestionLocked(r *QuestionReview, status string) {
+	q, ok := m.liveQuestion(r.QuestionID)
+	if !ok {
+		return
+	}
+	q.Status = status
+	stored := *r
+	stored.ID = m.newID("question_reviews")
+	stored.CreatedAt = time.Now()
+	m.reviews[q.ID] = append(m.reviews[q.ID], &stored)
+}
+
+func (m *memoryStore) GetQuestionReviews(questionID int) []*QuestionReview {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var reviews []*QuestionReview
+	for _, r := range m.reviews[questionID] {
+		c := *r
+		c.Username = ""
+		if u, ok := m.users[r.UserID]; ok {
+			c.Username = u.Username
+		}
+		reviews = append(reviews, &c)
+	}
+	return reviews
+}
+
+func (m *memoryStore) GetNextQuestionNumber() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	maxNum := 0
+	for _, q := range m.questions {
+		if q.Number > maxNum {
+			maxNum = q.Number
+		}
+	}
+	return maxNum + 1
+}
+
+func (m *memoryStore) CreateQuestion(q *Question, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.questions {
+		if other.Number == q.Number {
+			return fmt.Errorf("question number %d already exists", q.Number)
+		}
+	}
+	varJSON, _ := json.Marshal(q.VariantsList)
+	q.ID = m.newID("questions")
+	stored := *q
+	stored.VariantsJSON = string(varJSON)
+	stored.VariantsList = nil
+	stored.Type = q.QuestionType()
+	stored.Status = q.QuestionStatus()
+	stored.CreatedAt = time.Now()
+	stored.UpdatedAt = stored.CreatedAt
+	stored.Revision = 1
+	q.Revision = 1
+	m.questions[q.ID] = &stored
+	m.snapshotQuestionLocked(&stored, revisionCreate, userID)
+	return nil
+}
+
+func (m *memoryStore) UpdateQuestion(q *Question, userID int, review *QuestionReview) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.liveQuestion(q.ID)
+	if !ok {
+		return nil
+	}
+	varJSON, _ := json.Marshal(q.VariantsList)
+	stored.Text = q.Text
+	stored.Image = q.Image
+	stored.VariantsJSON = string(varJSON)
+	stored.CorrectAnswer = q.CorrectAnswer
+	stored.VariantA, stored.VariantB, stored.VariantC, stored.VariantD = q.VariantA, q.VariantB, q.VariantC, q.VariantD
+	stored.CategoryID = q.CategoryID
+	stored.Explanation, stored.ExplanationImage, stored.RuleRef = q.Explanation, q.ExplanationImage, q.RuleRef
+	stored.Type, stored.Hotspots = q.QuestionType(), q.Hotspots
+	stored.UpdatedAt = time.Now()
+	stored.Revision++
+	if review != nil {
+		m.reviewQuestionLocked(review, q.QuestionStatus())
+	}
+	m.snapshotQuestionLocked(stored, revisionUpdate, userID)
+	return nil
+}
+
+func (m *memoryStore) DeleteQuestion(id, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if q, ok := m.liveQuestion(id); ok {
+		q.Revision++
+		q.DeletedAt = time.Now()
+		m.snapshotQuestionLocked(q, revisionDelete, userID)
+	}
+	return nil
+}
+
+func (m *memoryStore) GetDeletedQuestions() []*Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var questions []*Question
+	for _, q := range m.questions {
+		if !q.DeletedAt.IsZero() {
+			questions = append(questions, copyQuestion(q))
+		}
+	}
+	sort.Slice(questions, func(i, j int) bool { return questions[i].DeletedAt.After(questions[j].DeletedAt) })
+	return questions
+}
+
+func (m *memoryStore) PurgeQuestion(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if q, ok := m.questions[id]; ok && !q.DeletedAt.IsZero() {
+		m.purgeQuestionLocked(id)
+	}
+	return nil
+}
+
+func (m *memoryStore) PurgeDeleted(before time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	purged := 0
+	for id, q := range m.questions {
+		if !q.DeletedAt.IsZero() && q.DeletedAt.Before(before) {
+			m.purgeQuestionLocked(id)
+			purged++
+		}
+	}
+	for id, u := range m.users {
+		if !u.DeletedAt.IsZero() && u.DeletedAt.Before(before) {
+			m.purgeUserLocked(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *memoryStore) RenumberQuestions(order []int, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var questions []*Question
+	numbers := make(map[int]int)
+	for _, q := range m.questions {
+		questions = append(questions, q)
+		numbers[q.ID] = q.Number
+	}
+	plan := renumberPlan(questions, order)
+	for _, t := range m.tickets {
+		if sorted, ok := resortTicket(t.QuestionIDs, numbers, plan); ok {
+			t.QuestionIDs = sorted
+		}
+	}
+	for id, n := range plan {
+		q := m.questions[id]
+		q.Number = n
+		if q.DeletedAt.IsZero() {
+			q.UpdatedAt = time.Now()
+			q.Revision++
+			m.snapshotQuestionLocked(q, revisionUpdate, userID)
+		}
+	}
+	return len(plan), nil
+}
+
+func (m *memoryStore) MergeQuestions(merge *QuestionMerge, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keep, ok := m.liveQuestion(merge.KeepID)
+	if !ok {
+		return fmt.Errorf("question %d not found", merge.KeepID)
+	}
+	for _, id := range merge.MergedIDs() {
+		letters := merge.Letters[id]
+		for _, set := range m.bookmarks {
+			if t, ok := set[id]; ok {
+				if _, has := set[keep.ID]; !has {
+					set[keep.ID] = t
+				}
+				delete(set, id)
+			}
+		}
+		for _, set := range m.states {
+			if st, ok := set[id]; ok {
+				if _, has := set[keep.ID]; !has {
+					st.QuestionID = keep.ID
+					set[keep.ID] = st
+				}
+				delete(set, id)
+			}
+		}
+		for _, t := range m.tickets {
+			if slices.Contains(t.QuestionIDs, keep.ID) {
+				t.QuestionIDs = slices.DeleteFunc(t.QuestionIDs, func(qid int) bool { return qid == id })
+			} else if i := slices.Index(t.QuestionIDs, id); i >= 0 {
+				t.QuestionIDs[i] = keep.ID
+			}
+		}
+		if links := m.questionArticles[id]; links != nil {
+			if m.questionArticles[keep.ID] == nil {
+				m.questionArticles[keep.ID] = make(map[int]bool)
+			}
+			for articleID := range links {
+				m.questionArticles[keep.ID][articleID] = true
+			}
+			delete(m.questionArticles, id)
+		}
+
+		answered := make(map[int]bool)
+		for _, a := range m.answers {
+			if a.QuestionID == keep.ID {
+				answered[a.SessionID] = true
+			}
+		}
+		kept := m.answers[:0]
+		for _, a := range m.answers {
+			if a.QuestionID == id {
+				answer, ok := remapAnswer(a.SelectedAnswer, letters)
+				if answered[a.SessionID] || !ok {
+					continue
+				}
+				a.QuestionID = keep.ID
+				a.SelectedAnswer = answer
+				a.QuestionRevision = keep.Revision
+				a.Credit = keep.Credit(answer)
+				a.IsCorrect = a.Credit == 1
+				answered[a.SessionID] = true
+			}
+			kept = append(kept, a)
+		}
+		m.answers = kept
+
+		if q, ok := m.liveQuestion(id); ok {
+			q.Revision++
+			q.DeletedAt = time.Now()
+			m.snapshotQuestionLocked(q, revisionDelete, userID)
+		}
+	}
+	for _, s := range m.sessions {
+		var n int
+		s.QuestionIDs, n, _ = replaceQuestionIDs(s.QuestionIDs, merge.Letters, keep.ID)
+		if !s.Completed {
+			s.TotalQuestions = n
+		}
+	}
+	return nil
+}
+
+// purgeQuestionLocked removes a question with its history, answers and
+// every reference to it. Callers must hold m.mu.
+func (m *memoryStore) purgeQuestionLocked(id int) {
+	delete(m.questions, id)
+	delete(m.revisions, id)
+	delete(m.reviews, id)
+	delete(m.questionArticles, id)
+	delete(m.translations, id)
+	for _, set := range m.bookmarks {
+		delete(set, id)
+	}
+	for _, set := range m.states {
+		delete(set, id)
+	}
+	for _, t := range m.tickets {
+		t.QuestionIDs = slices.DeleteFunc(t.QuestionIDs, func(qid int) bool { return qid == id })
+	}
+	kept := m.answers[:0]
+	for _, a := range m.answers {
+		if a.QuestionID != id {
+			kept = append(kept, a)
+		}
+	}
+	m.answers = kept
+}
+
+// snapshotQuestionLocked records q, whose Revision has already been
+// advanced, as a revision. Callers must hold m.mu.
+func (m *memoryStore) snapshotQuestionLocked(q *Question, action string, userID int) {
+	snapshot := *q
+	snapshot.VariantsList = nil
+	m.revisions[q.ID] = append(m.revisions[q.ID], &QuestionRevision{
+		ID:         m.newID("question_revisions"),
+		QuestionID: q.ID,
+		Revision:   q.Revision,
+		Action:     action,
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+		Question:   &snapshot,
+	})
+}
+
+// copyRevision returns a copy with the username filled in. Callers must
+// hold m.mu.
+func (m *memoryStore) copyRevision(rev *QuestionRevision) *QuestionRevision {
+	c := *rev
+	c.Question = copyQuestion(rev.Question)
+	if u, ok := m.users[rev.UserID]; ok {
+		c.Username = u.Username
+	}
+	return &c
+}
+
+func (m *memoryStore) GetQuestionRevisions(questionID int) []*QuestionRevision {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []*QuestionRevision
+	revisions := m.revisions[questionID]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		result = append(result, m.copyRevision(revisions[i]))
+	}
+	return result
+}
+
+func (m *memoryStore) GetRecentQuestionRevisions(limit int) []*QuestionRevision {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []*QuestionRevision
+	for _, revisions := range m.revisions {
+		for _, rev := range revisions {
+			result = append(result, m.copyRevision(rev))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (m *memoryStore) GetQuestionRevision(questionID, revision int) *QuestionRevision {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rev := range m.revisions[questionID] {
+		if rev.Revision == revision {
+			return m.copyRevision(rev)
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) RestoreQuestionRevision(questionID, revision, userID int, review *QuestionReview, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	revisions := m.revisions[questionID]
+	var source *Question
+	for _, rev := range revisions {
+		if rev.Revision == revision {
+			source = rev.Question
+		}
+	}
+	if source == nil {
+		return fmt.Errorf("question %d has no revision %d", questionID, revision)
+	}
+	categoryID := source.CategoryID
+	if _, ok := m.categories[categoryID]; !ok {
+		categoryID = 0
+	}
+
+	q, ok := m.questions[questionID]
+	if !ok {
+		for _, other := range m.questions {
+			if other.Number == source.Number {
+				return fmt.Errorf("question number %d already exists", source.Number)
+			}
+		}
+		restored := *source
+		restored.CreatedAt = time.Now()
+		restored.Status, restored.AuthorID = questionDraft, 0
+		q = &restored
+		m.questions[questionID] = q
+	}
+	q.Text = source.Text
+	q.Image = source.Image
+	q.VariantsJSON = source.VariantsJSON
+	q.CorrectAnswer = source.CorrectAnswer
+	q.VariantA, q.VariantB, q.VariantC, q.VariantD = source.VariantA, source.VariantB, source.VariantC, source.VariantD
+	q.CategoryID = categoryID
+	q.Explanation, q.ExplanationImage, q.RuleRef = source.Explanation, source.ExplanationImage, source.RuleRef
+	q.Type, q.Hotspots = source.Type, source.Hotspots
+	q.UpdatedAt = time.Now()
+	q.DeletedAt = time.Time{}
+	q.Revision = revisions[len(revisions)-1].Revision + 1
+	if review != nil {
+		m.reviewQuestionLocked(review, status)
+	}
+	m.snapshotQuestionLocked(q, revisionRestore, userID)
+	return nil
+}
+
+// matchesFilter applies a QuestionFilter the same way the SQL backends do:
+// the query is matched against text, the legacy variant_a..d fields and the
+// question number.
+func matchesFilter(q *Question, f QuestionFilter) bool {
+	if len(f.CategoryIDs) > 0 && !slices.Contains(f.CategoryIDs, q.CategoryID) {
+		return false
+	}
+	if f.Status != "" && q.QuestionStatus() != f.Status {
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+	needle := strings.ToLower(f.Query)
+	fields := []string{q.Text, q.VariantA, q.VariantB, q.VariantC, q.VariantD, strconv.Itoa(q.Number)}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) SearchQuestions(f QuestionFilter) []*Question {
+	var result []*Question
+	for _, q := range m.GetAllQuestions() {
+		if matchesFilter(q, f) {
+			result = append(result, q)
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) GetQuestionsByIDs(ids []int) map[int]*Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[int]*Question)
+	for _, id := range ids {
+		if q, ok := m.liveQuestion(id); ok {
+			result[id] = copyQuestion(q)
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) GetRandomQuestionIDs(limit int, categoryIDs []int) []int {
+	m.mu.RLock()
+	ids := make([]int, 0, len(m.questions))
+	for id, q := range m.questions {
+		if q.DeletedAt.IsZero() && matchesFilter(q, QuestionFilter{CategoryIDs: categoryIDs, Status: questionPublished}) {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.RUnlock()
+	return pickRandomIDs(ids, limit)
+}
+
+// pickRandomIDs returns up to limit ids from ids in random order.
+func pickRandomIDs(ids []int, limit int) []int {
+	for i := len(ids) - 1; i > 0; i-- {
+		j := getRandomInt(i + 1)
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	if limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// userAnswers returns the user's real answers in the order they were given.
+// Callers must hold m.mu.
+func (m *memoryStore) userAnswers(userID int) []*TestAnswer {
+	var result []*TestAnswer
+	for _, a := range m.answers {
+		if s, ok := m.sessions[a.SessionID]; ok && s.UserID == userID && a.SelectedAnswer != "" {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) GetMistakeQuestionIDs(userID, clearStreak int) []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	streak := make(map[int]int)
+	for _, a := range m.userAnswers(userID) {
+		if !a.IsCorrect {
+			streak[a.QuestionID] = 0
+		} else if n, ok := streak[a.QuestionID]; ok {
+			streak[a.QuestionID] = n + 1
+		}
+	}
+	var ids []int
+	for qid, n := range streak {
+		if n < clearStreak {
+			ids = append(ids, qid)
+		}
+	}
+	sort.Ints(ids)
+	return m.publishedQuestionIDs(ids)
+}
+
+func (m *memoryStore) GetUnansweredQuestionIDs(userID int) []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	answered := make(map[int]bool)
+	for _, a := range m.userAnswers(userID) {
+		answered[a.QuestionID] = true
+	}
+	var questions []*Question
+	for _, q := range m.questions {
+		if !answered[q.ID] && q.DeletedAt.IsZero() && q.IsPublished() {
+			questions = append(questions, q)
+		}
+	}
+	sortQuestionsByNumber(questions)
+	ids := make([]int, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	return ids
+}
+
+func (m *memoryStore) GetWeakestQuestionIDs(userID, limit int) []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	correct := make(map[int]int)
+	total := make(map[int]int)
+	for _, a := range m.userAnswers(userID) {
+		total[a.QuestionID]++
+		if a.IsCorrect {
+			correct[a.QuestionID]++
+		}
+	}
+	ids := make([]int, 0, len(total))
+	for qid := range total {
+		ids = append(ids, qid)
+	}
+	ids = m.publishedQuestionIDs(ids)
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		ra := float64(correct[a]) / float64(total[a])
+		rb := float64(correct[b]) / float64(total[b])
+		if ra != rb {
+			return ra < rb
+		}
+		if total[a] != total[b] {
+			return total[a] > total[b]
+		}
+		return a < b
+	})
+	if limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// categoryWithCount copies c and fills QuestionCount. Callers must hold m.mu.
+func (m *memoryStore) categoryWithCount(c *Category) *Category {
+	cp := *c
+	cp.QuestionCount = 0
+	for _, q := range m.questions {
+		if q.CategoryID == c.ID && q.DeletedAt.IsZero() {
+			cp.QuestionCount++
+		}
+	}
+	return &cp
+}
+
+func copyTranslation(t *QuestionTranslation) *QuestionTranslation {
+	c := *t
+	c.VariantsList = slices.Clone(t.VariantsList)
+	return &c
+}
+
+func (m *memoryStore) GetQuestionTranslations(lang string, questionIDs []int) map[int]*QuestionTranslation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[int]*QuestionTranslation)
+	for _, id := range questionIDs {
+		if t, ok := m.translations[id][lang]; ok {
+			result[id] = copyTranslation(t)
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) SaveQuestionTranslation(t *QuestionTranslation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.questions[t.QuestionID]; !ok {
+		return fmt.Errorf("question %d not found", t.QuestionID)
+	}
+	if m.translations[t.QuestionID] == nil {
+		m.translations[t.QuestionID] = make(map[string]*QuestionTranslation)
+	}
+	c := copyTranslation(t)
+	c.UpdatedAt = time.Now()
+	m.translations[t.QuestionID][t.Lang] = c
+	return nil
+}
+
+func (m *memoryStore) DeleteQuestionTranslation(questionID int, lang string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.translations[questionID], lang)
+	return nil
+}
+
+func (m *memoryStore) GetAllCategories() []*Category {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var categories []*Category
+	for _, c := range m.categories {
+		categories = append(categories, m.categoryWithCount(c))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories
+}
+
+func (m *memoryStore) GetCategoryByID(id int) *Category {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if c, ok := m.categories[id]; ok {
+		return m.categoryWithCount(c)
+	}
+	return nil
+}
+
+func (m *memoryStore) categoryNameTaken(name string, excludeID int) bool {
+	for _, c := range m.categories {
+		if c.Name == name && c.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) CreateCategory(c *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.categoryNameTaken(c.Name, 0) {
+		return fmt.Errorf("category %q already exists", c.Name)
+	}
+	c.ID = m.newID("categories")
+	stored := *c
+	stored.CreatedAt = time.Now()
+	m.categories[c.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) UpdateCategory(c *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.categoryNameTaken(c.Name, c.ID) {
+		return fmt.Errorf("category %q already exists", c.Name)
+	}
+	if stored, ok := m.categories[c.ID]; ok {
+		stored.Name = c.Name
+		stored.Description = c.Description
+	}
+	return nil
+}
+
+func (m *memoryStore) DeleteCategory(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.categories, id)
+	for _, q := range m.questions {
+		if q.CategoryID == id {
+			q.CategoryID = 0
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) GetUserBookmarkIDs(userID int) map[int]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[int]bool)
+	for qid := range m.bookmarks[userID] {
+		if _, ok := m.publishedQuestion(qid); ok {
+			result[qid] = true
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) ToggleBookmark(userID, questionID int) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set := m.bookmarks[userID]
+	if _, ok := set[questionID]; ok {
+		delete(set, questionID)
+		return "removed"
+	}
+	if set == nil {
+		set = make(map[int]time.Time)
+		m.bookmarks[userID] = set
+	}
+	set[questionID] = time.Now()
+	return "added"
+}
+
+func (m *memoryStore) GetBookmarkedQuestions(userID int) []*Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var questions []*Question
+	for qid := range m.bookmarks[userID] {
+		if q, ok := m.publishedQuestion(qid); ok {
+			questions = append(questions, copyQuestion(q))
+		}
+	}
+	sortQuestionsByNumber(questions)
+	return questions
+}
+
+func (m *memoryStore) CountBookmarks(userID int) int {
+	return len(m.GetUserBookmarkIDs(userID))
+}
+
+func copyTicket(t *Ticket) *Ticket {
+	c := *t
+	c.QuestionIDs = slices.Clone(t.QuestionIDs)
+	c.QuestionCount = len(c.QuestionIDs)
+	return &c
+}
+
+// liveTicket copies t without its trashed questions. Callers must hold
+// m.mu.
+func (m *memoryStore) liveTicket(t *Ticket) *Ticket {
+	c := copyTicket(t)
+	c.QuestionIDs = m.liveQuestionIDs(c.QuestionIDs)
+	c.QuestionCount = len(c.QuestionIDs)
+	return c
+}
+
+func (m *memoryStore) GetAllTickets() []*Ticket {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tickets []*Ticket
+	for _, t := range m.tickets {
+		tickets = append(tickets, m.liveTicket(t))
+	}
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].Number < tickets[j].Number })
+	return tickets
+}
+
+func (m *memoryStore) GetTicketByID(id int) *Ticket {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if t, ok := m.tickets[id]; ok {
+		return m.liveTicket(t)
+	}
+	return nil
+}
+
+// createTicketLocked validates and stores t. Callers must hold m.mu.
+func (m *memoryStore) createTicketLocked(t *Ticket) error {
+	for _, other := range m.tickets {
+		if other.Number == t.Number {
+			return fmt.Errorf("ticket number %d already exists", t.Number)
+		}
+	}
+	t.ID = m.newID("tickets")
+	stored := copyTicket(t)
+	stored.CreatedAt = time.Now()
+	m.tickets[t.ID] = stored
+	return nil
+}
+
+func (m *memoryStore) CreateTicket(t *Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createTicketLocked(t)
+}
+
+func (m *memoryStore) UpdateTicket(t *Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.tickets {
+		if other.Number == t.Number && other.ID != t.ID {
+			return fmt.Errorf("ticket number %d already exists", t.Number)
+		}
+	}
+	if stored, ok := m.tickets[t.ID]; ok {
+		stored.Number = t.Number
+		stored.Title = t.Title
+		stored.QuestionIDs = slices.Clone(t.QuestionIDs)
+	}
+	return nil
+}
+
+func (m *memoryStore) deleteTicketLocked(id int) {
+	delete(m.tickets, id)
+	for _, s := range m.sessions {
+		if s.TicketID == id {
+			s.TicketID = 0
+		}
+	}
+}
+
+func (m *memoryStore) DeleteTicket(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteTicketLocked(id)
+	return nil
+}
+
+func (m *memoryStore) ReplaceAllTickets(tickets []*Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := range m.tickets {
+		m.deleteTicketLocked(id)
+	}
+	for _, t := range tickets {
+		if err := m.createTicketLocked(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) GetTicketStats(userID int) map[int]*TicketStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[int]*TicketStat)
+	for _, s := range m.completedSessions(userID) {
+		if s.TicketID == 0 {
+			continue
+		}
+		st, ok := result[s.TicketID]
+		if !ok {
+			st = &TicketStat{TicketID: s.TicketID}
+			result[s.TicketID] = st
+		}
+		st.Attempts++
+		if s.TicketPassed() {
+			st.Passed++
+		}
+		if s.ScorePercent > st.BestScore {
+			st.BestScore = s.ScorePercent
+		}
+	}
+	return result
+}
+
+func (m *memoryStore) CreateTestSession(s *TestSession, questionIDs []int) error {
+	idsJSON, _ := json.Marshal(questionIDs)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.ID = m.newID("test_sessions")
+	s.QuestionIDs = string(idsJSON)
+	s.CreatedAt = time.Now()
+	stored := *s
+	m.sessions[s.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) GetTestSession(id, userID int) *TestSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.sessions[id]; ok && s.UserID == userID {
+		return copySession(s)
+	}
+	return nil
+}
+
+func (m *memoryStore) UpdateTestSession(s *TestSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.sessions[s.ID]; ok {
+		stored.CorrectAnswers = s.CorrectAnswers
+		stored.WrongAnswers = s.WrongAnswers
+		stored.TimeSpent = s.TimeSpent
+		stored.Completed = s.Completed
+		stored.Passed = s.Passed
+		stored.Points = s.Points
+	}
+	return nil
+}
+
+// completedSessions returns completed sessions, newest first, optionally
+// restricted to one user. Callers must hold m.mu.
+func (m *memoryStore) completedSessions(userID int) []*TestSession {
+	var sessions []*TestSession
+	for _, s := range m.sessions {
+		if s.Completed && (userID == 0 || s.UserID == userID) {
+			u, ok := m.liveUser(s.UserID)
+			if !ok {
+				continue
+			}
+			c := copySession(s)
+			c.Username = u.Username
+			sessions = append(sessions, c)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].ID > sessions[j].ID
+		}
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+func (m *memoryStore) GetUserCompletedSessions(userID int) []*TestSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := m.completedSessions(userID)
+	for _, s := range sessions {
+		s.Username = ""
+	}
+	return sessions
+}
+
+func (m *memoryStore) CountUserCompletedSessions(userID int) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.completedSessions(userID))
+}
+
+func (m *memoryStore) GetRecentCompletedSessions(limit int) []*TestSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := m.completedSessions(0)
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions
+}
+
+func (m *memoryStore) CountCompletedSessions() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.completedSessions(0))
+}
+
+func (m *memoryStore) GetUnfinishedSessions(userID int) []*TestSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	answered := make(map[int]int)
+	for _, a := range m.answers {
+		answered[a.SessionID]++
+	}
+	var sessions []*TestSession
+	for _, s := range m.sessions {
+		if !s.Completed && (userID == 0 || s.UserID == userID) {
+			c := copySession(s)
+			c.AnsweredCount = answered[s.ID]
+			sessions = append(sessions, c)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID > sessions[j].ID })
+	return sessions
+}
+
+func (m *memoryStore) DeleteTestSession(id, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok && s.UserID == userID {
+		m.deleteSessionLocked(id)
+	}
+	return nil
+}
+
+func (m *memoryStore) CreateTestAnswer(sessionID, questionID int, selectedAnswer string, isCorrect bool, credit float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.answers {
+		if a.SessionID == sessionID && a.QuestionID == questionID {
+			return fmt.Errorf("question %d already answered in session %d", questionID, sessionID)
+		}
+	}
+	revision := 0
+	if q, ok := m.questions[questionID]; ok {
+		revision = q.Revision
+	}
+	m.answers = append(m.answers, &TestAnswer{
+		ID:               m.newID("test_answers"),
+		SessionID:        sessionID,
+		QuestionID:       questionID,
+		SelectedAnswer:   selectedAnswer,
+		IsCorrect:        isCorrect,
+		Credit:           credit,
+		QuestionRevision: revision,
+	})
+	return nil
+}
+
+func (m *memoryStore) GetSessionAnswers(sessionID int) []*TestAnswer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var answers []*TestAnswer
+	for _, a := range m.answers {
+		if a.SessionID != sessionID {
+			continue
+		}
+		q, ok := m.questions[a.QuestionID]
+		if !ok {
+			continue
+		}
+		c := *a
+		c.Question = copyQuestion(q)
+		for _, rev := range m.revisions[a.QuestionID] {
+			if rev.Revision == a.QuestionRevision {
+				answered := *rev.Question
+				answered.Revision = q.Revision
+				c.Question = copyQuestion(&answered)
+			}
+		}
+		answers = append(answers, &c)
+	}
+	return answers
+}
+
+func (m *memoryStore) GetSettings() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]string, len(m.settings))
+	for name, value := range m.settings {
+		result[name] = value
+	}
+	return result
+}
+
+func (m *memoryStore) SaveSettings(values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, value := range values {
+		m.settings[name] = value
+	}
+	return nil
+}
+
+func (m *memoryStore) GetQuestionState(userID, questionID int) *QuestionState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if st, ok := m.states[userID][questionID]; ok {
+		c := *st
+		return &c
+	}
+	return nil
+}
+
+func (m *memoryStore) SaveQuestionState(st *QuestionState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states[st.UserID] == nil {
+		m.states[st.UserID] = make(map[int]*QuestionState)
+	}
+	c := *st
+	m.states[st.UserID][st.QuestionID] = &c
+	return nil
+}
+
+// dueStates returns the user's states due before the given time, most
+// overdue first. Callers must hold m.mu.
+func (m *memoryStore) dueStates(userID int, before time.Time) []*QuestionState {
+	var due []*QuestionState
+	for _, st := range m.states[userID] {
+		if _, ok := m.publishedQuestion(st.QuestionID); ok && !st.DueAt.After(before) {
+			due = append(due, st)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].DueAt.Equal(due[j].DueAt) {
+			return due[i].QuestionID < due[j].QuestionID
+		}
+		return due[i].DueAt.Before(due[j].DueAt)
+	})
+	return due
+}
+
+func (m *memoryStore) GetDueQuestionIDs(userID int, before time.Time, limit int) []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ids []int
+	for _, st := range m.dueStates(userID, before) {
+		if len(ids) == limit {
+			break
+		}
+		ids = append(ids, st.QuestionID)
+	}
+	return ids
+}
+
+func (m *memoryStore) CountDueQuestions(userID int, before time.Time) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.dueStates(userID, before))
+}
+
+// articleWithChapter copies a and fills in its chapter. Callers must hold
+// m.mu.
+func (m *memoryStore) articleWithChapter(a *HandbookArticle) *HandbookArticle {
+	c := *a
+	if ch, ok := m.chapters[a.ChapterID]; ok {
+		c.ChapterNumber = ch.Number
+		c.ChapterTitle = ch.Title
+	}
+	return &c
+}
+
+func sortArticles(articles []*HandbookArticle) {
+	sort.Slice(articles, func(i, j int) bool {
+		a, b := articles[i], articles[j]
+		if a.ChapterNumber != b.ChapterNumber {
+			return a.ChapterNumber < b.ChapterNumber
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.ID < b.ID
+	})
+}
+
+// chapterWithArticles copies c with its articles. Callers must hold m.mu.
+func (m *memoryStore) chapterWithArticles(c *HandbookChapter) *HandbookChapter {
+	cp := *c
+	cp.Articles = nil
+	for _, a := range m.articles {
+		if a.ChapterID == c.ID {
+			cp.Articles = append(cp.Articles, m.articleWithChapter(a))
+		}
+	}
+	sortArticles(cp.Articles)
+	return &cp
+}
+
+func (m *memoryStore) GetHandbookChapters() []*HandbookChapter {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var chapters []*HandbookChapter
+	for _, c := range m.chapters {
+		chapters = append(chapters, m.chapterWithArticles(c))
+	}
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].Number < chapters[j].Number })
+	return chapters
+}
+
+func (m *memoryStore) GetHandbookChapter(id int) *HandbookChapter {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if c, ok := m.chapters[id]; ok {
+		return m.chapterWithArticles(c)
+	}
+	return nil
+}
+
+func (m *memoryStore) chapterNumberTaken(number, excludeID int) bool {
+	for _, c := range m.chapters {
+		if c.Number == number && c.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) CreateHandbookChapter(c *HandbookChapter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chapterNumberTaken(c.Number, 0) {
+		return fmt.Errorf("chapter number %d already exists", c.Number)
+	}
+	c.ID = m.newID("handbook_chapters")
+	stored := *c
+	stored.Articles = nil
+	stored.CreatedAt = time.Now()
+	m.chapters[c.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) UpdateHandbookChapter(c *HandbookChapter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chapterNumberTaken(c.Number, c.ID) {
+		return fmt.Errorf("chapter number %d already exists", c.Number)
+	}
+	if stored, ok := m.chapters[c.ID]; ok {
+		stored.Number = c.Number
+		stored.Title = c.Title
+	}
+	return nil
+}
+
+func (m *memoryStore) DeleteHandbookChapter(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.chapters, id)
+	for _, a := range m.articles {
+		if a.ChapterID == id {
+			m.deleteArticleLocked(a.ID)
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) GetHandbookArticle(id int) *HandbookArticle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if a, ok := m.articles[id]; ok {
+		return m.articleWithChapter(a)
+	}
+	return nil
+}
+
+func (m *memoryStore) CreateHandbookArticle(a *HandbookArticle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chapters[a.ChapterID]; !ok {
+		return fmt.Errorf("chapter %d not found", a.ChapterID)
+	}
+	a.ID = m.newID("handbook_articles")
+	stored := *a
+	stored.CreatedAt = time.Now()
+	stored.UpdatedAt = stored.CreatedAt
+	m.articles[a.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) UpdateHandbookArticle(a *HandbookArticle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chapters[a.ChapterID]; !ok {
+		return fmt.Errorf("chapter %d not found", a.ChapterID)
+	}
+	if stored, ok := m.articles[a.ID]; ok {
+		stored.ChapterID = a.ChapterID
+		stored.Number = a.Number
+		stored.Title = a.Title
+		stored.Body = a.Body
+		stored.Position = a.Position
+		stored.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// deleteArticleLocked removes an article and every question link to it.
+// Callers must hold m.mu.
+func (m *memoryStore) deleteArticleLocked(id int) {
+	delete(m.articles, id)
+	for _, set := range m.questionArticles {
+		delete(set, id)
+	}
+}
+
+func (m *memoryStore) DeleteHandbookArticle(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteArticleLocked(id)
+	return nil
+}
+
+// matchesTerms reports whether every term appears in one of fields.
+func matchesTerms(terms []string, fields ...string) bool {
+	for _, t := range terms {
+		found := false
+		for _, f := range fields {
+			if strings.Contains(strings.ToLower(f), t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *memoryStore) SearchHandbookArticles(query string) []*HandbookArticle {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var articles []*HandbookArticle
+	for _, a := range m.articles {
+		if matchesTerms(terms, a.Number, a.Title, a.Body) {
+			articles = append(articles, m.articleWithChapter(a))
+		}
+	}
+	sortArticles(articles)
+	return articles
+}
+
+func (m *memoryStore) GetQuestionArticles(questionID int) []*HandbookArticle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var articles []*HandbookArticle
+	for id := range m.questionArticles[questionID] {
+		if a, ok := m.articles[id]; ok {
+			articles = append(articles, m.articleWithChapter(a))
+		}
+	}
+	sortArticles(articles)
+	return articles
+}
+
+func (m *memoryStore) SetQuestionArticles(questionID int, articleIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.questions[questionID]; !ok {
+		return fmt.Errorf("question %d not found", questionID)
+	}
+	set := make(map[int]bool)
+	for _, id := range articleIDs {
+		if _, ok := m.articles[id]; !ok {
+			return fmt.Errorf("article %d not found", id)
+		}
+		set[id] = true
+	}
+	m.questionArticles[questionID] = set
+	return nil
+}
+
+func (m *memoryStore) GetArticleQuestions(articleID int) []*Question {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var questions []*Question
+	for qid, set := range m.questionArticles {
+		if !set[articleID] {
+			continue
+		}
+		if q, ok := m.publishedQuestion(qid); ok {
+			questions = append(questions, copyQuestion(q))
+		}
+	}
+	sortQuestionsByNumber(questions)
+	return questions
+}
+
+func (m *memoryStore) GetUserQuestionStats(userID int, questionIDs []int) map[int]*QuestionStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wanted := make(map[int]bool)
+	for _, id := range questionIDs {
+		wanted[id] = true
+	}
+	result := make(map[int]*QuestionStat)
+	for _, a := range m.userAnswers(userID) {
+		if !wanted[a.QuestionID] {
+			continue
+		}
+		st, ok := result[a.QuestionID]
+		if !ok {
+			st = &QuestionStat{QuestionID: a.QuestionID}
+			result[a.QuestionID] = st
+		}
+		st.Answered++
+		if a.IsCorrect {
+			st.Correct++
+		}
+	}
+	return result
+}
+
+func copyRoadSign(rs *RoadSign) *RoadSign {
+	c := *rs
+	return &c
+}
+
+func (m *memoryStore) GetAllRoadSigns() []*RoadSign {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var signs []*RoadSign
+	for _, rs := range m.signs {
+		signs = append(signs, copyRoadSign(rs))
+	}
+	sortRoadSigns(signs)
+	return signs
+}
+
+func (m *memoryStore) GetRoadSign(id int) *RoadSign {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if rs, ok := m.signs[id]; ok {
+		return copyRoadSign(rs)
+	}
+	return nil
+}
+
+func (m *memoryStore) signCodeTaken(code string, excludeID int) bool {
+	for _, rs := range m.signs {
+		if rs.Code == code && rs.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) CreateRoadSign(rs *RoadSign) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.signCodeTaken(rs.Code, 0) {
+		return fmt.Errorf("road sign %q already exists", rs.Code)
+	}
+	rs.ID = m.newID("road_signs")
+	stored := *rs
+	stored.CreatedAt = time.Now()
+	m.signs[rs.ID] = &stored
+	return nil
+}
+
+func (m *memoryStore) UpdateRoadSign(rs *RoadSign) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.signCodeTaken(rs.Code, rs.ID) {
+		return fmt.Errorf("road sign %q already exists", rs.Code)
+	}
+	if stored, ok := m.signs[rs.ID]; ok {
+		stored.Code = rs.Code
+		stored.Name = rs.Name
+		stored.Description = rs.Description
+		stored.Image = rs.Image
+	}
+	return nil
+}
+
+func (m *memoryStore) DeleteRoadSign(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.signs, id)
+	return nil
+}
+
+func (m *memoryStore) SearchRoadSigns(query string) []*RoadSign {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var signs []*RoadSign
+	for _, rs := range m.signs {
+		if matchesTerms(terms, rs.Code, rs.Name, rs.Description) {
+			signs = append(signs, copyRoadSign(rs))
+		}
+	}
+	sortRoadSigns(signs)
+	return signs
+}
+
+func (m *memoryStore) GetMediaReferences() []*MediaReference {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var refs []*MediaReference
+	seen := make(map[MediaReference]bool)
+	add := func(p, kind string, id int, label string) {
+		ref := MediaReference{Path: p, Kind: kind, OwnerID: id, Label: label}
+		if p != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, &ref)
+		}
+	}
+	for _, q := range m.questions {
+		add(q.Image, "question", q.ID, strconv.Itoa(q.Number))
+		add(q.ExplanationImage, "question", q.ID, strconv.Itoa(q.Number))
+	}
+	for id, revisions := range m.revisions {
+		for _, rev := range revisions {
+			add(rev.Question.Image, "revision", id, strconv.Itoa(rev.Question.Number))
+			add(rev.Question.ExplanationImage, "revision", id, strconv.Itoa(rev.Question.Number))
+		}
+	}
+	for _, rs := range m.signs {
+		add(rs.Image, "sign", rs.ID, rs.Code)
+	}
+	return refs
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+func newPostgresStore(dsn string) (*sqlStore, error) {
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	return &sqlStore{db: conn, dialect: dialectPostgres}, nil
+}
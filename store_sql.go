@@ -0,0 +1,1676 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+)
+
+// sqlStore implements Store on top of database/sql. Queries are written once
+// in PostgreSQL syntax with $N placeholders, which SQLite also accepts; only
+// DDL needs rewriting per dialect (see ddl).
+type sqlStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+const questionColumns = `id, number, text, image, variants_json, correct_answer,
+		variant_a, variant_b, variant_c, variant_d, created_at, updated_at, category_id, revision, deleted_at,
+		explanation, explanation_image, rule_ref, question_type, hotspots_json, status, author_id`
+
+// liveQuestionIDs selects the ids of questions that are not in the trash.
+const liveQuestionIDs = `(SELECT id FROM questions WHERE deleted_at IS NULL)`
+
+// publishedQuestionIDs selects the ids of the questions students are
+// served: live and published.
+const publishedQuestionIDs = `(SELECT id FROM questions WHERE deleted_at IS NULL AND status = 'published')`
+
+// revisionColumns is the question content kept in question_revisions.
+const revisionColumns = `number, text, image, variants_json, correct_answer,
+		variant_a, variant_b, variant_c, variant_d, category_id,
+		explanation, explanation_image, rule_ref, question_type, hotspots_json`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// prefixScanner lets a joined row fill some leading columns before handing
+// the rest to a shared scanner such as scanQuestion.
+type prefixScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.prefix, dest...)...)
+}
+
+// prefixColumns qualifies every column in a comma separated list with alias.
+func prefixColumns(alias, cols string) string {
+	parts := strings.Split(cols, ",")
+	for i, p := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// placeholders returns "$start, $start+1, ..." for n arguments.
+func placeholders(start, n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(ph, ",")
+}
+
+func intArgs(ids []int) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// nullableID stores 0 as NULL for optional foreign keys.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// ddl adapts a PostgreSQL schema statement to the store's dialect. SQLite
+// cannot change a column's type but ignores VARCHAR lengths anyway, so
+// ALTER COLUMN ... TYPE becomes a no-op there.
+func (s *sqlStore) ddl(q string) string {
+	if s.dialect != dialectSQLite {
+		return q
+	}
+	if strings.Contains(q, " ALTER COLUMN ") && strings.Contains(q, " TYPE ") {
+		return "SELECT 1"
+	}
+	r := strings.NewReplacer(
+		"SERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"NOW()", "CURRENT_TIMESTAMP",
+	)
+	return r.Replace(q)
+}
+
+func (s *sqlStore) GetUserByID(id int) *User {
+	u := &User{}
+	err := s.db.QueryRow("SELECT id, username, password_hash, is_staff, date_joined, content_lang, locale, role FROM users WHERE id=$1 AND deleted_at IS NULL", id).
+		Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined, &u.ContentLang, &u.Locale, &u.Role)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+func (s *sqlStore) GetUserByUsername(username string) *User {
+	u := &User{}
+	err := s.db.QueryRow("SELECT id, username, password_hash, is_staff, date_joined, content_lang, locale, role FROM users WHERE username=$1 AND deleted_at IS NULL", username).
+		Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined, &u.ContentLang, &u.Locale, &u.Role)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// CreateUser adds a user; a role makes them staff.
+func (s *sqlStore) CreateUser(username, passHash, role string) error {
+	_, err := s.db.Exec("INSERT INTO users (username, password_hash, is_staff, role) VALUES ($1, $2, $3, $4)",
+		username, passHash, role != "", role)
+	return err
+}
+
+func (s *sqlStore) UpdateUserRole(id int, role string) error {
+	_, err := s.db.Exec("UPDATE users SET role=$1, is_staff=$2 WHERE id=$3", role, role != "", id)
+	return err
+}
+
+func (s *sqlStore) UpdateUserUsername(id int, username string) error {
+	_, err := s.db.Exec("UPDATE users SET username=$1 WHERE id=$2", username, id)
+	return err
+}
+
+func (s *sqlStore) UpdateUserPassword(id int, passHash string) error {
+	_, err := s.db.Exec("UPDATE users SET password_hash=$1 WHERE id=$2", passHash, id)
+	return err
+}
+
+func (s *sqlStore) UpdateUserContentLang(id int, lang string) error {
+	_, err := s.db.Exec("UPDATE users SET content_lang=$1 WHERE id=$2", lang, id)
+	return err
+}
+
+func (s *sqlStore) UpdateUserLocale(id int, locale string) error {
+	_, err := s.db.Exec("UPDATE users SET locale=$1 WHERE id=$2", locale, id)
+	return err
+}
+
+func (s *sqlStore) GetNonStaffUsers() []*User {
+	return s.queryUsers("SELECT id, username, password_hash, is_staff, date_joined, role FROM users WHERE is_staff=FALSE AND deleted_at IS NULL ORDER BY id")
+}
+
+func (s *sqlStore) GetStaffUsers() []*User {
+	return s.queryUsers("SELECT id, username, password_hash, is_staff, date_joined, role FROM users WHERE is_staff=TRUE AND deleted_at IS NULL ORDER BY id")
+}
+
+func (s *sqlStore) queryUsers(query string) []*User {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var users []*User
+	for rows.Next() {
+		u := &User{}
+		rows.Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined, &u.Role)
+		users = append(users, u)
+	}
+	return users
+}
+
+func (s *sqlStore) CountNonStaffUsers() int {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM users WHERE is_staff=FALSE AND deleted_at IS NULL").Scan(&count)
+	return count
+}
+
+func (s *sqlStore) DeleteUser(id int) error {
+	_, err := s.db.Exec("UPDATE users SET deleted_at=$1 WHERE id=$2 AND is_staff=FALSE AND deleted_at IS NULL",
+		time.Now().UTC().Truncate(time.Second), id)
+	return err
+}
+
+func (s *sqlStore) GetDeletedUsers() []*User {
+	rows, err := s.db.Query(`SELECT id, username, password_hash, is_staff, date_joined, deleted_at FROM users
+		WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		log.Printf("Error getting deleted users: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var users []*User
+	for rows.Next() {
+		u := &User{}
+		rows.Scan(&u.ID, &u.Username, &u.PassHash, &u.IsStaff, &u.DateJoined, &u.DeletedAt)
+		users = append(users, u)
+	}
+	return users
+}
+
+func (s *sqlStore) RestoreUser(id int) error {
+	_, err := s.db.Exec("UPDATE users SET deleted_at=NULL WHERE id=$1", id)
+	return err
+}
+
+// PurgeUser deletes a trashed user for good, together with their sessions
+// and bookmarks.
+func (s *sqlStore) PurgeUser(id int) error {
+	_, err := s.db.Exec("DELETE FROM users WHERE id=$1 AND deleted_at IS NOT NULL", id)
+	return err
+}
+
+func (s *sqlStore) UsernameExists(username string, excludeID int) bool {
+	var count int
+	if excludeID > 0 {
+		s.db.QueryRow("SELECT COUNT(*) FROM users WHERE username=$1 AND id!=$2", username, excludeID).Scan(&count)
+	} else {
+		s.db.QueryRow("SELECT COUNT(*) FROM users WHERE username=$1", username).Scan(&count)
+	}
+	return count > 0
+}
+
+func scanQuestion(row rowScanner) *Question {
+	q := &Question{}
+	var image sql.NullString
+	var categoryID sql.NullInt64
+	var deletedAt sql.NullTime
+	var hotspots string
+	var authorID sql.NullInt64
+	err := row.Scan(&q.ID, &q.Number, &q.Text, &image, &q.VariantsJSON, &q.CorrectAnswer,
+		&q.VariantA, &q.VariantB, &q.VariantC, &q.VariantD, &q.CreatedAt, &q.UpdatedAt, &categoryID, &q.Revision, &deletedAt,
+		&q.Explanation, &q.ExplanationImage, &q.RuleRef, &q.Type, &hotspots, &q.Status, &authorID)
+	if err != nil {
+		return nil
+	}
+	q.AuthorID = int(authorID.Int64)
+	q.Hotspots = parseHotspotsJSON(hotspots)
+	if image.Valid {
+		q.Image = image.String
+	}
+	q.CategoryID = int(categoryID.Int64)
+	q.DeletedAt = deletedAt.Time
+	q.ComputeVariants()
+	return q
+}
+
+func scanQuestionRows(rows *sql.Rows) []*Question {
+	defer rows.Close()
+	var questions []*Question
+	for rows.Next() {
+		if q := scanQuestion(rows); q != nil {
+			questions = append(questions, q)
+		}
+	}
+	return questions
+}
+
+func (s *sqlStore) GetAllQuestions() []*Question {
+	rows, err := s.db.Query("SELECT " + questionColumns + " FROM questions WHERE deleted_at IS NULL ORDER BY number")
+	if err != nil {
+		log.Printf("Error getting questions: %v", err)
+		return nil
+	}
+	return scanQuestionRows(rows)
+}
+
+func (s *sqlStore) GetQuestionByID(id int) *Question {
+	row := s.db.QueryRow("SELECT "+questionColumns+" FROM questions WHERE id=$1 AND deleted_at IS NULL", id)
+	return scanQuestion(row)
+}
+
+func (s *sqlStore) CountQuestions() int {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM questions WHERE deleted_at IS NULL").Scan(&count)
+	return count
+}
+
+func (s *sqlStore) CountQuestionsByStatus() map[string]int {
+	counts := make(map[string]int)
+	rows, err := s.db.Query("SELECT status, COUNT(*) FROM questions WHERE deleted_at IS NULL GROUP BY status")
+	if err != nil {
+		log.Printf("Error counting questions: %v", err)
+		return counts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		rows.Scan(&status, &n)
+		counts[status] = n
+	}
+	return counts
+}
+
+// ReviewQuestion logs a review entry and moves the question to status in
+// one go.
+func (s *sqlStore) ReviewQuestion(r *QuestionReview, status string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return reviewQuestionTx(tx, r, status)
+	})
+}
+
+// reviewQuestionTx is ReviewQuestion inside tx, for the saves that change a
+// question's content and status together.
+func reviewQuestionTx(tx *sql.Tx, r *QuestionReview, status string) error {
+	res, err := tx.Exec("UPDATE questions SET status=$1 WHERE id=$2 AND deleted_at IS NULL", status, r.QuestionID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	_, err = tx.Exec("INSERT INTO question_reviews (question_id, user_id, action, comment) VALUES ($1, $2, $3, $4)",
+		r.QuestionID, nullableID(r.UserID), r.Action, r.Comment)
+	return err
+}
+
+func (s *sqlStore) GetQuestionReviews(questionID int) []*QuestionReview {
+	rows, err := s.db.Query(`SELECT r.id, r.question_id, COALESCE(r.user_id, 0), COALESCE(u.username, ''), r.action, r.comment, r.created_at
+		FROM question_reviews r LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.question_id=$1 ORDER BY r.id`, questionID)
+	if err != nil {
+		log.Printf("Error getting question reviews: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var reviews []*QuestionReview
+	for rows.Next() {
+		r := &QuestionReview{}
+		rows.Scan(&r.ID, &r.QuestionID, &r.UserID, &r.Username, &r.Action, &r.Comment, &r.CreatedAt)
+		reviews = append(reviews, r)
+	}
+	return reviews
+}
+
+func (s *sqlStore) GetNextQuestionNumber() int {
+	var maxNum sql.NullInt64
+	s.db.QueryRow("SELECT MAX(number) FROM questions").Scan(&maxNum)
+	if maxNum.Valid {
+		return int(maxNum.Int64) + 1
+	}
+	return 1
+}
+
+func (s *sqlStore) CreateQuestion(q *Question, userID int) error {
+	varJSON, _ := json.Marshal(q.VariantsList)
+	return s.withTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`INSERT INTO questions (number, text, image, variants_json, correct_answer, variant_a, variant_b, variant_c, variant_d, category_id,
+			explanation, explanation_image, rule_ref, question_type, hotspots_json, status, author_id, revision)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, 1) RETURNING id`,
+			q.Number, q.Text, q.Image, string(varJSON), q.CorrectAnswer,
+			q.VariantA, q.VariantB, q.VariantC, q.VariantD, nullableID(q.CategoryID),
+			q.Explanation, q.ExplanationImage, q.RuleRef, q.QuestionType(), hotspotsJSON(q.Hotspots),
+			q.QuestionStatus(), nullableID(q.AuthorID)).Scan(&q.ID)
+		if err != nil {
+			return err
+		}
+		q.Revision = 1
+		return snapshotQuestionTx(tx, q.ID, revisionCreate, userID)
+	})
+}
+
+// UpdateQuestion saves q's content. With a review it also moves q to
+// q.Status and logs the review, in the same transaction, so that an edit
+// sent back to review is never live.
+func (s *sqlStore) UpdateQuestion(q *Question, userID int, review *QuestionReview) error {
+	varJSON, _ := json.Marshal(q.VariantsList)
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE questions SET text=$1, image=$2, variants_json=$3, correct_answer=$4,
+			variant_a=$5, variant_b=$6, variant_c=$7, variant_d=$8, category_id=$9,
+			explanation=$10, explanation_image=$11, rule_ref=$12, question_type=$13, hotspots_json=$14,
+			revision=revision+1, updated_at=CURRENT_TIMESTAMP WHERE id=$15`,
+			q.Text, q.Image, string(varJSON), q.CorrectAnswer,
+			q.VariantA, q.VariantB, q.VariantC, q.VariantD, nullableID(q.CategoryID),
+			q.Explanation, q.ExplanationImage, q.RuleRef, q.QuestionType(), hotspotsJSON(q.Hotspots), q.ID)
+		if err != nil {
+			return err
+		}
+		if review != nil {
+			if err := reviewQuestionTx(tx, review, q.QuestionStatus()); err != nil {
+				return err
+			}
+		}
+		return snapshotQuestionTx(tx, q.ID, revisionUpdate, userID)
+	})
+}
+
+// DeleteQuestion moves a question to the trash. Answers, bookmarks and
+// ticket places stay, so restoring it brings everything back.
+func (s *sqlStore) DeleteQuestion(id, userID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("UPDATE questions SET revision=revision+1, deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL",
+			time.Now().UTC().Truncate(time.Second), id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return snapshotQuestionTx(tx, id, revisionDelete, userID)
+	})
+}
+
+func (s *sqlStore) GetDeletedQuestions() []*Question {
+	rows, err := s.db.Query("SELECT " + questionColumns + " FROM questions WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		log.Printf("Error getting deleted questions: %v", err)
+		return nil
+	}
+	return scanQuestionRows(rows)
+}
+
+// PurgeQuestion deletes a trashed question for good, including its history
+// and every answer given to it.
+func (s *sqlStore) PurgeQuestion(id int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM questions WHERE id=$1 AND deleted_at IS NOT NULL", id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		if _, err := tx.Exec("DELETE FROM question_revisions WHERE question_id=$1", id); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM question_reviews WHERE question_id=$1", id)
+		return err
+	})
+}
+
+// PurgeDeleted purges every question and user trashed before the given time
+// and returns how many rows went.
+func (s *sqlStore) PurgeDeleted(before time.Time) (int, error) {
+	before = before.UTC().Truncate(time.Second)
+	purged := 0
+	err := s.withTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"question_revisions", "question_reviews"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE question_id IN
+				(SELECT id FROM questions WHERE deleted_at IS NOT NULL AND deleted_at < $1)`, before); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"questions", "users"} {
+			res, err := tx.Exec("DELETE FROM "+table+" WHERE deleted_at IS NOT NULL AND deleted_at < $1", before)
+			if err != nil {
+				return err
+			}
+			n, _ := res.RowsAffected()
+			purged += int(n)
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// MergeQuestions moves everything that points at the merged questions over
+// to the kept one and then trashes them. Where a user, session, ticket or
+// article already has the kept question, the merged one's row is dropped.
+// Moved answers are rewritten in the kept question's letters and scored
+// again against its current revision; answers that cannot be rewritten are
+// dropped. Finished tests keep the score they were given.
+func (s *sqlStore) MergeQuestions(m *QuestionMerge, userID int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		keep := scanQuestion(tx.QueryRow("SELECT "+questionColumns+" FROM questions WHERE id=$1 AND deleted_at IS NULL", m.KeepID))
+		if keep == nil {
+			return fmt.Errorf("question %d not found", m.KeepID)
+		}
+		if len(m.Letters) == 0 {
+			return nil
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		for _, id := range m.MergedIDs() {
+			letters := m.Letters[id]
+			for _, ref := range []struct{ table, owner string }{
+				{"bookmarks", "user_id"},
+				{"user_question_state", "user_id"},
+				{"ticket_questions", "ticket_id"},
+				{"question_articles", "article_id"},
+			} {
+				if _, err := tx.Exec(`UPDATE `+ref.table+` SET question_id=$1 WHERE question_id=$2
+					AND `+ref.owner+` NOT IN (SELECT `+ref.owner+` FROM `+ref.table+` WHERE question_id=$1)`, m.KeepID, id); err != nil {
+					return err
+				}
+				if _, err := tx.Exec("DELETE FROM "+ref.table+" WHERE question_id=$1", id); err != nil {
+					return err
+				}
+			}
+
+			rows, err := tx.Query("SELECT id, selected_answer FROM test_answers WHERE question_id=$1", id)
+			if err != nil {
+				return err
+			}
+			type answerRow struct {
+				id       int
+				selected string
+			}
+			var answers []answerRow
+			for rows.Next() {
+				var a answerRow
+				rows.Scan(&a.id, &a.selected)
+				answers = append(answers, a)
+			}
+			rows.Close()
+			for _, a := range answers {
+				answer, ok := remapAnswer(a.selected, letters)
+				if !ok {
+					continue
+				}
+				credit := keep.Credit(answer)
+				if _, err := tx.Exec(`UPDATE test_answers SET question_id=$1, selected_answer=$2, question_revision=$3,
+					is_correct=$4, credit=$5
+					WHERE id=$6 AND session_id NOT IN (SELECT session_id FROM test_answers WHERE question_id=$1)`,
+					m.KeepID, answer, keep.Revision, credit == 1, credit, a.id); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.Exec("DELETE FROM test_answers WHERE question_id=$1", id); err != nil {
+				return err
+			}
+
+			res, err := tx.Exec("UPDATE questions SET revision=revision+1, deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL", now, id)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				if err := snapshotQuestionTx(tx, id, revisionDelete, userID); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Unfinished tests shrink to the questions left; finished ones keep
+		// the total they were graded on. Only sessions whose JSON list
+		// names a merged question are read: with its brackets turned into
+		// commas, the list holds ",id," for each of its ids.
+		var conds []string
+		var args []interface{}
+		for id := range m.Letters {
+			args = append(args, fmt.Sprintf("%%,%d,%%", id))
+			conds = append(conds, fmt.Sprintf("REPLACE(REPLACE(question_ids, '[', ','), ']', ',') LIKE $%d", len(args)))
+		}
+		rows, err := tx.Query("SELECT id, question_ids FROM test_sessions WHERE "+strings.Join(conds, " OR "), args...)
+		if err != nil {
+			return err
+		}
+		type sessionList struct {
+			ids string
+			n   int
+		}
+		lists := make(map[int]sessionList)
+		for rows.Next() {
+			var id int
+			var list string
+			rows.Scan(&id, &list)
+			if list, n, changed := replaceQuestionIDs(list, m.Letters, m.KeepID); changed {
+				lists[id] = sessionList{list, n}
+			}
+		}
+		rows.Close()
+		for id, list := range lists {
+			if _, err := tx.Exec("UPDATE test_sessions SET question_ids=$1, total_questions=CASE WHEN completed THEN total_questions ELSE $2 END WHERE id=$3",
+				list.ids, list.n, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RenumberQuestions gives the questions the numbers renumberPlan works out
+// from order and returns how many changed. Numbers are UNIQUE, so the
+// changed questions first step aside to negative numbers and only then
+// take their new ones. Live questions get a revision for the change.
+func (s *sqlStore) RenumberQuestions(order []int, userID int) (int, error) {
+	var plan map[int]int
+	err := s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id, number, deleted_at IS NOT NULL FROM questions")
+		if err != nil {
+			return err
+		}
+		var questions []*Question
+		numbers := make(map[int]int)
+		for rows.Next() {
+			q := &Question{}
+			var deleted bool
+			if err := rows.Scan(&q.ID, &q.Number, &deleted); err != nil {
+				rows.Close()
+				return err
+			}
+			if deleted {
+				q.DeletedAt = time.Now()
+			}
+			questions = append(questions, q)
+			numbers[q.ID] = q.Number
+		}
+		rows.Close()
+		plan = renumberPlan(questions, order)
+		if len(plan) == 0 {
+			return nil
+		}
+
+		rows, err = tx.Query("SELECT ticket_id, question_id FROM ticket_questions ORDER BY ticket_id, position")
+		if err != nil {
+			return err
+		}
+		tickets := make(map[int][]int)
+		for rows.Next() {
+			var ticketID, questionID int
+			rows.Scan(&ticketID, &questionID)
+			tickets[ticketID] = append(tickets[ticketID], questionID)
+		}
+		rows.Close()
+		for ticketID, ids := range tickets {
+			sorted, ok := resortTicket(ids, numbers, plan)
+			if !ok {
+				continue
+			}
+			for i, id := range sorted {
+				if _, err := tx.Exec("UPDATE ticket_questions SET position=$1 WHERE ticket_id=$2 AND question_id=$3", i+1, ticketID, id); err != nil {
+					return err
+				}
+			}
+		}
+
+		for id := range plan {
+			if _, err := tx.Exec("UPDATE questions SET number=$1 WHERE id=$2", -id, id); err != nil {
+				return err
+			}
+		}
+		for _, q := range questions {
+			n, ok := plan[q.ID]
+			if !ok {
+				continue
+			}
+			if !q.DeletedAt.IsZero() {
+				if _, err := tx.Exec("UPDATE questions SET number=$1 WHERE id=$2", n, q.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.Exec("UPDATE questions SET number=$1, revision=revision+1, updated_at=CURRENT_TIMESTAMP WHERE id=$2", n, q.ID); err != nil {
+				return err
+			}
+			if err := snapshotQuestionTx(tx, q.ID, revisionUpdate, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(plan), nil
+}
+
+// snapshotQuestionTx copies the question row, whose revision column has
+// already been advanced, into question_revisions.
+func snapshotQuestionTx(tx *sql.Tx, questionID int, action string, userID int) error {
+	_, err := tx.Exec(`INSERT INTO question_revisions (question_id, revision, action, user_id, `+revisionColumns+`)
+		SELECT id, revision, $2, $3, number, text, COALESCE(image, ''), variants_json, correct_answer,
+		variant_a, variant_b, variant_c, variant_d, category_id,
+		explanation, explanation_image, rule_ref, question_type, hotspots_json
+		FROM questions WHERE id=$1`, questionID, action, nullableID(userID))
+	return err
+}
+
+// revisionContent receives revisionColumns from a row where they may all be
+// NULL because the revision was LEFT JOINed.
+type revisionContent struct {
+	number, categoryID                     sql.NullInt64
+	text, image, variantsJSON, correct     sql.NullString
+	variantA, variantB, variantC, variantD sql.NullString
+	explanation, explanationImage, ruleRef sql.NullString
+	questionType, hotspots                 sql.NullString
+}
+
+func (rc *revisionContent) dest() []interface{} {
+	return []interface{}{&rc.number, &rc.text, &rc.image, &rc.variantsJSON, &rc.correct,
+		&rc.variantA, &rc.variantB, &rc.variantC, &rc.variantD, &rc.categoryID,
+		&rc.explanation, &rc.explanationImage, &rc.ruleRef, &rc.questionType, &rc.hotspots}
+}
+
+// apply overwrites q's content with the revision and reports whether there
+// was one.
+func (rc *revisionContent) apply(q *Question) bool {
+	if !rc.number.Valid {
+		return false
+	}
+	q.Number = int(rc.number.Int64)
+	q.Text = rc.text.String
+	q.Image = rc.image.String
+	q.VariantsJSON = rc.variantsJSON.String
+	q.CorrectAnswer = rc.correct.String
+	q.VariantA, q.VariantB, q.VariantC, q.VariantD = rc.variantA.String, rc.variantB.String, rc.variantC.String, rc.variantD.String
+	q.CategoryID = int(rc.categoryID.Int64)
+	q.Explanation, q.ExplanationImage, q.RuleRef = rc.explanation.String, rc.explanationImage.String, rc.ruleRef.String
+	q.Type, q.Hotspots = rc.questionType.String, parseHotspotsJSON(rc.hotspots.String)
+	q.ComputeVariants()
+	return true
+}
+
+var revisionSelect = `SELECT r.id, r.question_id, r.revision, r.action, r.user_id, COALESCE(u.username, ''), r.created_at,
+		` + prefixColumns("r", revisionColumns) + `
+		FROM question_revisions r LEFT JOIN users u ON r.user_id = u.id`
+
+func scanRevision(row rowScanner) *QuestionRevision {
+	rev := &QuestionRevision{}
+	var userID sql.NullInt64
+	var content revisionContent
+	dest := append([]interface{}{&rev.ID, &rev.QuestionID, &rev.Revision, &rev.Action, &userID, &rev.Username, &rev.CreatedAt},
+		content.dest()...)
+	if err := row.Scan(dest...); err != nil {
+		return nil
+	}
+	rev.UserID = int(userID.Int64)
+	rev.Question = &Question{ID: rev.QuestionID, Revision: rev.Revision}
+	content.apply(rev.Question)
+	return rev
+}
+
+func (s *sqlStore) queryRevisions(query string, args ...interface{}) []*QuestionRevision {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error getting question revisions: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var revisions []*QuestionRevision
+	for rows.Next() {
+		if rev := scanRevision(rows); rev != nil {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions
+}
+
+func (s *sqlStore) GetQuestionRevisions(questionID int) []*QuestionRevision {
+	return s.queryRevisions(revisionSelect+" WHERE r.question_id=$1 ORDER BY r.revision DESC", questionID)
+}
+
+func (s *sqlStore) GetRecentQuestionRevisions(limit int) []*QuestionRevision {
+	return s.queryRevisions(revisionSelect+" ORDER BY r.created_at DESC, r.id DESC LIMIT $1", limit)
+}
+
+func (s *sqlStore) GetQuestionRevision(questionID, revision int) *QuestionRevision {
+	return scanRevision(s.db.QueryRow(revisionSelect+" WHERE r.question_id=$1 AND r.revision=$2", questionID, revision))
+}
+
+// RestoreQuestionRevision writes the content of an old revision back as a
+// new revision. A deleted question is recreated under its old id and number.
+// With a review it also moves the question to status, as UpdateQuestion does.
+func (s *sqlStore) RestoreQuestionRevision(questionID, revision, userID int, review *QuestionReview, status string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var c revisionContent
+		err := tx.QueryRow("SELECT "+revisionColumns+" FROM question_revisions WHERE question_id=$1 AND revision=$2",
+			questionID, revision).Scan(c.dest()...)
+		if err != nil {
+			return err
+		}
+		var latest int
+		if err := tx.QueryRow("SELECT MAX(revision) FROM question_revisions WHERE question_id=$1", questionID).Scan(&latest); err != nil {
+			return err
+		}
+		var categoryID sql.NullInt64
+		if c.categoryID.Valid {
+			tx.QueryRow("SELECT id FROM categories WHERE id=$1", c.categoryID.Int64).Scan(&categoryID)
+		}
+
+		res, err := tx.Exec(`UPDATE questions SET text=$1, image=$2, variants_json=$3, correct_answer=$4,
+			variant_a=$5, variant_b=$6, variant_c=$7, variant_d=$8, category_id=$9,
+			explanation=$10, explanation_image=$11, rule_ref=$12, question_type=$13, hotspots_json=$14,
+			revision=$15, updated_at=CURRENT_TIMESTAMP, deleted_at=NULL WHERE id=$16`,
+			c.text, c.image, c.variantsJSON, c.correct, c.variantA, c.variantB, c.variantC, c.variantD,
+			categoryID, c.explanation, c.explanationImage, c.ruleRef, c.questionType, c.hotspots, latest+1, questionID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			_, err = tx.Exec(`INSERT INTO questions (id, number, text, image, variants_json, correct_answer,
+				variant_a, variant_b, variant_c, variant_d, category_id,
+				explanation, explanation_image, rule_ref, question_type, hotspots_json, revision, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+				questionID, c.number, c.text, c.image, c.variantsJSON, c.correct,
+				c.variantA, c.variantB, c.variantC, c.variantD, categoryID,
+				c.explanation, c.explanationImage, c.ruleRef, c.questionType, c.hotspots, latest+1, questionDraft)
+			if err != nil {
+				return err
+			}
+		}
+		if review != nil {
+			if err := reviewQuestionTx(tx, review, status); err != nil {
+				return err
+			}
+		}
+		return snapshotQuestionTx(tx, questionID, revisionRestore, userID)
+	})
+}
+
+// questionFilterSQL turns a filter into WHERE conditions over the questions
+// table (aliased as alias) with placeholders starting at $start.
+func questionFilterSQL(f QuestionFilter, alias string, start int) (string, []interface{}) {
+	col := func(name string) string { return alias + "." + name }
+	var conds []string
+	var args []interface{}
+	if f.Query != "" {
+		n := fmt.Sprintf("$%d", start+len(args))
+		var like []string
+		for _, c := range []string{"text", "variant_a", "variant_b", "variant_c", "variant_d"} {
+			like = append(like, "LOWER("+col(c)+") LIKE "+n)
+		}
+		like = append(like, "CAST("+col("number")+" AS TEXT) LIKE "+n)
+		conds = append(conds, "("+strings.Join(like, " OR ")+")")
+		args = append(args, "%"+strings.ToLower(f.Query)+"%")
+	}
+	if len(f.CategoryIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("%s IN (%s)", col("category_id"), placeholders(start+len(args), len(f.CategoryIDs))))
+		args = append(args, intArgs(f.CategoryIDs)...)
+	}
+	if f.Status != "" {
+		conds = append(conds, fmt.Sprintf("%s = $%d", col("status"), start+len(args)))
+		args = append(args, f.Status)
+	}
+	conds = append(conds, col("deleted_at")+" IS NULL")
+	return strings.Join(conds, " AND "), args
+}
+
+func (s *sqlStore) SearchQuestions(f QuestionFilter) []*Question {
+	where, args := questionFilterSQL(f, "q", 1)
+	rows, err := s.db.Query(`SELECT `+prefixColumns("q", questionColumns)+`
+		FROM questions q WHERE `+where+` ORDER BY q.number`, args...)
+	if err != nil {
+		log.Printf("Error searching questions: %v", err)
+		return nil
+	}
+	return scanQuestionRows(rows)
+}
+
+func (s *sqlStore) GetQuestionsByIDs(ids []int) map[int]*Question {
+	if len(ids) == 0 {
+		return make(map[int]*Question)
+	}
+	query := fmt.Sprintf(`SELECT %s
+		FROM questions WHERE deleted_at IS NULL AND id IN (%s)`, questionColumns, placeholders(1, len(ids)))
+	rows, err := s.db.Query(query, intArgs(ids)...)
+	if err != nil {
+		log.Printf("Error getting questions by IDs: %v", err)
+		return make(map[int]*Question)
+	}
+	result := make(map[int]*Question)
+	for _, q := range scanQuestionRows(rows) {
+		result[q.ID] = q
+	}
+	return result
+}
+
+// queryIDs runs a query returning a single integer column.
+func (s *sqlStore) queryIDs(query string, args ...interface{}) []int {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying ids: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (s *sqlStore) GetRandomQuestionIDs(limit int, categoryIDs []int) []int {
+	where, args := questionFilterSQL(QuestionFilter{CategoryIDs: categoryIDs, Status: questionPublished}, "q", 2)
+	return s.queryIDs("SELECT q.id FROM questions q WHERE "+where+" ORDER BY RANDOM() LIMIT $1",
+		append([]interface{}{limit}, args...)...)
+}
+
+// userAnswersSQL selects the user's real answers (skipped questions are
+// left out) as ua(id, question_id, is_correct).
+const userAnswersSQL = `(SELECT ta.id, ta.question_id, ta.is_correct
+		FROM test_answers ta JOIN test_sessions ts ON ta.session_id = ts.id
+		WHERE ts.user_id = $1 AND ta.selected_answer != '') ua`
+
+// GetMistakeQuestionIDs returns questions the user has answered wrongly and
+// not yet answered correctly clearStreak times in a row since.
+func (s *sqlStore) GetMistakeQuestionIDs(userID, clearStreak int) []int {
+	return s.queryIDs(`SELECT w.question_id FROM (
+			SELECT ua.question_id, MAX(ua.id) AS last_wrong FROM `+userAnswersSQL+`
+			WHERE ua.is_correct = FALSE GROUP BY ua.question_id
+		) w
+		WHERE (SELECT COUNT(*) FROM `+userAnswersSQL+`
+			WHERE ua.question_id = w.question_id AND ua.id > w.last_wrong) < $2
+		AND w.question_id IN `+publishedQuestionIDs+`
+		ORDER BY w.question_id`, userID, clearStreak)
+}
+
+func (s *sqlStore) GetUnansweredQuestionIDs(userID int) []int {
+	return s.queryIDs(`SELECT q.id FROM questions q
+		WHERE q.deleted_at IS NULL AND q.status = 'published' AND q.id NOT IN (SELECT ua.question_id FROM `+userAnswersSQL+`)
+		ORDER BY q.number`, userID)
+}
+
+// GetWeakestQuestionIDs returns the user's answered questions with the
+// lowest accuracy first; ties go to the more often answered question.
+func (s *sqlStore) GetWeakestQuestionIDs(userID, limit int) []int {
+	return s.queryIDs(`SELECT ua.question_id FROM `+userAnswersSQL+`
+		WHERE ua.question_id IN `+publishedQuestionIDs+`
+		GROUP BY ua.question_id
+		ORDER BY SUM(CASE WHEN ua.is_correct THEN 1 ELSE 0 END) * 1.0 / COUNT(*), COUNT(*) DESC, ua.question_id
+		LIMIT $2`, userID, limit)
+}
+
+func (s *sqlStore) GetQuestionTranslations(lang string, questionIDs []int) map[int]*QuestionTranslation {
+	result := make(map[int]*QuestionTranslation)
+	if len(questionIDs) == 0 {
+		return result
+	}
+	rows, err := s.db.Query(`SELECT question_id, lang, text, variants_json, explanation, updated_at
+		FROM question_translations WHERE lang=$1 AND question_id IN (`+placeholders(2, len(questionIDs))+`)`,
+		append([]interface{}{lang}, intArgs(questionIDs)...)...)
+	if err != nil {
+		log.Printf("Error getting question translations: %v", err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &QuestionTranslation{}
+		var variantsJSON string
+		rows.Scan(&t.QuestionID, &t.Lang, &t.Text, &variantsJSON, &t.Explanation, &t.UpdatedAt)
+		json.Unmarshal([]byte(variantsJSON), &t.VariantsList)
+		result[t.QuestionID] = t
+	}
+	return result
+}
+
+func (s *sqlStore) SaveQuestionTranslation(t *QuestionTranslation) error {
+	varJSON, _ := json.Marshal(t.VariantsList)
+	_, err := s.db.Exec(`INSERT INTO question_translations (question_id, lang, text, variants_json, explanation, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (question_id, lang) DO UPDATE SET text = excluded.text, variants_json = excluded.variants_json,
+			explanation = excluded.explanation, updated_at = excluded.updated_at`,
+		t.QuestionID, t.Lang, t.Text, string(varJSON), t.Explanation)
+	return err
+}
+
+func (s *sqlStore) DeleteQuestionTranslation(questionID int, lang string) error {
+	_, err := s.db.Exec("DELETE FROM question_translations WHERE question_id=$1 AND lang=$2", questionID, lang)
+	return err
+}
+
+func (s *sqlStore) GetAllCategories() []*Category {
+	rows, err := s.db.Query(`SELECT c.id, c.name, c.description, c.created_at, COUNT(q.id)
+		FROM categories c LEFT JOIN questions q ON q.category_id = c.id AND q.deleted_at IS NULL
+		GROUP BY c.id, c.name, c.description, c.created_at ORDER BY c.name`)
+	if err != nil {
+		log.Printf("Error getting categories: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var categories []*Category
+	for rows.Next() {
+		c := &Category{}
+		rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.QuestionCount)
+		categories = append(categories, c)
+	}
+	return categories
+}
+
+func (s *sqlStore) GetCategoryByID(id int) *Category {
+	c := &Category{}
+	err := s.db.QueryRow(`SELECT c.id, c.name, c.description, c.created_at,
+		(SELECT COUNT(*) FROM questions q WHERE q.category_id = c.id AND q.deleted_at IS NULL)
+		FROM categories c WHERE c.id=$1`, id).
+		Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.QuestionCount)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+func (s *sqlStore) CreateCategory(c *Category) error {
+	return s.db.QueryRow("INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id",
+		c.Name, c.Description).Scan(&c.ID)
+}
+
+func (s *sqlStore) UpdateCategory(c *Category) error {
+	_, err := s.db.Exec("UPDATE categories SET name=$1, description=$2 WHERE id=$3", c.Name, c.Description, c.ID)
+	return err
+}
+
+func (s *sqlStore) DeleteCategory(id int) error {
+	_, err := s.db.Exec("DELETE FROM categories WHERE id=$1", id)
+	return err
+}
+
+func (s *sqlStore) GetUserBookmarkIDs(userID int) map[int]bool {
+	rows, err := s.db.Query("SELECT question_id FROM bookmarks WHERE user_id=$1 AND question_id IN "+publishedQuestionIDs, userID)
+	if err != nil {
+		return make(map[int]bool)
+	}
+	defer rows.Close()
+	result := make(map[int]bool)
+	for rows.Next() {
+		var qid int
+		rows.Scan(&qid)
+		result[qid] = true
+	}
+	return result
+}
+
+func (s *sqlStore) ToggleBookmark(userID, questionID int) string {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE user_id=$1 AND question_id=$2", userID, questionID).Scan(&count)
+	if count > 0 {
+		s.db.Exec("DELETE FROM bookmarks WHERE user_id=$1 AND question_id=$2", userID, questionID)
+		return "removed"
+	}
+	s.db.Exec("INSERT INTO bookmarks (user_id, question_id) VALUES ($1, $2)", userID, questionID)
+	return "added"
+}
+
+func (s *sqlStore) GetBookmarkedQuestions(userID int) []*Question {
+	rows, err := s.db.Query(`SELECT `+prefixColumns("q", questionColumns)+`
+		FROM questions q JOIN bookmarks b ON q.id = b.question_id
+		WHERE b.user_id=$1 AND q.deleted_at IS NULL AND q.status = 'published' ORDER BY q.number`, userID)
+	if err != nil {
+		return nil
+	}
+	return scanQuestionRows(rows)
+}
+
+func (s *sqlStore) CountBookmarks(userID int) int {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE user_id=$1 AND question_id IN "+publishedQuestionIDs, userID).Scan(&count)
+	return count
+}
+
+// withTx runs fn inside a transaction, committing only if it succeeds.
+func (s *sqlStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) GetAllTickets() []*Ticket {
+	rows, err := s.db.Query(`SELECT t.id, t.number, t.title, t.created_at, COUNT(tq.question_id)
+		FROM tickets t LEFT JOIN ticket_questions tq ON tq.ticket_id = t.id AND tq.question_id IN ` + liveQuestionIDs + `
+		GROUP BY t.id, t.number, t.title, t.created_at ORDER BY t.number`)
+	if err != nil {
+		log.Printf("Error getting tickets: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var tickets []*Ticket
+	for rows.Next() {
+		t := &Ticket{}
+		rows.Scan(&t.ID, &t.Number, &t.Title, &t.CreatedAt, &t.QuestionCount)
+		tickets = append(tickets, t)
+	}
+	return tickets
+}
+
+func (s *sqlStore) GetTicketByID(id int) *Ticket {
+	t := &Ticket{}
+	err := s.db.QueryRow("SELECT id, number, title, created_at FROM tickets WHERE id=$1", id).
+		Scan(&t.ID, &t.Number, &t.Title, &t.CreatedAt)
+	if err != nil {
+		return nil
+	}
+	rows, err := s.db.Query("SELECT question_id FROM ticket_questions WHERE ticket_id=$1 AND question_id IN "+liveQuestionIDs+" ORDER BY position", id)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var qid int
+		rows.Scan(&qid)
+		t.QuestionIDs = append(t.QuestionIDs, qid)
+	}
+	t.QuestionCount = len(t.QuestionIDs)
+	return t
+}
+
+func insertTicketQuestions(tx *sql.Tx, t *Ticket) error {
+	for i, qid := range t.QuestionIDs {
+		_, err := tx.Exec("INSERT INTO ticket_questions (ticket_id, question_id, position) VALUES ($1, $2, $3)",
+			t.ID, qid, i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createTicketTx(tx *sql.Tx, t *Ticket) error {
+	err := tx.QueryRow("INSERT INTO tickets (number, title) VALUES ($1, $2) RETURNING id", t.Number, t.Title).Scan(&t.ID)
+	if err != nil {
+		return err
+	}
+	return insertTicketQuestions(tx, t)
+}
+
+func (s *sqlStore) CreateTicket(t *Ticket) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return createTicketTx(tx, t)
+	})
+}
+
+func (s *sqlStore) UpdateTicket(t *Ticket) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE tickets SET number=$1, title=$2 WHERE id=$3", t.Number, t.Title, t.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM ticket_questions WHERE ticket_id=$1", t.ID); err != nil {
+			return err
+		}
+		return insertTicketQuestions(tx, t)
+	})
+}
+
+func (s *sqlStore) DeleteTicket(id int) error {
+	_, err := s.db.Exec("DELETE FROM tickets WHERE id=$1", id)
+	return err
+}
+
+// ReplaceAllTickets swaps the whole ticket set in one transaction. Sessions
+// taken on the old tickets keep their results but lose the ticket link.
+func (s *sqlStore) ReplaceAllTickets(tickets []*Ticket) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM tickets"); err != nil {
+			return err
+		}
+		for _, t := range tickets {
+			if err := createTicketTx(tx, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTicketStats aggregates completed ticket attempts, for one user or, with
+// userID 0, for everybody.
+func (s *sqlStore) GetTicketStats(userID int) map[int]*TicketStat {
+	rows, err := s.db.Query(`SELECT ticket_id, COUNT(*),
+		SUM(CASE WHEN wrong_answers <= $1 THEN 1 ELSE 0 END),
+		MAX(CASE WHEN total_questions > 0 THEN CAST(points * 100 / total_questions AS INTEGER) ELSE 0 END)
+		FROM test_sessions
+		WHERE completed=TRUE AND ticket_id IS NOT NULL AND ($2 = 0 OR user_id = $2)
+		AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		GROUP BY ticket_id`, ticketMaxMistakes, userID)
+	result := make(map[int]*TicketStat)
+	if err != nil {
+		log.Printf("Error getting ticket stats: %v", err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		st := &TicketStat{}
+		rows.Scan(&st.TicketID, &st.Attempts, &st.Passed, &st.BestScore)
+		result[st.TicketID] = st
+	}
+	return result
+}
+
+func (s *sqlStore) CreateTestSession(ts *TestSession, questionIDs []int) error {
+	idsJSON, _ := json.Marshal(questionIDs)
+	ts.QuestionIDs = string(idsJSON)
+	return s.db.QueryRow(`INSERT INTO test_sessions (user_id, total_questions, question_ids, ticket_id,
+		mode, time_limit, max_mistakes, started_at, hide_feedback, shuffle_seed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		ts.UserID, ts.TotalQuestions, ts.QuestionIDs, nullableID(ts.TicketID),
+		ts.Mode, ts.TimeLimit, ts.MaxMistakes, ts.StartedAt, ts.HideFeedback, ts.ShuffleSeed).Scan(&ts.ID)
+}
+
+const sessionColumns = `id, user_id, total_questions, correct_answers, wrong_answers,
+		time_spent, completed, question_ids, created_at, ticket_id,
+		mode, time_limit, max_mistakes, started_at, passed, hide_feedback, shuffle_seed, points`
+
+func scanSession(row rowScanner, extra ...interface{}) *TestSession {
+	s := &TestSession{}
+	var ticketID sql.NullInt64
+	var startedAt sql.NullTime
+	dest := append([]interface{}{&s.ID, &s.UserID, &s.TotalQuestions, &s.CorrectAnswers, &s.WrongAnswers,
+		&s.TimeSpent, &s.Completed, &s.QuestionIDs, &s.CreatedAt, &ticketID,
+		&s.Mode, &s.TimeLimit, &s.MaxMistakes, &startedAt, &s.Passed, &s.HideFeedback, &s.ShuffleSeed, &s.Points}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil
+	}
+	s.TicketID = int(ticketID.Int64)
+	s.StartedAt = startedAt.Time
+	s.CalcScorePercent()
+	return s
+}
+
+func (s *sqlStore) GetTestSession(id, userID int) *TestSession {
+	row := s.db.QueryRow("SELECT "+sessionColumns+" FROM test_sessions WHERE id=$1 AND user_id=$2", id, userID)
+	return scanSession(row)
+}
+
+func (s *sqlStore) UpdateTestSession(ts *TestSession) error {
+	_, err := s.db.Exec(`UPDATE test_sessions SET correct_answers=$1, wrong_answers=$2, time_spent=$3, completed=$4, passed=$5, points=$6 WHERE id=$7`,
+		ts.CorrectAnswers, ts.WrongAnswers, ts.TimeSpent, ts.Completed, ts.Passed, ts.Points, ts.ID)
+	return err
+}
+
+func (s *sqlStore) GetUserCompletedSessions(userID int) []*TestSession {
+	rows, err := s.db.Query(`SELECT `+sessionColumns+` FROM test_sessions
+		WHERE user_id=$1 AND completed=TRUE ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var sessions []*TestSession
+	for rows.Next() {
+		if ts := scanSession(rows); ts != nil {
+			sessions = append(sessions, ts)
+		}
+	}
+	return sessions
+}
+
+func (s *sqlStore) CountUserCompletedSessions(userID int) int {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM test_sessions WHERE user_id=$1 AND completed=TRUE", userID).Scan(&count)
+	return count
+}
+
+func (s *sqlStore) GetRecentCompletedSessions(limit int) []*TestSession {
+	rows, err := s.db.Query(`SELECT `+prefixColumns("ts", sessionColumns)+`, u.username
+		FROM test_sessions ts JOIN users u ON ts.user_id = u.id
+		WHERE ts.completed=TRUE AND u.deleted_at IS NULL ORDER BY ts.created_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var sessions []*TestSession
+	for rows.Next() {
+		var username string
+		if ts := scanSession(rows, &username); ts != nil {
+			ts.Username = username
+			sessions = append(sessions, ts)
+		}
+	}
+	return sessions
+}
+
+func (s *sqlStore) CountCompletedSessions() int {
+	var count int
+	s.db.QueryRow(`SELECT COUNT(*) FROM test_sessions
+		WHERE completed=TRUE AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)`).Scan(&count)
+	return count
+}
+
+// GetUnfinishedSessions lists open sessions, newest first, with how many
+// questions have been answered so far. userID 0 means all users.
+func (s *sqlStore) GetUnfinishedSessions(userID int) []*TestSession {
+	rows, err := s.db.Query(`SELECT `+sessionColumns+`,
+		(SELECT COUNT(*) FROM test_answers ta WHERE ta.session_id = test_sessions.id)
+		FROM test_sessions WHERE completed=FALSE AND ($1 = 0 OR user_id = $1)
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var sessions []*TestSession
+	for rows.Next() {
+		var answered int
+		if ts := scanSession(rows, &answered); ts != nil {
+			ts.AnsweredCount = answered
+			sessions = append(sessions, ts)
+		}
+	}
+	return sessions
+}
+
+func (s *sqlStore) DeleteTestSession(id, userID int) error {
+	_, err := s.db.Exec("DELETE FROM test_sessions WHERE id=$1 AND user_id=$2", id, userID)
+	return err
+}
+
+func (s *sqlStore) CreateTestAnswer(sessionID, questionID int, selectedAnswer string, isCorrect bool, credit float64) error {
+	_, err := s.db.Exec(`INSERT INTO test_answers (session_id, question_id, selected_answer, is_correct, credit, question_revision)
+		VALUES ($1, $2, $3, $4, $5, COALESCE((SELECT revision FROM questions WHERE id=$2), 0))`,
+		sessionID, questionID, selectedAnswer, isCorrect, credit)
+	return err
+}
+
+// GetSessionAnswers returns each answer with the question as it was when
+// the answer was given; Question.Revision stays the current revision.
+func (s *sqlStore) GetSessionAnswers(sessionID int) []*TestAnswer {
+	rows, err := s.db.Query(`SELECT ta.id, ta.session_id, ta.question_id, ta.selected_answer, ta.is_correct, ta.credit, ta.question_revision,
+		`+prefixColumns("r", revisionColumns)+`,
+		`+prefixColumns("q", questionColumns)+`
+		FROM test_answers ta JOIN questions q ON ta.question_id = q.id
+		LEFT JOIN question_revisions r ON r.question_id = ta.question_id AND r.revision = ta.question_revision
+		WHERE ta.session_id=$1 ORDER BY ta.id`, sessionID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var answers []*TestAnswer
+	for rows.Next() {
+		a := &TestAnswer{}
+		var content revisionContent
+		a.Question = scanQuestion(prefixScanner{rows, append([]interface{}{
+			&a.ID, &a.SessionID, &a.QuestionID, &a.SelectedAnswer, &a.IsCorrect, &a.Credit, &a.QuestionRevision},
+			content.dest()...)})
+		if a.Question != nil {
+			content.apply(a.Question)
+			answers = append(answers, a)
+		}
+	}
+	return answers
+}
+
+func (s *sqlStore) GetSettings() map[string]string {
+	result := make(map[string]string)
+	rows, err := s.db.Query("SELECT name, value FROM settings")
+	if err != nil {
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		rows.Scan(&name, &value)
+		result[name] = value
+	}
+	return result
+}
+
+func (s *sqlStore) SaveSettings(values map[string]string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for name, value := range values {
+			if _, err := tx.Exec(`INSERT INTO settings (name, value) VALUES ($1, $2)
+				ON CONFLICT (name) DO UPDATE SET value = excluded.value`, name, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) GetQuestionState(userID, questionID int) *QuestionState {
+	st := &QuestionState{UserID: userID, QuestionID: questionID}
+	err := s.db.QueryRow(`SELECT repetitions, interval_days, ease, due_at, reviewed_at
+		FROM user_question_state WHERE user_id=$1 AND question_id=$2`, userID, questionID).
+		Scan(&st.Repetitions, &st.Interval, &st.Ease, &st.DueAt, &st.ReviewedAt)
+	if err != nil {
+		return nil
+	}
+	return st
+}
+
+func (s *sqlStore) SaveQuestionState(st *QuestionState) error {
+	_, err := s.db.Exec(`INSERT INTO user_question_state (user_id, question_id, repetitions, interval_days, ease, due_at, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, question_id) DO UPDATE SET repetitions = excluded.repetitions,
+			interval_days = excluded.interval_days, ease = excluded.ease,
+			due_at = excluded.due_at, reviewed_at = excluded.reviewed_at`,
+		st.UserID, st.QuestionID, st.Repetitions, st.Interval, st.Ease, st.DueAt, st.ReviewedAt)
+	return err
+}
+
+// GetDueQuestionIDs returns questions due before the given time, most
+// overdue first.
+func (s *sqlStore) GetDueQuestionIDs(userID int, before time.Time, limit int) []int {
+	return s.queryIDs(`SELECT question_id FROM user_question_state
+		WHERE user_id=$1 AND due_at <= $2 AND question_id IN `+publishedQuestionIDs+` ORDER BY due_at, question_id LIMIT $3`,
+		userID, before.UTC().Truncate(time.Second), limit)
+}
+
+func (s *sqlStore) CountDueQuestions(userID int, before time.Time) int {
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM user_question_state WHERE user_id=$1 AND due_at <= $2 AND question_id IN "+publishedQuestionIDs,
+		userID, before.UTC().Truncate(time.Second)).Scan(&count)
+	return count
+}
+
+func (s *sqlStore) queryChapters(query string, args ...interface{}) []*HandbookChapter {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error getting handbook chapters: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var chapters []*HandbookChapter
+	for rows.Next() {
+		c := &HandbookChapter{}
+		rows.Scan(&c.ID, &c.Number, &c.Title, &c.CreatedAt)
+		chapters = append(chapters, c)
+	}
+	return chapters
+}
+
+// GetHandbookChapters returns the chapters in order, each with its articles.
+func (s *sqlStore) GetHandbookChapters() []*HandbookChapter {
+	chapters := s.queryChapters("SELECT id, number, title, created_at FROM handbook_chapters ORDER BY number")
+	byID := make(map[int]*HandbookChapter)
+	for _, c := range chapters {
+		byID[c.ID] = c
+	}
+	for _, a := range s.queryArticles(articleSelect + " ORDER BY c.number, a.position, a.id") {
+		if c, ok := byID[a.ChapterID]; ok {
+			c.Articles = append(c.Articles, a)
+		}
+	}
+	return chapters
+}
+
+func (s *sqlStore) GetHandbookChapter(id int) *HandbookChapter {
+	chapters := s.queryChapters("SELECT id, number, title, created_at FROM handbook_chapters WHERE id=$1", id)
+	if len(chapters) == 0 {
+		return nil
+	}
+	c := chapters[0]
+	c.Articles = s.queryArticles(articleSelect+" WHERE a.chapter_id=$1 ORDER BY a.position, a.id", id)
+	return c
+}
+
+func (s *sqlStore) CreateHandbookChapter(c *HandbookChapter) error {
+	return s.db.QueryRow("INSERT INTO handbook_chapters (number, title) VALUES ($1, $2) RETURNING id",
+		c.Number, c.Title).Scan(&c.ID)
+}
+
+func (s *sqlStore) UpdateHandbookChapter(c *HandbookChapter) error {
+	_, err := s.db.Exec("UPDATE handbook_chapters SET number=$1, title=$2 WHERE id=$3", c.Number, c.Title, c.ID)
+	return err
+}
+
+// DeleteHandbookChapter deletes a chapter with its articles; questions only
+// lose their links to them.
+func (s *sqlStore) DeleteHandbookChapter(id int) error {
+	_, err := s.db.Exec("DELETE FROM handbook_chapters WHERE id=$1", id)
+	return err
+}
+
+const articleSelect = `SELECT a.id, a.chapter_id, a.number, a.title, a.body, a.position, a.created_at, a.updated_at,
+		c.number, c.title
+		FROM handbook_articles a JOIN handbook_chapters c ON c.id = a.chapter_id`
+
+func (s *sqlStore) queryArticles(query string, args ...interface{}) []*HandbookArticle {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error getting handbook articles: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var articles []*HandbookArticle
+	for rows.Next() {
+		a := &HandbookArticle{}
+		rows.Scan(&a.ID, &a.ChapterID, &a.Number, &a.Title, &a.Body, &a.Position, &a.CreatedAt, &a.UpdatedAt,
+			&a.ChapterNumber, &a.ChapterTitle)
+		articles = append(articles, a)
+	}
+	return articles
+}
+
+func (s *sqlStore) GetHandbookArticle(id int) *HandbookArticle {
+	articles := s.queryArticles(articleSelect+" WHERE a.id=$1", id)
+	if len(articles) == 0 {
+		return nil
+	}
+	return articles[0]
+}
+
+func (s *sqlStore) CreateHandbookArticle(a *HandbookArticle) error {
+	return s.db.QueryRow(`INSERT INTO handbook_articles (chapter_id, number, title, body, position)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		a.ChapterID, a.Number, a.Title, a.Body, a.Position).Scan(&a.ID)
+}
+
+func (s *sqlStore) UpdateHandbookArticle(a *HandbookArticle) error {
+	_, err := s.db.Exec(`UPDATE handbook_articles SET chapter_id=$1, number=$2, title=$3, body=$4, position=$5,
+		updated_at=CURRENT_TIMESTAMP WHERE id=$6`,
+		a.ChapterID, a.Number, a.Title, a.Body, a.Position, a.ID)
+	return err
+}
+
+func (s *sqlStore) DeleteHandbookArticle(id int) error {
+	_, err := s.db.Exec("DELETE FROM handbook_articles WHERE id=$1", id)
+	return err
+}
+
+// searchTermsSQL requires every search term to appear in one of cols.
+func searchTermsSQL(terms []string, cols ...string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for i, t := range terms {
+		var like []string
+		for _, c := range cols {
+			like = append(like, fmt.Sprintf("LOWER(%s) LIKE $%d", c, i+1))
+		}
+		conds = append(conds, "("+strings.Join(like, " OR ")+")")
+		args = append(args, "%"+t+"%")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (s *sqlStore) SearchHandbookArticles(query string) []*HandbookArticle {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	where, args := searchTermsSQL(terms, "a.number", "a.title", "a.body")
+	return s.queryArticles(articleSelect+" WHERE "+where+" ORDER BY c.number, a.position, a.id", args...)
+}
+
+func (s *sqlStore) GetQuestionArticles(questionID int) []*HandbookArticle {
+	return s.queryArticles(articleSelect+`
+		JOIN question_articles qa ON qa.article_id = a.id
+		WHERE qa.question_id=$1 ORDER BY c.number, a.position, a.id`, questionID)
+}
+
+// SetQuestionArticles replaces the articles a question links to.
+func (s *sqlStore) SetQuestionArticles(questionID int, articleIDs []int) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM question_articles WHERE question_id=$1", questionID); err != nil {
+			return err
+		}
+		seen := make(map[int]bool)
+		for _, id := range articleIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if _, err := tx.Exec("INSERT INTO question_articles (question_id, article_id) VALUES ($1, $2)", questionID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) GetArticleQuestions(articleID int) []*Question {
+	rows, err := s.db.Query(`SELECT `+prefixColumns("q", questionColumns)+`
+		FROM questions q JOIN question_articles qa ON qa.question_id = q.id
+		WHERE qa.article_id=$1 AND q.deleted_at IS NULL AND q.status = 'published' ORDER BY q.number`, articleID)
+	if err != nil {
+		log.Printf("Error getting article questions: %v", err)
+		return nil
+	}
+	return scanQuestionRows(rows)
+}
+
+// GetUserQuestionStats counts the user's answers to the given questions.
+// Questions never answered are missing from the result.
+func (s *sqlStore) GetUserQuestionStats(userID int, questionIDs []int) map[int]*QuestionStat {
+	result := make(map[int]*QuestionStat)
+	if len(questionIDs) == 0 {
+		return result
+	}
+	rows, err := s.db.Query(`SELECT ua.question_id, COUNT(*), SUM(CASE WHEN ua.is_correct THEN 1 ELSE 0 END)
+		FROM `+userAnswersSQL+`
+		WHERE ua.question_id IN (`+placeholders(2, len(questionIDs))+`)
+		GROUP BY ua.question_id`, append([]interface{}{userID}, intArgs(questionIDs)...)...)
+	if err != nil {
+		log.Printf("Error getting question stats: %v", err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		st := &QuestionStat{}
+		rows.Scan(&st.QuestionID, &st.Answered, &st.Correct)
+		result[st.QuestionID] = st
+	}
+	return result
+}
+
+func (s *sqlStore) queryRoadSigns(query string, args ...interface{}) []*RoadSign {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error getting road signs: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var signs []*RoadSign
+	for rows.Next() {
+		rs := &RoadSign{}
+		rows.Scan(&rs.ID, &rs.Code, &rs.Name, &rs.Description, &rs.Image, &rs.CreatedAt)
+		signs = append(signs, rs)
+	}
+	sortRoadSigns(signs)
+	return signs
+}
+
+const roadSignSelect = "SELECT id, code, name, description, image, created_at FROM road_signs"
+
+func (s *sqlStore) GetAllRoadSigns() []*RoadSign {
+	return s.queryRoadSigns(roadSignSelect)
+}
+
+func (s *sqlStore) GetRoadSign(id int) *RoadSign {
+	signs := s.queryRoadSigns(roadSignSelect+" WHERE id=$1", id)
+	if len(signs) == 0 {
+		return nil
+	}
+	return signs[0]
+}
+
+func (s *sqlStore) CreateRoadSign(rs *RoadSign) error {
+	return s.db.QueryRow("INSERT INTO road_signs (code, name, description, image) VALUES ($1, $2, $3, $4) RETURNING id",
+		rs.Code, rs.Name, rs.Description, rs.Image).Scan(&rs.ID)
+}
+
+func (s *sqlStore) UpdateRoadSign(rs *RoadSign) error {
+	_, err := s.db.Exec("UPDATE road_signs SET code=$1, name=$2, description=$3, image=$4 WHERE id=$5",
+		rs.Code, rs.Name, rs.Description, rs.Image, rs.ID)
+	return err
+}
+
+func (s *sqlStore) DeleteRoadSign(id int) error {
+	_, err := s.db.Exec("DELETE FROM road_signs WHERE id=$1", id)
+	return err
+}
+
+func (s *sqlStore) SearchRoadSigns(query string) []*RoadSign {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	where, args := searchTermsSQL(terms, "code", "name", "description")
+	return s.queryRoadSigns(roadSignSelect+" WHERE "+where, args...)
+}
+
+// mediaReferencesQuery lists every image path in use, trashed questions and
+// old revisions included.
+const mediaReferencesQuery = `
+	SELECT image, 'question', id, CAST(number AS TEXT) FROM questions WHERE image IS NOT NULL AND image <> ''
+	UNION ALL
+	SELECT explanation_image, 'question', id, CAST(number AS TEXT) FROM questions WHERE explanation_image <> ''
+	UNION
+	SELECT image, 'revision', question_id, CAST(number AS TEXT) FROM question_revisions WHERE image IS NOT NULL AND image <> ''
+	UNION
+	SELECT explanation_image, 'revision', question_id, CAST(number AS TEXT) FROM question_revisions WHERE explanation_image <> ''
+	UNION ALL
+	SELECT image, 'sign', id, code FROM road_signs WHERE image <> ''`
+
+func (s *sqlStore) GetMediaReferences() []*MediaReference {
+	rows, err := s.db.Query(mediaReferencesQuery)
+	if err != nil {
+		log.Printf("Error getting media references: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var refs []*MediaReference
+	for rows.Next() {
+		ref := &MediaReference{}
+		rows.Scan(&ref.Path, &ref.Kind, &ref.OwnerID, &ref.Label)
+		refs = append(refs, ref)
+	}
+	return refs
+}
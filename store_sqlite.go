@@ -0,0 +1,31 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteStore opens (or creates) a SQLite database file. Foreign keys are
+// switched on per connection so ON DELETE CASCADE behaves as on PostgreSQL.
+func newSQLiteStore(path string) (*sqlStore, error) {
+	if path == "" {
+		path = "avtotestprime.db"
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		os.MkdirAll(dir, 0755)
+	}
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	return &sqlStore{db: conn, dialect: dialectSQLite}, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// sweepInterval is how often the background housekeeping runs.
+const sweepInterval = 5 * time.Minute
+
+// sweepStaleSessions grades and closes every open session whose time is up,
+// so abandoned tests do not stay unfinished forever. It returns how many
+// sessions were closed.
+func sweepStaleSessions() int {
+	now := time.Now()
+	closed := 0
+	for _, s := range db.GetUnfinishedSessions(0) {
+		if s.Stale(now) {
+			gradeSession(s, nil)
+			closed++
+		}
+	}
+	return closed
+}
+
+// purgeExpiredTrash permanently deletes questions and users that have been
+// in the trash longer than the retention period.
+func purgeExpiredTrash() (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -getTrashRetentionDays())
+	return db.PurgeDeleted(cutoff)
+}
+
+// startSweeper runs the periodic housekeeping: closing stale sessions and
+// emptying expired trash.
+func startSweeper(interval time.Duration) {
+	go func() {
+		for {
+			if n := sweepStaleSessions(); n > 0 {
+				log.Printf("Closed %d stale test sessions", n)
+			}
+			if n, err := purgeExpiredTrash(); err != nil {
+				log.Printf("Error purging trash: %v", err)
+			} else if n > 0 {
+				log.Printf("Purged %d expired items from the trash", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
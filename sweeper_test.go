@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		if err := db.CreateUser("student", "x", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.CreateUser("leaver", "x", ""); err != nil {
+			t.Fatal(err)
+		}
+		student, leaver := db.GetUserByUsername("student"), db.GetUserByUsername("leaver")
+		trashed := &Question{Number: 1, Text: "Old question?", CorrectAnswer: "A",
+			VariantsList: []Variant{{Letter: "A", Text: "Yes"}, {Letter: "B", Text: "No"}}}
+		kept := &Question{Number: 2, Text: "Current question?", CorrectAnswer: "B",
+			VariantsList: []Variant{{Letter: "A", Text: "Yes"}, {Letter: "B", Text: "No"}}}
+		for _, q := range []*Question{trashed, kept} {
+			if err := db.CreateQuestion(q, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		session := &TestSession{UserID: student.ID, TotalQuestions: 2}
+		if err := db.CreateTestSession(session, []int{trashed.ID, kept.ID}); err != nil {
+			t.Fatal(err)
+		}
+		db.CreateTestAnswer(session.ID, trashed.ID, "A", true, 1)
+		db.CreateTestAnswer(session.ID, kept.ID, "B", true, 1)
+		db.ToggleBookmark(student.ID, trashed.ID)
+		db.DeleteQuestion(trashed.ID, 0)
+		db.DeleteUser(leaver.ID)
+
+		// Nothing has been in the trash long enough yet.
+		if n, err := db.PurgeDeleted(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+			t.Fatalf("purged %d, %v before the cutoff", n, err)
+		}
+		if len(db.GetDeletedQuestions()) != 1 || len(db.GetDeletedUsers()) != 1 {
+			t.Fatal("trash emptied before the cutoff")
+		}
+
+		if n, err := db.PurgeDeleted(time.Now().Add(2 * time.Second)); err != nil || n != 2 {
+			t.Fatalf("purged %d, %v; want the question and the user", n, err)
+		}
+		if len(db.GetDeletedQuestions()) != 0 || len(db.GetDeletedUsers()) != 0 {
+			t.Error("trash not empty after purging")
+		}
+		if db.GetQuestionRevisions(trashed.ID) != nil || db.GetUserByUsername("leaver") != nil {
+			t.Error("purged rows are still there")
+		}
+		if err := db.RestoreQuestionRevision(trashed.ID, 1, 0, nil, ""); err == nil || db.GetQuestionByID(trashed.ID) != nil {
+			t.Error("restored a purged question")
+		}
+		if len(db.GetUserBookmarkIDs(student.ID)) != 0 {
+			t.Error("bookmark of a purged question kept")
+		}
+		if answers := db.GetSessionAnswers(session.ID); len(answers) != 1 || answers[0].QuestionID != kept.ID {
+			t.Errorf("answers after purging: %+v", answers)
+		}
+		if db.GetQuestionByID(kept.ID) == nil || len(db.GetQuestionRevisions(kept.ID)) != 1 || db.GetUserByID(student.ID) == nil {
+			t.Error("purging touched live rows")
+		}
+	})
+}
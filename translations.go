@@ -0,0 +1,160 @@
+package main
+
+import "time"
+
+// Question content languages. Questions are written in Uzbek Latin; the
+// other languages are translations kept in question_translations.
+const (
+	langUzLatin    = "uz"
+	langUzCyrillic = "uz-Cyrl"
+	langRussian    = "ru"
+)
+
+type contentLanguage struct {
+	Code string
+	Name string
+}
+
+var contentLanguages = []contentLanguage{
+	{langUzLatin, "O'zbekcha (lotin)"},
+	{langUzCyrillic, "Ўзбекча (кирилл)"},
+	{langRussian, "Русский"},
+}
+
+// translationLanguages are the languages that have translations, in
+// contentLanguages order.
+func translationLanguages() []contentLanguage {
+	return contentLanguages[1:]
+}
+
+func isContentLang(code string) bool {
+	for _, l := range contentLanguages {
+		if l.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// userContentLang is the language u reads questions in.
+func userContentLang(u *User) string {
+	if u != nil && isContentLang(u.ContentLang) {
+		return u.ContentLang
+	}
+	return langUzLatin
+}
+
+// QuestionTranslation is a question's text, variants and explanation in one
+// language. Empty fields fall back to the original (see localizeQuestion).
+type QuestionTranslation struct {
+	QuestionID   int
+	Lang         string
+	Text         string
+	VariantsList []Variant
+	Explanation  string
+	UpdatedAt    time.Time
+}
+
+func (t *QuestionTranslation) variantText(letter string) string {
+	for _, v := range t.VariantsList {
+		if v.Letter == letter {
+			return v.Text
+		}
+	}
+	return ""
+}
+
+// Translation states shown in the missing-translation report.
+const (
+	translationMissing  = "missing"
+	translationPartial  = "partial"
+	translationOutdated = "outdated"
+	translationComplete = "complete"
+)
+
+// translationStatus tells whether t covers everything q has. A translation
+// saved before the question was last changed is outdated.
+func translationStatus(q *Question, t *QuestionTranslation) string {
+	if t == nil {
+		return translationMissing
+	}
+	if t.Text == "" || (q.Explanation != "" && t.Explanation == "") {
+		return translationPartial
+	}
+	for _, v := range q.VariantsList {
+		if t.variantText(v.Letter) == "" {
+			return translationPartial
+		}
+	}
+	if q.UpdatedAt.After(t.UpdatedAt) {
+		return translationOutdated
+	}
+	return translationComplete
+}
+
+// fallbackText is what a reader of lang sees where there is no human
+// translation: Uzbek is transliterated into the other alphabet, anything
+// else gets the original.
+func fallbackText(s, lang string, markdown bool) string {
+	var convert func(string) string
+	switch {
+	case lang == langUzCyrillic && !isCyrillicText(s):
+		convert = latinToCyrillic
+	case lang == langUzLatin && isCyrillicText(s):
+		convert = cyrillicToLatin
+	default:
+		return s
+	}
+	if markdown {
+		return transliterateMarkdown(s, convert)
+	}
+	return convert(s)
+}
+
+// localizeQuestion rewrites q's text, variants and explanation for lang,
+// using t where it has the field. Variants keep their letters, labels and
+// order, so it works on shuffled questions too.
+func localizeQuestion(q *Question, lang string, t *QuestionTranslation) {
+	if t == nil {
+		t = &QuestionTranslation{}
+	}
+	pick := func(human, original string, markdown bool) string {
+		if human != "" {
+			return human
+		}
+		return fallbackText(original, lang, markdown)
+	}
+	q.Text = pick(t.Text, q.Text, false)
+	for i, v := range q.VariantsList {
+		q.VariantsList[i].Text = pick(t.variantText(v.Letter), v.Text, false)
+	}
+	q.Explanation = pick(t.Explanation, q.Explanation, true)
+}
+
+// localizeQuestions shows questions in lang, loading all their translations
+// in one go.
+func localizeQuestions(lang string, questions []*Question) {
+	if len(questions) == 0 {
+		return
+	}
+	ids := make([]int, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	var translations map[int]*QuestionTranslation
+	if lang != langUzLatin {
+		translations = db.GetQuestionTranslations(lang, ids)
+	}
+	for _, q := range questions {
+		localizeQuestion(q, lang, translations[q.ID])
+	}
+}
+
+// localizeQuestionMap is localizeQuestions for a map from GetQuestionsByIDs.
+func localizeQuestionMap(lang string, qMap map[int]*Question) {
+	questions := make([]*Question, 0, len(qMap))
+	for _, q := range qMap {
+		questions = append(questions, q)
+	}
+	localizeQuestions(lang, questions)
+}
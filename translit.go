@@ -0,0 +1,175 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Uzbek is written in both a Latin and a Cyrillic alphabet that map onto each
+// other almost letter for letter. The functions below convert between them
+// following the 1995 alphabet; loanword spellings (for example "ц" written
+// as "s" or "ts") cannot be told apart by rule and keep the regular form.
+
+// apostrophes are the characters used for oʻ, gʻ and the tutuq belgisi.
+const apostrophes = "'ʻʼ‘’`"
+
+var latinDigraphs = map[string]string{
+	"sh": "ш", "ch": "ч", "yo": "ё", "yu": "ю", "ya": "я", "ye": "е",
+}
+
+var latinLetters = map[rune]string{
+	'a': "а", 'b': "б", 'c': "ц", 'd': "д", 'e': "е", 'f': "ф", 'g': "г",
+	'h': "ҳ", 'i': "и", 'j': "ж", 'k': "к", 'l': "л", 'm': "м", 'n': "н",
+	'o': "о", 'p': "п", 'q': "қ", 'r': "р", 's': "с", 't': "т", 'u': "у",
+	'v': "в", 'w': "в", 'x': "х", 'y': "й", 'z': "з",
+}
+
+var cyrillicLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ғ': "g'", 'д': "d", 'ё': "yo",
+	'ж': "j", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'қ': "q", 'л': "l",
+	'м': "m", 'н': "n", 'о': "o", 'ў': "o'", 'п': "p", 'р': "r", 'с': "s",
+	'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ҳ': "h", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "sh", 'ъ': "'", 'ь': "", 'ы': "i", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// matchCase gives out the case of the n source runes at i: all caps when the
+// word is written in caps, otherwise capitalised if the first rune is.
+func matchCase(out string, src []rune, i, n int) string {
+	if !unicode.IsUpper(src[i]) {
+		return out
+	}
+	caps := false
+	if n > 1 && unicode.IsLetter(src[i+1]) {
+		caps = unicode.IsUpper(src[i+1])
+	} else if j := i + n; j < len(src) && unicode.IsLetter(src[j]) {
+		caps = unicode.IsUpper(src[j])
+	} else if i > 0 && unicode.IsLetter(src[i-1]) {
+		caps = unicode.IsUpper(src[i-1])
+	}
+	if caps {
+		return strings.ToUpper(out)
+	}
+	r := []rune(out)
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+	return string(r)
+}
+
+func isLatinVowel(r rune) bool {
+	return strings.ContainsRune("aeiouAEIOU", r)
+}
+
+func isApostrophe(r rune) bool {
+	return strings.ContainsRune(apostrophes, r)
+}
+
+// latinToCyrillic converts Uzbek Latin text to Cyrillic. Anything that is not
+// a Latin letter is kept as it is.
+func latinToCyrillic(s string) string {
+	src := []rune(s)
+	var out strings.Builder
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		lower := unicode.ToLower(r)
+		next := rune(0)
+		if i+1 < len(src) {
+			next = unicode.ToLower(src[i+1])
+		}
+		wordStart := i == 0 || !unicode.IsLetter(src[i-1]) && !isApostrophe(src[i-1])
+		// "ye" is е where Cyrillic е sounds that way: at the start of a
+		// word, after a vowel and after the tutuq belgisi (poyezd, s'yezd).
+		yeSound := wordStart || isLatinVowel(src[i-1]) || isApostrophe(src[i-1])
+
+		switch {
+		case (lower == 'o' || lower == 'g') && isApostrophe(next):
+			out.WriteString(matchCase(map[rune]string{'o': "ў", 'g': "ғ"}[lower], src, i, 2))
+			i++
+		case lower == 's' && isApostrophe(next) && i+2 < len(src) && unicode.ToLower(src[i+2]) == 'h':
+			// "s'h" keeps the two sounds apart: Is'hoq is Исҳоқ.
+			out.WriteString(matchCase("с", src, i, 1))
+			i++
+		case latinDigraphs[string([]rune{lower, next})] != "" && (lower != 'y' || next != 'e' || yeSound) &&
+			!(next == 'o' && i+2 < len(src) && isApostrophe(src[i+2])):
+			// Elsewhere "ye" is йе, and yo' is йў.
+			out.WriteString(matchCase(latinDigraphs[string([]rune{lower, next})], src, i, 2))
+			i++
+		case lower == 'e' && (wordStart || i > 0 && isLatinVowel(src[i-1])):
+			out.WriteString(matchCase("э", src, i, 1))
+		case isApostrophe(r) && i > 0 && unicode.IsLetter(src[i-1]) && unicode.IsLetter(next):
+			out.WriteString("ъ")
+		default:
+			if c, ok := latinLetters[lower]; ok {
+				out.WriteString(matchCase(c, src, i, 1))
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	return out.String()
+}
+
+func isCyrillicVowel(r rune) bool {
+	return strings.ContainsRune("аеёиоуўэюяъь", unicode.ToLower(r))
+}
+
+// cyrillicToLatin converts Uzbek Cyrillic text to Latin, using a plain
+// apostrophe for oʻ, gʻ and the tutuq belgisi.
+func cyrillicToLatin(s string) string {
+	src := []rune(s)
+	var out strings.Builder
+	for i, r := range src {
+		lower := unicode.ToLower(r)
+		if lower == 'е' {
+			// Е sounds "ye" at the start of a word and after a vowel.
+			if i == 0 || !unicode.IsLetter(src[i-1]) || isCyrillicVowel(src[i-1]) {
+				out.WriteString(matchCase("ye", src, i, 1))
+			} else {
+				out.WriteString(matchCase("e", src, i, 1))
+			}
+			continue
+		}
+		if lower == 'с' && i+1 < len(src) && unicode.ToLower(src[i+1]) == 'ҳ' {
+			out.WriteString(matchCase("s'", src, i, 1))
+		} else if l, ok := cyrillicLetters[lower]; ok {
+			out.WriteString(matchCase(l, src, i, 1))
+		} else {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// isCyrillicText reports whether most letters of s are Cyrillic.
+func isCyrillicText(s string) bool {
+	cyrillic, latin := 0, 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	return cyrillic > latin
+}
+
+// markdownVerbatim matches the parts of Markdown that must not be
+// transliterated: code spans, link targets and bare URLs.
+var markdownVerbatim = regexp.MustCompile("`[^`]*`|\\]\\([^)\\s]+\\)|https?://\\S+")
+
+// transliterateMarkdown applies convert to Markdown text, leaving code,
+// link targets and URLs as written.
+func transliterateMarkdown(s string, convert func(string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range markdownVerbatim.FindAllStringIndex(s, -1) {
+		out.WriteString(convert(s[last:loc[0]]))
+		out.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(convert(s[last:]))
+	return out.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestLatinToCyrillic(t *testing.T) {
+	tests := []struct {
+		latin, cyrillic string
+	}{
+		{"Yo'l harakati qoidalari", "Йўл ҳаракати қоидалари"},
+		{"O'zbekiston", "Ўзбекистон"},
+		{"G'ildirak", "Ғилдирак"},
+		{"SHAHAR", "ШАҲАР"},
+		{"choyxona", "чойхона"},
+		{"Is'hoq", "Исҳоқ"},
+		{"ma'lum", "маълум"},
+		{"yer", "ер"},
+		{"poyezd", "поезд"},
+		{"Poyezd to'xtadi", "Поезд тўхтади"},
+		{"s'yezd", "съезд"},
+		{"kelyapti", "келяпти"},
+		{"shoir", "шоир"},
+		{"oila", "оила"},
+		{"ekin", "экин"},
+		{"shoe", "шоэ"},
+		{"5 km/soat", "5 км/соат"},
+	}
+	for _, tt := range tests {
+		if got := latinToCyrillic(tt.latin); got != tt.cyrillic {
+			t.Errorf("latinToCyrillic(%q) = %q, want %q", tt.latin, got, tt.cyrillic)
+		}
+	}
+}
+
+func TestCyrillicLatinRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"Йўл ҳаракати қоидалари",
+		"Поезд тўхтади",
+		"Ер юзи",
+		"Исҳоқ маълум экин",
+		"Қуёш ва ой",
+		"съезд",
+		"ЎЗБЕКИСТОН",
+	} {
+		latin := cyrillicToLatin(s)
+		if back := latinToCyrillic(latin); back != s {
+			t.Errorf("%q -> %q -> %q", s, latin, back)
+		}
+	}
+}
+
+func TestTransliterateMarkdown(t *testing.T) {
+	got := transliterateMarkdown("Qoida `yo'l` [bu yerda](https://yol.uz/yer) http://x.uz/poyezd", latinToCyrillic)
+	want := "Қоида `yo'l` [бу ерда](https://yol.uz/yer) http://x.uz/poyezd"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}